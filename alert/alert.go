@@ -0,0 +1,308 @@
+// alert turns samples into alerts with hysteresis, a minimum duration and flap detection.
+package alert
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// State tells if an alert stays active.
+type State int
+
+const (
+	Notice   State = iota // a one-off notification, e.g. a sudden increase
+	Firing                // the alert stays active until a Resolved alert of the same rule
+	Resolved              // the alert of the rule cleared
+)
+
+var stateNames = map[State]string{
+	Notice:   "notice",
+	Firing:   "firing",
+	Resolved: "resolved",
+}
+
+func (s State) String() string {
+	return stateNames[s]
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (s State) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (s *State) UnmarshalText(text []byte) error {
+	for k, v := range stateNames {
+		if v == string(text) {
+			*s = k
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown alert state %q", text)
+}
+
+// Severity is how urgent an alert is.
+type Severity int
+
+const (
+	Info Severity = iota
+	Warning
+	Critical
+)
+
+var severityNames = map[Severity]string{
+	Info:     "info",
+	Warning:  "warning",
+	Critical: "critical",
+}
+
+func (s Severity) String() string {
+	return severityNames[s]
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (s *Severity) UnmarshalText(text []byte) error {
+	for k, v := range severityNames {
+		if v == string(text) {
+			*s = k
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown severity %q", text)
+}
+
+// Alert is a notification raised by a threshold alert or a rule.
+type Alert struct {
+	ID        string    `json:"id"`              // set when the alert is broadcast
+	State     State     `json:"state"`           // whether the alert is firing, resolved or a one-off notice
+	Severity  Severity  `json:"severity"`        // how urgent the alert is
+	Rule      string    `json:"rule"`            // name of the rule or threshold alert, e.g. "cpu_threshold" or "high_cpu"
+	Metric    string    `json:"metric"`          // series the alert is about, e.g. "cpu"
+	Class     string    `json:"class,omitempty"` // class of the host the alert is about, e.g. "db"
+	Value     float64   `json:"value"`           // value of the metric when the alert was raised
+	Threshold float64   `json:"threshold"`       // threshold the value is compared with, 0 if there is none
+	Sparkline string    `json:"sparkline"`       // recent values of the metric, see Sparkline
+	Text      string    `json:"text"`            // human readable message
+	Time      time.Time `json:"time"`            // when the alert was raised
+
+	// key of Text in the i18n catalogs and its arguments, so that each subscriber gets it in their language
+	Msg  string                 `json:"msg,omitempty"`
+	Args map[string]interface{} `json:"args,omitempty"`
+}
+
+var sparks = []rune("▁▂▃▄▅▆▇█")
+
+// Sparkline renders values as a line of block characters, e.g. "▁▂▄█▆".
+func Sparkline(values []float64) string {
+	if len(values) == 0 {
+		return ""
+	}
+
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, v := range values {
+		lo = math.Min(lo, v)
+		hi = math.Max(hi, v)
+	}
+
+	res := make([]rune, len(values))
+	for i, v := range values {
+		j := 0
+		if hi > lo {
+			j = int((v - lo) / (hi - lo) * float64(len(sparks)-1))
+		}
+		res[i] = sparks[j]
+	}
+
+	return string(res)
+}
+
+// Matches checks if the alert belongs to the rule or metric with the given name, "*" matches every alert.
+func (a Alert) Matches(name string) bool {
+	return name == "*" || name == a.Rule || name == a.Metric
+}
+
+// Event is the result of feeding a sample to a Tracker.
+type Event int
+
+const (
+	None      Event = iota // nothing worth notifying
+	Fire                   // the alert started firing
+	Resolve                // the alert cleared
+	FlapStart              // the alert started flapping, further changes are suppressed
+	FlapStop               // the alert stopped flapping
+)
+
+// Options controls how a Tracker turns samples into events.
+type Options struct {
+	Trigger    float64       // a sample above Trigger is breaching
+	Clear      float64       // a firing alert clears once a sample is below Clear
+	MinSamples int           // consecutive breaching samples needed to fire
+	FlapWindow time.Duration // window in which state changes are counted
+	FlapCount  int           // state changes within FlapWindow that count as flapping, 0 disables flap detection
+}
+
+type sample struct {
+	Value float64
+	Time  time.Time
+}
+
+// Summary describes what happened in the flap window.
+type Summary struct {
+	Changes    int           // state changes in the window
+	Suppressed int           // fire and resolve events suppressed while flapping
+	Min        float64       // minimum value in the window
+	Max        float64       // maximum value in the window
+	Window     time.Duration // length of the window
+	Firing     bool          // whether the alert is firing at the moment
+}
+
+// Tracker keeps the state of a single alert.
+type Tracker struct {
+	Name       string
+	firing     bool
+	flapping   bool
+	streak     int
+	suppressed int
+	changes    []time.Time
+	samples    []sample
+	severity   Severity // highest severity of the firing alert, see Escalate
+}
+
+// NewTracker creates a new Tracker with the given name.
+func NewTracker(name string) *Tracker {
+	return &Tracker{
+		Name: name,
+	}
+}
+
+// Firing returns whether the alert is firing.
+func (t *Tracker) Firing() bool {
+	return t.firing
+}
+
+// Flapping returns whether the alert is flapping.
+func (t *Tracker) Flapping() bool {
+	return t.flapping
+}
+
+// Escalate raises the severity of the firing alert to s, it returns true if s is higher than the severity the alert had. The severity is forgotten once the alert is resolved.
+func (t *Tracker) Escalate(s Severity) bool {
+	if !t.firing {
+		t.severity = Info
+		return false
+	}
+	if s <= t.severity {
+		return false
+	}
+	t.severity = s
+	return true
+}
+
+// Update feeds a value to the tracker and returns the resulting event.
+func (t *Tracker) Update(value float64, now time.Time, opt Options) Event {
+	clear := opt.Clear
+	if clear > opt.Trigger {
+		clear = opt.Trigger
+	}
+
+	t.samples = append(t.samples, sample{Value: value, Time: now})
+
+	return t.Observe(value > opt.Trigger, value < clear, now, opt)
+}
+
+// Observe feeds an already evaluated sample to the tracker. breach means the sample is over the trigger threshold and clear means it is under the clear threshold; a sample that is neither keeps the current state.
+func (t *Tracker) Observe(breach bool, clear bool, now time.Time, opt Options) Event {
+	changed := false
+
+	switch {
+	case breach:
+		t.streak++
+		if !t.firing && t.streak >= max(opt.MinSamples, 1) {
+			t.firing = true
+			changed = true
+		}
+	case clear:
+		t.streak = 0
+		if t.firing {
+			t.firing = false
+			changed = true
+		}
+	default:
+		t.streak = 0
+	}
+
+	if changed {
+		t.changes = append(t.changes, now)
+	}
+	t.prune(now, opt.FlapWindow)
+
+	if t.flapping {
+		if changed {
+			t.suppressed++
+		}
+		if len(t.changes) == 0 {
+			t.flapping = false
+			return FlapStop
+		}
+		return None
+	}
+
+	if opt.FlapCount > 0 && len(t.changes) >= opt.FlapCount {
+		t.flapping = true
+		t.suppressed = 0
+		return FlapStart
+	}
+
+	if !changed {
+		return None
+	}
+	if t.firing {
+		return Fire
+	}
+	return Resolve
+}
+
+// prune removes the state changes and samples that are out of the flap window
+func (t *Tracker) prune(now time.Time, window time.Duration) {
+	end := now.Add(-window)
+
+	i := 0
+	for i < len(t.changes) && !t.changes[i].After(end) {
+		i++
+	}
+	t.changes = t.changes[i:]
+
+	i = 0
+	for i < len(t.samples) && !t.samples[i].Time.After(end) {
+		i++
+	}
+	t.samples = t.samples[i:]
+}
+
+// Summary returns what happened in the current flap window.
+func (t *Tracker) Summary(window time.Duration) Summary {
+	s := Summary{
+		Changes:    len(t.changes),
+		Suppressed: t.suppressed,
+		Min:        math.Inf(1),
+		Max:        math.Inf(-1),
+		Window:     window,
+		Firing:     t.firing,
+	}
+
+	for _, v := range t.samples {
+		s.Min = math.Min(s.Min, v.Value)
+		s.Max = math.Max(s.Max, v.Value)
+	}
+	if len(t.samples) == 0 {
+		s.Min, s.Max = 0, 0
+	}
+
+	return s
+}
@@ -0,0 +1,121 @@
+package alert
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTrackerHysteresis(t *testing.T) {
+	opt := Options{
+		Trigger:    75,
+		Clear:      70,
+		MinSamples: 2,
+	}
+
+	tr := NewTracker("cpu")
+	n := time.Now()
+
+	steps := []struct {
+		value float64
+		want  Event
+	}{
+		{80, None},    // first breaching sample
+		{72, None},    // between thresholds, streak resets
+		{80, None},    // first breaching sample again
+		{81, Fire},    // second consecutive breaching sample
+		{74, None},    // under trigger but above clear, still firing
+		{76, None},    // still firing
+		{69, Resolve}, // under clear threshold
+		{74, None},    // between thresholds, still ok
+	}
+
+	for i, s := range steps {
+		n = n.Add(time.Minute)
+		if got := tr.Update(s.value, n, opt); got != s.want {
+			t.Errorf("step %d: Update(%.0f) = %d, want %d", i, s.value, got, s.want)
+		}
+	}
+}
+
+func TestTrackerEscalate(t *testing.T) {
+	opt := Options{Trigger: 75, Clear: 70}
+	tr := NewTracker("cpu")
+	n := time.Now()
+
+	if tr.Escalate(Warning) {
+		t.Error("an alert that is not firing escalated")
+	}
+	tr.Update(80, n, opt)
+	if !tr.Escalate(Warning) || tr.Escalate(Warning) {
+		t.Error("firing alert should take its severity once")
+	}
+	if !tr.Escalate(Critical) || tr.Escalate(Warning) || tr.Escalate(Critical) {
+		t.Error("firing alert should only escalate to a higher severity")
+	}
+
+	// a new firing starts over
+	tr.Update(60, n.Add(time.Minute), opt)
+	tr.Escalate(Warning)
+	tr.Update(80, n.Add(2*time.Minute), opt)
+	if !tr.Escalate(Warning) {
+		t.Error("severity of a resolved alert was kept")
+	}
+}
+
+func TestTrackerFlapping(t *testing.T) {
+	opt := Options{
+		Trigger:    75,
+		Clear:      75,
+		MinSamples: 1,
+		FlapWindow: 10 * time.Minute,
+		FlapCount:  4,
+	}
+
+	tr := NewTracker("cpu")
+	n := time.Now()
+
+	events := []Event{}
+	for _, v := range []float64{80, 70, 80, 70, 80, 70} {
+		n = n.Add(time.Minute)
+		events = append(events, tr.Update(v, n, opt))
+	}
+
+	want := []Event{Fire, Resolve, Fire, FlapStart, None, None}
+	for i := range want {
+		if events[i] != want[i] {
+			t.Errorf("event %d = %d, want %d", i, events[i], want[i])
+		}
+	}
+
+	s := tr.Summary(opt.FlapWindow)
+	if s.Changes != 6 || s.Suppressed != 2 || s.Min != 70 || s.Max != 80 {
+		t.Errorf("unexpected summary %+v", s)
+	}
+
+	// stable for longer than the flap window
+	var last Event
+	for i := 0; i < 11; i++ {
+		n = n.Add(time.Minute)
+		if e := tr.Update(70, n, opt); e != None {
+			last = e
+		}
+	}
+	if last != FlapStop {
+		t.Errorf("last event = %d, want %d", last, FlapStop)
+	}
+	if tr.Flapping() || tr.Firing() {
+		t.Error("tracker should be ok after flapping stopped")
+	}
+}
+
+func TestSparkline(t *testing.T) {
+	if got := Sparkline([]float64{0, 1, 2, 3, 4, 5, 6, 7}); got != "▁▂▃▄▅▆▇█" {
+		t.Errorf("got %s", got)
+	}
+	if got := Sparkline([]float64{5, 5}); got != "▁▁" {
+		t.Errorf("got %s", got)
+	}
+	if got := Sparkline(nil); got != "" {
+		t.Errorf("got %s", got)
+	}
+}
@@ -0,0 +1,281 @@
+package bot
+
+import (
+	"fmt"
+	"monitor/alert"
+	"monitor/i18n"
+	"sort"
+	"strconv"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// keepAlerts is how long resolved alerts and notices are kept, so that buttons on old messages still work
+const keepAlerts = 24 * time.Hour
+
+// AlertRecord is a broadcast alert and what happened to it.
+type AlertRecord struct {
+	alert.Alert
+	AckedBy    string
+	AckedAt    time.Time
+	RemindedAt time.Time
+	Escalated  bool
+	ResolvedAt time.Time
+}
+
+// Active checks if the alert is firing and not acknowledged.
+func (r *AlertRecord) Active() bool {
+	return r.State == alert.Firing && r.ResolvedAt.IsZero() && r.AckedBy == ""
+}
+
+// alertButtons returns the inline keyboard attached to an alert message in the language of l
+func (b *Bot) alertButtons(l *i18n.Locale, a alert.Alert) tgbotapi.InlineKeyboardMarkup {
+	row := []tgbotapi.InlineKeyboardButton{}
+	if a.State == alert.Firing {
+		row = append(row, b.NewButton(l.T("alert.ack"), "ack", a.ID))
+	}
+	row = append(row,
+		b.NewButton(l.T("alert.mute"), "mute", a.ID),
+		b.NewButton(l.T("alert.plot"), "plot", plotMetric(a), plotRange),
+	)
+
+	return tgbotapi.NewInlineKeyboardMarkup(row)
+}
+
+// groupButtons returns the inline keyboard attached to a message with several alerts in the language of l, one row per alert
+func (b *Bot) groupButtons(l *i18n.Locale, alerts []alert.Alert) tgbotapi.InlineKeyboardMarkup {
+	rows := [][]tgbotapi.InlineKeyboardButton{}
+	for _, a := range alerts {
+		row := []tgbotapi.InlineKeyboardButton{}
+		if a.State == alert.Firing {
+			row = append(row, b.NewButton(l.T("alert.ack_id", "id", a.ID), "ack", a.ID))
+		}
+		row = append(row,
+			b.NewButton(l.T("alert.mute_id", "id", a.ID), "mute", a.ID),
+			b.NewButton(l.T("alert.plot_id", "id", a.ID), "plot", plotMetric(a), plotRange),
+		)
+		rows = append(rows, row)
+	}
+
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// plotRange is the time range of the plot buttons of alerts
+const plotRange = "30m"
+
+// plotMetric returns the metric plotted by the plot button of a, "*" for the default plot
+func plotMetric(a alert.Alert) string {
+	if a.Metric == "" {
+		return "*"
+	}
+	return a.Metric
+}
+
+// record gives the alert an ID and keeps track of it. An alert of a rule that is still firing takes the ID of the firing alert.
+func (b *Bot) record(a alert.Alert) alert.Alert {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// forget old alerts
+	for id, r := range b.alerts {
+		if r.State != alert.Firing || !r.ResolvedAt.IsZero() {
+			if time.Since(r.Time) > keepAlerts {
+				delete(b.alerts, id)
+			}
+		}
+	}
+
+	// a firing alert of the rule is still open
+	if a.State != alert.Notice {
+		for _, r := range b.alerts {
+			if r.State == alert.Firing && r.ResolvedAt.IsZero() && r.Rule == a.Rule {
+				if a.State == alert.Resolved {
+					r.ResolvedAt = a.Time
+				}
+				// the resolution goes to whoever got the alert
+				if a.Severity < r.Severity {
+					a.Severity = r.Severity
+				}
+				a.ID = r.ID
+				return a
+			}
+		}
+	}
+
+	b.alertID++
+	a.ID = strconv.Itoa(b.alertID)
+	b.alerts[a.ID] = &AlertRecord{
+		Alert:      a,
+		RemindedAt: a.Time,
+	}
+
+	return a
+}
+
+// Alert returns a copy of the record of the alert with the given ID.
+func (b *Bot) Alert(id string) (AlertRecord, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	r, ok := b.alerts[id]
+	if !ok {
+		return AlertRecord{}, false
+	}
+	return *r, true
+}
+
+// FiringAlerts returns copies of the records of the alerts still firing, acknowledged or not, oldest first.
+func (b *Bot) FiringAlerts() []AlertRecord {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	res := []AlertRecord{}
+	for _, r := range b.alerts {
+		if r.State == alert.Firing && r.ResolvedAt.IsZero() {
+			res = append(res, *r)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Time.Before(res[j].Time)
+	})
+	return res
+}
+
+// Ack acknowledges the alert with the given ID, it returns false if there is no such alert or it is acknowledged already.
+func (b *Bot) Ack(id string, by string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	r, ok := b.alerts[id]
+	if !ok || r.AckedBy != "" {
+		return false
+	}
+
+	r.AckedBy = by
+	r.AckedAt = time.Now()
+	return true
+}
+
+// Remind resends alerts that are still firing and not acknowledged every interval, and sends them to the secondary subscribers once they have been unacknowledged for escalateAfter. A zero duration disables the respective behavior.
+func (b *Bot) Remind(interval time.Duration, escalateAfter time.Duration) {
+	now := time.Now()
+	reminders := []alert.Alert{}
+	escalations := []alert.Alert{}
+
+	b.mu.Lock()
+	for _, r := range b.alerts {
+		if !r.Active() {
+			continue
+		}
+		if interval > 0 && now.Sub(r.RemindedAt) >= interval {
+			r.RemindedAt = now
+			reminders = append(reminders, r.Alert)
+		}
+		if escalateAfter > 0 && !r.Escalated && now.Sub(r.Time) >= escalateAfter {
+			r.Escalated = true
+			escalations = append(escalations, r.Alert)
+		}
+	}
+	b.mu.Unlock()
+
+	for _, a := range reminders {
+		if b.matchSilence(a) {
+			continue
+		}
+		b.broadcastAlertMsg(b.recipients(a, now), a, "alert.reminder", now.Sub(a.Time).Round(time.Minute))
+	}
+
+	for _, a := range escalations {
+		if b.matchSilence(a) {
+			continue
+		}
+		b.broadcastAlertMsg(b.secondaryIDs(), a, "alert.escalated", now.Sub(a.Time).Round(time.Minute))
+	}
+}
+
+// broadcastAlertMsg queues the message key about a, unacknowledged for d, with the buttons of a to the given chats
+func (b *Bot) broadcastAlertMsg(chatIDs []int64, a alert.Alert, key string, d time.Duration) {
+	for _, chatID := range chatIDs {
+		l := b.ChatLocale(chatID)
+		markup := b.alertButtons(l, a)
+		b.enqueue(chatID, l.T(key, "for", d, "text", AlertText(l, a)), &markup)
+	}
+}
+
+// subscriberIDs returns the chat IDs of all subscribers
+func (b *Bot) subscriberIDs() []int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ids := make([]int64, 0, len(b.subscribers))
+	for id := range b.subscribers {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// secondaryIDs returns the chat IDs of all secondary subscribers
+func (b *Bot) secondaryIDs() []int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ids := make([]int64, 0, len(b.secondary))
+	for id := range b.secondary {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// ackButton handle the "Ack" button of alert messages
+func ackButton(b *Bot, update tgbotapi.Update) {
+	id := b.ButtonArgs(update).String("id")
+	by := UserName(update)
+
+	if !b.Ack(id, by) {
+		r, ok := b.Alert(id)
+		if !ok {
+			b.Toast(update, b.T(update, "alert.gone", "id", id))
+		} else if r.AckedBy != "" {
+			b.Toast(update, b.T(update, "alert.acked_already", "id", id, "by", r.AckedBy))
+		}
+		return
+	}
+
+	b.BroadcastT("alert.acked", "id", id, "by", by)
+}
+
+// muteButton handle the "Mute 1h" button of alert messages
+func muteButton(b *Bot, update tgbotapi.Update) {
+	id := b.ButtonArgs(update).String("id")
+	r, ok := b.Alert(id)
+	if !ok {
+		b.Toast(update, b.T(update, "alert.gone", "id", id))
+		return
+	}
+
+	s := b.Mute(r.Rule, time.Hour, fmt.Sprintf("muted from alert #%s", r.ID), UserName(update))
+	b.Audit(update, "mute", strconv.Itoa(s.ID), "", s.auditValue())
+	b.Reply(update, "silence.muted", "silence", s.text(b.Locale(update)))
+}
+
+// CmdSecondary handle /secondary command, it toggles the subscription to escalated alerts.
+func CmdSecondary(b *Bot, update tgbotapi.Update, _ Args) {
+	chatID := update.Message.Chat.ID
+
+	b.mu.Lock()
+	_, ok := b.secondary[chatID]
+	if ok {
+		delete(b.secondary, chatID)
+	} else {
+		b.secondary[chatID] = struct{}{}
+	}
+	b.saveState()
+	b.mu.Unlock()
+
+	if ok {
+		b.Reply(update, "secondary.off")
+	} else {
+		b.Reply(update, "secondary.on")
+	}
+}
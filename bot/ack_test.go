@@ -0,0 +1,104 @@
+package bot
+
+import (
+	"monitor/alert"
+	"monitor/bot/bottest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecord(t *testing.T) {
+	b, _ := New(nil, nil)
+	now := time.Now()
+
+	// an alert of a rule that keeps firing keeps its ID until resolved
+	fire := b.record(alert.Alert{State: alert.Firing, Severity: alert.Critical, Rule: "cpu", Time: now})
+	again := b.record(alert.Alert{State: alert.Firing, Severity: alert.Warning, Rule: "cpu", Time: now})
+	resolve := b.record(alert.Alert{State: alert.Resolved, Rule: "cpu", Time: now})
+	if fire.ID != "1" || again.ID != fire.ID || resolve.ID != fire.ID {
+		t.Fatalf("IDs %s, %s, %s, want the same", fire.ID, again.ID, resolve.ID)
+	}
+	if resolve.Severity != alert.Critical {
+		t.Errorf("resolution severity %s, want that of the alert so that it reaches the same chats", resolve.Severity)
+	}
+	if r, _ := b.Alert(fire.ID); r.ResolvedAt.IsZero() || r.Active() {
+		t.Error("the alert should be resolved")
+	}
+
+	if next := b.record(alert.Alert{State: alert.Firing, Rule: "cpu", Time: now}); next.ID != "2" {
+		t.Errorf("ID after resolution %s, want a new one", next.ID)
+	}
+	if notice := b.record(alert.Alert{State: alert.Notice, Rule: "cpu", Time: now}); notice.ID != "3" {
+		t.Errorf("notice ID %s, want a new one", notice.ID)
+	}
+}
+
+// countText returns how many messages to chatID contain text
+func countText(srv *bottest.Server, chatID int64, text string) int {
+	n := 0
+	for _, r := range srv.Requests() {
+		if r.Method == "sendMessage" && r.ChatID() == chatID && strings.Contains(r.Text(), text) {
+			n++
+		}
+	}
+	return n
+}
+
+func TestE2ERemind(t *testing.T) {
+	b, srv := startBot(t)
+	b.Subscribe(10)
+	b.mu.Lock()
+	b.secondary[20] = struct{}{}
+	b.mu.Unlock()
+
+	old := time.Now().Add(-2 * time.Hour)
+	b.BroadcastAlerts(alert.Alert{State: alert.Firing, Severity: alert.Warning, Rule: "cpu", Text: "cpu is high", Time: old})
+	b.BroadcastAlerts(alert.Alert{State: alert.Firing, Severity: alert.Warning, Rule: "mem", Text: "mem is high", Time: old})
+	srv.WaitText(10, "mem is high")
+	if !b.Ack("2", "alice") || b.Ack("2", "bob") {
+		t.Fatal("an alert should be acknowledged once")
+	}
+
+	// unacknowledged alerts are resent, and escalated once after escalate_after
+	b.Remind(time.Hour, 90*time.Minute)
+	srv.WaitText(10, "Reminder, unacknowledged for 2h:\ncpu is high")
+	srv.WaitText(20, "Escalated, unacknowledged for 2h:\ncpu is high")
+	b.Remind(time.Hour, 90*time.Minute)
+	b.Remind(0, 0)
+
+	// give the queue time to send anything else
+	time.Sleep(200 * time.Millisecond)
+	if n := countText(srv, 10, "Reminder"); n != 1 {
+		t.Errorf("%d reminders, want one per interval", n)
+	}
+	if n := countText(srv, 20, "Escalated"); n != 1 {
+		t.Errorf("%d escalations, want one", n)
+	}
+	if n := countText(srv, 10, "mem is high") + countText(srv, 20, "mem is high"); n != 1 {
+		t.Errorf("the acknowledged alert was sent %d times, want no reminder", n)
+	}
+}
+
+func TestE2EAckButton(t *testing.T) {
+	b, srv := startBot(t)
+	b.Grant(10, Operator)
+	b.Subscribe(10)
+
+	b.BroadcastAlerts(alert.Alert{State: alert.Firing, Severity: alert.Warning, Rule: "cpu", Text: "cpu is high", Time: time.Now()})
+	ack := srv.WaitText(10, "cpu is high").Buttons()[0]
+
+	srv.Press(10, ack)
+	srv.WaitText(10, "Alert #1 acknowledged by")
+	if r, _ := b.Alert("1"); r.AckedBy == "" || r.Active() {
+		t.Errorf("record %+v, want acknowledged", r)
+	}
+
+	id := srv.Press(10, ack)
+	toast := srv.Wait(func(r bottest.Request) bool {
+		return r.Method == "answerCallbackQuery" && r.Params["callback_query_id"] == id
+	})
+	if !strings.Contains(toast.Text(), "was acknowledged by") || !strings.HasSuffix(toast.Text(), "already") {
+		t.Errorf("second ack answered %q, want acknowledged already", toast.Text())
+	}
+}
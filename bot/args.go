@@ -0,0 +1,416 @@
+package bot
+
+import (
+	"fmt"
+	"monitor/i18n"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Kind is the type of a command argument.
+type Kind int
+
+const (
+	KindString   Kind = iota // one word
+	KindText                 // the rest of the message, must be the last positional argument
+	KindInt                  // integer
+	KindFloat                // floating point number
+	KindDuration             // duration like 30m or 2h, see time.ParseDuration
+	KindEnum                 // one of the choices
+	KindBool                 // flag without value
+)
+
+// kindNames are the keys of the names of the kinds in the i18n catalogs
+var kindNames = map[Kind]string{
+	KindString:   "kind.string",
+	KindText:     "kind.text",
+	KindInt:      "kind.int",
+	KindFloat:    "kind.float",
+	KindDuration: "kind.duration",
+	KindEnum:     "kind.enum",
+	KindBool:     "kind.bool",
+}
+
+// Param is the declaration of a command argument, positional unless it is a flag.
+type Param struct {
+	Name     string
+	Kind     Kind
+	Help     string
+	Choices  []string // allowed values of KindEnum
+	Def      string   // default value, used when an optional argument is missing
+	Optional bool
+	Flag     bool // given as --name value, or --name for KindBool
+}
+
+// StringArg declares a one word argument.
+func StringArg(name string, help string) Param {
+	return Param{Name: name, Kind: KindString, Help: help}
+}
+
+// TextArg declares an argument taking the rest of the message.
+func TextArg(name string, help string) Param {
+	return Param{Name: name, Kind: KindText, Help: help}
+}
+
+// IntArg declares an integer argument.
+func IntArg(name string, help string) Param {
+	return Param{Name: name, Kind: KindInt, Help: help}
+}
+
+// FloatArg declares a floating point argument.
+func FloatArg(name string, help string) Param {
+	return Param{Name: name, Kind: KindFloat, Help: help}
+}
+
+// DurationArg declares a duration argument, e.g. 30m.
+func DurationArg(name string, help string) Param {
+	return Param{Name: name, Kind: KindDuration, Help: help}
+}
+
+// EnumArg declares an argument that is one of choices.
+func EnumArg(name string, help string, choices ...string) Param {
+	return Param{Name: name, Kind: KindEnum, Help: help, Choices: choices}
+}
+
+// BoolFlag declares a flag without value, e.g. --pin.
+func BoolFlag(name string, help string) Param {
+	return Param{Name: name, Kind: KindBool, Help: help, Optional: true, Flag: true, Def: "false"}
+}
+
+// Default makes the argument optional with the given default value.
+func (p Param) Default(v string) Param {
+	p.Def = v
+	p.Optional = true
+	return p
+}
+
+// Opt makes the argument optional without default value.
+func (p Param) Opt() Param {
+	p.Optional = true
+	return p
+}
+
+// AsFlag makes the argument an optional flag given as --name value.
+func (p Param) AsFlag() Param {
+	p.Flag = true
+	p.Optional = true
+	return p
+}
+
+// parse converts s to the type of the argument
+func (p Param) parse(s string) (interface{}, error) {
+	switch p.Kind {
+	case KindInt:
+		i, err := strconv.Atoi(s)
+		if err != nil {
+			return nil, p.errorf(s, "args.not_kind")
+		}
+		return i, nil
+	case KindFloat:
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, p.errorf(s, "args.not_kind")
+		}
+		return f, nil
+	case KindDuration:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return nil, p.errorf(s, "args.not_kind")
+		}
+		return d, nil
+	case KindEnum:
+		resolved, suggestions := resolve(s, p.Choices)
+		if resolved != "" {
+			return resolved, nil
+		}
+		return nil, &ArgError{Param: p, Value: s, Reason: "args.choose", Suggestions: suggestions}
+	case KindBool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return nil, p.errorf(s, "args.not_kind")
+		}
+		return b, nil
+	}
+	return s, nil
+}
+
+func (p Param) errorf(value string, reason string) error {
+	return &ArgError{Param: p, Value: value, Reason: reason}
+}
+
+// usage returns the argument as shown in the usage line
+func (p Param) usage() string {
+	var s string
+	switch {
+	case p.Kind == KindBool:
+		s = "--" + p.Name
+	case p.Flag:
+		s = fmt.Sprintf("--%s <%s>", p.Name, p.Name)
+	case p.Kind == KindEnum && len(p.Choices) <= 4:
+		s = strings.Join(p.Choices, "|")
+	default:
+		s = "<" + p.Name + ">"
+	}
+
+	if !p.Optional {
+		return s
+	}
+	if p.Def != "" && p.Kind != KindBool {
+		return fmt.Sprintf("[%s=%s]", strings.Trim(s, "<>"), p.Def)
+	}
+	return "[" + s + "]"
+}
+
+// ArgError is an invalid or missing command argument.
+type ArgError struct {
+	Param       Param
+	Value       string
+	Reason      string   // key of the reason in the i18n catalogs, e.g. "args.missing"
+	Suggestions []string // close valid values, e.g. "mem" for "memory"
+}
+
+func (e *ArgError) Error() string {
+	return e.Localize(i18n.Default)
+}
+
+// Localize returns the error in the language of l.
+func (e *ArgError) Localize(l *i18n.Locale) string {
+	reason := l.T(e.Reason, "kind", l.T(kindNames[e.Param.Kind]), "choices", strings.Join(e.Param.Choices, ", "))
+	if e.Value == "" {
+		return l.T("args.missing_arg", "name", e.Param.Name, "reason", reason)
+	}
+	return l.T("args.invalid", "name", e.Param.Name, "value", e.Value, "reason", reason)
+}
+
+// Args are the parsed arguments of a command by name.
+type Args map[string]interface{}
+
+// Has checks if the argument was given or has a default value.
+func (a Args) Has(name string) bool {
+	_, ok := a[name]
+	return ok
+}
+
+// String returns a string, text or enum argument.
+func (a Args) String(name string) string {
+	s, _ := a[name].(string)
+	return s
+}
+
+// Int returns an integer argument.
+func (a Args) Int(name string) int {
+	i, _ := a[name].(int)
+	return i
+}
+
+// Float returns a floating point argument.
+func (a Args) Float(name string) float64 {
+	f, _ := a[name].(float64)
+	return f
+}
+
+// Duration returns a duration argument.
+func (a Args) Duration(name string) time.Duration {
+	d, _ := a[name].(time.Duration)
+	return d
+}
+
+// Bool returns a flag without value.
+func (a Args) Bool(name string) bool {
+	b, _ := a[name].(bool)
+	return b
+}
+
+// Args declares the arguments of the command, they are parsed and validated before the handler is called.
+func (c *Cmd) Args(params ...Param) *Cmd {
+	c.Params = params
+	return c
+}
+
+// Subcommand is a command under another one, e.g. add in /rule add.
+type Subcommand struct {
+	Name    string
+	Handler CmdHandleFunc
+	Params  []Param
+}
+
+// Sub adds a subcommand taking params. It runs instead of the handler of the command when the first argument names it, subcommands can be abbreviated like commands. Other first arguments are parsed as the arguments of the command, if it declares any.
+func (c *Cmd) Sub(name string, f CmdHandleFunc, params ...Param) *Cmd {
+	c.Subs = append(c.Subs, Subcommand{Name: name, Handler: f, Params: params})
+	return c
+}
+
+// usage returns the usage of the subcommand of the command named cmd, e.g. "/rule"
+func (s *Subcommand) usage(cmd string) string {
+	return usage(cmd+" "+s.Name, s.Params)
+}
+
+// Usage returns the usage of the command with its arguments, one line per subcommand if it has any.
+func (c *Cmd) Usage(name string) string {
+	if len(c.Subs) == 0 {
+		return usage("/"+name, c.Params)
+	}
+
+	lines := []string{}
+	if len(c.Params) > 0 {
+		lines = append(lines, usage("/"+name, c.Params))
+	}
+	for _, s := range c.Subs {
+		lines = append(lines, s.usage("/"+name))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// usage returns the usage line of cmd taking params, followed by the help of the params
+func usage(cmd string, params []Param) string {
+	var sb strings.Builder
+	sb.WriteString(cmd)
+	for _, p := range params {
+		sb.WriteString(" " + p.usage())
+	}
+
+	for _, p := range params {
+		if p.Help == "" {
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("\n  %s: %s", p.Name, p.Help))
+		if p.Kind == KindEnum && len(p.Choices) > 4 {
+			sb.WriteString(" (" + strings.Join(p.Choices, ", ") + ")")
+		}
+	}
+
+	return sb.String()
+}
+
+// Parse parses the arguments of the command in text, which is everything after the command.
+func (c *Cmd) Parse(text string) (Args, error) {
+	_, args, err := c.parse(text)
+	return args, err
+}
+
+// parse parses the arguments in text of the command, or of the subcommand named by the first argument which is returned as well
+func (c *Cmd) parse(text string) (*Subcommand, Args, error) {
+	word, rest, _ := strings.Cut(strings.TrimSpace(text), " ")
+	if word == "" || len(c.Subs) == 0 {
+		args, err := parseParams(c.Params, text)
+		return nil, args, err
+	}
+
+	names := make([]string, len(c.Subs))
+	for i, s := range c.Subs {
+		names[i] = s.Name
+	}
+	name, err := EnumArg("subcommand", "", names...).parse(word)
+	if err != nil {
+		if len(c.Params) == 0 {
+			return nil, nil, err
+		}
+		args, err := parseParams(c.Params, text)
+		return nil, args, err
+	}
+
+	for i := range c.Subs {
+		if s := &c.Subs[i]; s.Name == name {
+			args, err := parseParams(s.Params, rest)
+			return s, args, err
+		}
+	}
+	panic("unreachable")
+}
+
+// parseParams parses text as the arguments declared by params. Optional positional arguments are only given the words left over by the required ones, so an optional argument may come first, e.g. [user] <role>.
+func parseParams(params []Param, text string) (Args, error) {
+	args := Args{}
+	words := strings.Fields(text)
+
+	positional := []Param{}
+	flags := map[string]Param{}
+	for _, p := range params {
+		if p.Flag {
+			flags[p.Name] = p
+		} else {
+			positional = append(positional, p)
+		}
+	}
+
+	rest := []string{}
+	for i := 0; i < len(words); i++ {
+		w := words[i]
+		if !strings.HasPrefix(w, "--") || len(w) == 2 {
+			rest = append(rest, w)
+			continue
+		}
+
+		name, value, hasValue := strings.Cut(w[2:], "=")
+		p, ok := flags[name]
+		if !ok {
+			return nil, i18n.Errorf("args.unknown_flag", "flag", name)
+		}
+		if p.Kind == KindBool && !hasValue {
+			value = "true"
+		} else if !hasValue {
+			if i+1 >= len(words) {
+				return nil, &ArgError{Param: p, Reason: "args.missing_value"}
+			}
+			i++
+			value = words[i]
+		}
+
+		v, err := p.parse(value)
+		if err != nil {
+			return nil, err
+		}
+		args[name] = v
+	}
+
+	required := 0
+	for _, p := range positional {
+		if !p.Optional {
+			required++
+		}
+	}
+	spare := len(rest) - required // words for the optional arguments
+
+	i := 0
+	for _, p := range positional {
+		if p.Optional && spare <= 0 {
+			continue
+		}
+		if i >= len(rest) {
+			return nil, &ArgError{Param: p, Reason: "args.missing"}
+		}
+		if p.Optional {
+			spare--
+		}
+
+		value := rest[i]
+		if p.Kind == KindText {
+			value = strings.Join(rest[i:], " ")
+			rest = rest[:i+1]
+		}
+		i++
+
+		v, err := p.parse(value)
+		if err != nil {
+			return nil, err
+		}
+		args[p.Name] = v
+	}
+	if len(rest) > i {
+		return nil, i18n.Errorf("args.too_many", "args", strings.Join(rest[i:], " "))
+	}
+
+	// defaults of the missing arguments
+	for _, p := range params {
+		if _, ok := args[p.Name]; !ok && p.Def != "" {
+			v, err := p.parse(p.Def)
+			if err != nil {
+				panic(fmt.Sprintf("invalid default of argument %s: %v", p.Name, err))
+			}
+			args[p.Name] = v
+		}
+	}
+
+	return args, nil
+}
@@ -0,0 +1,112 @@
+package bot
+
+import (
+	"monitor/i18n"
+	"strings"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestParse(t *testing.T) {
+	c := (&Cmd{}).Args(
+		EnumArg("metric", "series", "cpu", "mem"),
+		DurationArg("range", "time range").Default("1h"),
+		IntArg("width", "plot width").AsFlag(),
+		BoolFlag("pin", "pin the message"),
+		TextArg("title", "plot title").Opt(),
+	)
+
+	args, err := c.Parse("cpu --pin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if args.String("metric") != "cpu" || args.Duration("range") != time.Hour || !args.Bool("pin") || args.Has("width") || args.Has("title") {
+		t.Errorf("got %v", args)
+	}
+
+	args, err = c.Parse("mem 30m --width=800 load after deploy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if args.Duration("range") != 30*time.Minute || args.Int("width") != 800 || args.String("title") != "load after deploy" || args.Bool("pin") {
+		t.Errorf("got %v", args)
+	}
+
+	for text, want := range map[string]string{
+		"":                   "metric: missing",
+		"disk":               `invalid metric "disk": choose one of cpu, mem`,
+		"cpu soon":           `invalid range "soon": not a duration`,
+		"cpu 1h --width":     "width: missing value",
+		"cpu 1h --width x":   `invalid width "x": not an integer`,
+		"cpu 1h --color=red": "unknown flag --color",
+	} {
+		if _, err := c.Parse(text); err == nil || !strings.HasPrefix(err.Error(), want) {
+			t.Errorf("Parse(%q) = %v, want %q", text, err, want)
+		}
+	}
+
+	if _, err := (&Cmd{}).Args(IntArg("n", "")).Parse("1 2"); err == nil || err.Error() != "too many arguments: 2" {
+		t.Errorf("got %v", err)
+	}
+
+	_, err = c.Parse("cpu 1h --width x")
+	if got := i18n.TraditionalChinese.Error(err); got != "無效的 width「x」：不是整數" {
+		t.Errorf("localized error = %q", got)
+	}
+
+	want := "/plot cpu|mem [range=1h] [--width <width>] [--pin] [<title>]"
+	if got, _, _ := strings.Cut(c.Usage("plot"), "\n"); got != want {
+		t.Errorf("usage = %q, want %q", got, want)
+	}
+}
+
+func TestParseSub(t *testing.T) {
+	var called string
+	handler := func(name string) CmdHandleFunc {
+		return func(*Bot, tgbotapi.Update, Args) { called = name }
+	}
+	c := (&Cmd{Handler: handler("digest")}).Args(IntArg("minutes", "").Opt()).
+		Sub("off", handler("off"))
+
+	for text, want := range map[string]string{"": "digest", "30": "digest", "off": "off"} {
+		sub, _, err := c.parse(text)
+		if err != nil {
+			t.Fatalf("parse(%q): %v", text, err)
+		}
+		called = ""
+		if sub != nil {
+			sub.Handler(nil, tgbotapi.Update{}, nil)
+		} else {
+			c.Handler(nil, tgbotapi.Update{}, nil)
+		}
+		if called != want {
+			t.Errorf("parse(%q) runs %s, want %s", text, called, want)
+		}
+	}
+	if _, err := c.Parse("soon"); err == nil {
+		t.Error("Parse should reject a word that is neither a subcommand nor a number")
+	}
+
+	grant := (&Cmd{}).Args(IntArg("user", "").Opt(), EnumArg("role", "", "viewer", "admin"))
+	args, err := grant.Parse("admin")
+	if err != nil || args.Has("user") || args.String("role") != "admin" {
+		t.Errorf("Parse(admin) = %v, %v", args, err)
+	}
+	args, err = grant.Parse("42 viewer")
+	if err != nil || args.Int("user") != 42 || args.String("role") != "viewer" {
+		t.Errorf("Parse(42 viewer) = %v, %v", args, err)
+	}
+
+	rule := (&Cmd{}).Sub("del", handler("del"), TextArg("name", "")).Sub("test", handler("test"), TextArg("rule", ""))
+	if sub, args, err := rule.parse("del high cpu"); err != nil || sub.Name != "del" || args.String("name") != "high cpu" {
+		t.Errorf("parse(del high cpu) = %v, %v, %v", sub, args, err)
+	}
+	if _, err := rule.Parse("drop x"); err == nil {
+		t.Error("Parse should reject an unknown subcommand")
+	}
+	if got := rule.Usage("rule"); !strings.Contains(got, "/rule del <name>") || !strings.Contains(got, "/rule test <rule>") {
+		t.Errorf("usage = %q", got)
+	}
+}
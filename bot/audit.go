@@ -0,0 +1,284 @@
+package bot
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// AuditEntry is a recorded change, e.g. a /set or a granted role.
+type AuditEntry struct {
+	ID      int       `json:"id"`
+	Time    time.Time `json:"time"`
+	User    string    `json:"user"`
+	UserID  int64     `json:"user_id"`
+	ChatID  int64     `json:"chat_id"`
+	Action  string    `json:"action"`            // what was done, e.g. "set", "mute", "rule" or "role"
+	Target  string    `json:"target"`            // what was changed, e.g. "cpu_threshold"
+	Old     string    `json:"old"`               // value before the change, empty if there was none
+	New     string    `json:"new"`               // value after the change, empty if it was removed
+	Reverts int       `json:"reverts,omitempty"` // ID of the entry this change reverted
+}
+
+func (e AuditEntry) String() string {
+	old, new := e.Old, e.New
+	if old == "" {
+		old = "-"
+	}
+	if new == "" {
+		new = "-"
+	}
+	s := fmt.Sprintf("#%d %s %s %s %s: %s → %s", e.ID, e.Time.Format("2006-01-02 15:04"), e.User, e.Action, e.Target, old, new)
+	if e.Reverts != 0 {
+		s += fmt.Sprintf(" (reverts #%d)", e.Reverts)
+	}
+	return s
+}
+
+// Reverter undoes a recorded change by restoring e.Old.
+type Reverter func(b *Bot, e AuditEntry) error
+
+// audit is the append-only log of changes, appended to path as JSON lines if path is not empty
+type audit struct {
+	mu        sync.Mutex
+	path      string
+	entries   []AuditEntry
+	reverters map[string]Reverter
+}
+
+func newAudit() *audit {
+	return &audit{
+		entries:   []AuditEntry{},
+		reverters: map[string]Reverter{},
+	}
+}
+
+// PersistAudit loads the audit log at path and appends every new entry to it.
+func (b *Bot) PersistAudit(path string) error {
+	a := b.audit
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.path = path
+
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	saved := []AuditEntry{}
+	s := bufio.NewScanner(f)
+	s.Buffer(nil, 1<<20)
+	for s.Scan() {
+		var e AuditEntry
+		if err := json.Unmarshal(s.Bytes(), &e); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		saved = append(saved, e)
+	}
+	if err := s.Err(); err != nil {
+		return err
+	}
+
+	// entries recorded before loading come after the saved ones
+	for i := range a.entries {
+		a.entries[i].ID += len(saved)
+	}
+	a.entries = append(saved, a.entries...)
+	for _, e := range a.entries[len(saved):] {
+		a.append(e)
+	}
+
+	return nil
+}
+
+// append writes e to the end of the log file, it must be called with a.mu held
+func (a *audit) append(e AuditEntry) {
+	if a.path == "" {
+		return
+	}
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		log.Printf("audit: %v\n", err)
+		return
+	}
+
+	f, err := os.OpenFile(a.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		log.Printf("audit: %v\n", err)
+		return
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		log.Printf("audit: %v\n", err)
+	}
+}
+
+// record adds e to the log and returns it with its ID
+func (a *audit) record(e AuditEntry) AuditEntry {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	e.ID = len(a.entries) + 1
+	a.entries = append(a.entries, e)
+	a.append(e)
+
+	return e
+}
+
+// auditEntry returns the entry of a change made by the sender of update
+func auditEntry(update tgbotapi.Update, action string, target string, old string, new string) AuditEntry {
+	e := AuditEntry{
+		Time:   time.Now(),
+		User:   UserName(update),
+		Action: action,
+		Target: target,
+		Old:    old,
+		New:    new,
+	}
+	if u := update.SentFrom(); u != nil {
+		e.UserID = u.ID
+	}
+	if c := update.FromChat(); c != nil {
+		e.ChatID = c.ID
+	}
+	return e
+}
+
+// Audit records a change made by the sender of update, old and new are the values before and after the change.
+func (b *Bot) Audit(update tgbotapi.Update, action string, target string, old string, new string) AuditEntry {
+	return b.audit.record(auditEntry(update, action, target, old, new))
+}
+
+// AuditLog returns the last n entries of the audit log, oldest first.
+func (b *Bot) AuditLog(n int) []AuditEntry {
+	a := b.audit
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if n > len(a.entries) {
+		n = len(a.entries)
+	}
+	return append([]AuditEntry{}, a.entries[len(a.entries)-n:]...)
+}
+
+// AddReverter sets how changes of the given action are reverted.
+func (b *Bot) AddReverter(action string, f Reverter) {
+	b.audit.mu.Lock()
+	defer b.audit.mu.Unlock()
+
+	b.audit.reverters[action] = f
+}
+
+// Revert reverts the change with the given ID on behalf of the sender of update, and records the revert.
+func (b *Bot) Revert(id int, update tgbotapi.Update) (AuditEntry, error) {
+	a := b.audit
+	a.mu.Lock()
+	if id < 1 || id > len(a.entries) {
+		a.mu.Unlock()
+		return AuditEntry{}, fmt.Errorf("no audit entry #%d", id)
+	}
+	e := a.entries[id-1]
+	f, ok := a.reverters[e.Action]
+	a.mu.Unlock()
+
+	if !ok {
+		return AuditEntry{}, fmt.Errorf("%s changes can not be reverted", e.Action)
+	}
+	if err := f(b, e); err != nil {
+		return AuditEntry{}, err
+	}
+
+	r := auditEntry(update, e.Action, e.Target, e.New, e.Old)
+	r.Reverts = e.ID
+	return a.record(r), nil
+}
+
+// revertRole restores the role of a user
+func revertRole(b *Bot, e AuditEntry) error {
+	id, err := strconv.ParseInt(e.Target, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid user ID %q", e.Target)
+	}
+
+	role := Guest
+	if e.Old != "" {
+		if err := role.UnmarshalText([]byte(e.Old)); err != nil {
+			return err
+		}
+	}
+
+	b.Grant(id, role)
+	return nil
+}
+
+// revertMute ends the silence created by a mute, the target is the silence ID
+func revertMute(b *Bot, e AuditEntry) error {
+	if e.New == "" {
+		return errors.New("an ended silence can not be restored")
+	}
+
+	id, err := strconv.Atoi(e.Target)
+	if err != nil {
+		return fmt.Errorf("invalid silence ID %q", e.Target)
+	}
+	if !b.Unmute(id) {
+		return fmt.Errorf("silence #%d already ended", id)
+	}
+	return nil
+}
+
+// CmdAudit handle /audit command, it shows the last "n" entries of the audit log.
+func CmdAudit(b *Bot, update tgbotapi.Update, args Args) {
+	chatID := update.Message.Chat.ID
+
+	n := args.Int("n")
+	if n <= 0 {
+		b.Reply(update, "audit.invalid_n")
+		return
+	}
+
+	entries := b.AuditLog(n)
+	if len(entries) == 0 {
+		b.Reply(update, "audit.empty")
+		return
+	}
+
+	l := b.Locale(update)
+	var sb strings.Builder
+	sb.WriteString(l.T("audit.header") + "\n")
+	for _, e := range entries {
+		sb.WriteString(e.String() + "\n")
+	}
+	sb.WriteString("\n" + l.T("audit.footer"))
+
+	b.SendMsg(chatID, sb.String())
+}
+
+// CmdRevert handle /revert command, it reverts the change of the audit log with the "id" argument.
+func CmdRevert(b *Bot, update tgbotapi.Update, args Args) {
+	id := args.Int("id")
+
+	e, err := b.Revert(id, update)
+	if err != nil {
+		b.Reply(update, "audit.revert_failed", "id", id, "err", err)
+		return
+	}
+
+	b.Reply(update, "audit.reverted", "entry", e.String())
+}
@@ -0,0 +1,59 @@
+package bot
+
+import (
+	"path/filepath"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// fromUser returns a message update sent by the given user
+func fromUser(id int64) tgbotapi.Update {
+	return tgbotapi.Update{Message: &tgbotapi.Message{
+		From: &tgbotapi.User{ID: id, UserName: "admin"},
+		Chat: &tgbotapi.Chat{ID: id},
+	}}
+}
+
+func TestAudit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	u := fromUser(1)
+
+	b, _ := New(nil, nil)
+	if err := b.PersistAudit(path); err != nil {
+		t.Fatal(err)
+	}
+	b.Grant(2, Operator)
+	b.Audit(u, "role", "2", "", "operator")
+	b.Audit(u, "set", "cpu_threshold", "75", "90")
+
+	// reload the log, the IDs continue
+	b, _ = New(nil, nil)
+	b.Grant(2, Operator)
+	if err := b.PersistAudit(path); err != nil {
+		t.Fatal(err)
+	}
+	if log := b.AuditLog(10); len(log) != 2 || log[0].ID != 1 || log[1].Target != "cpu_threshold" || log[0].UserID != 1 {
+		t.Fatalf("AuditLog = %+v", log)
+	}
+
+	if _, err := b.Revert(2, u); err == nil {
+		t.Error("Revert should fail without a reverter")
+	}
+
+	r, err := b.Revert(1, u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.ID != 3 || r.Reverts != 1 || r.Old != "operator" || r.New != "" {
+		t.Errorf("revert entry = %+v", r)
+	}
+	if b.Role(2) != Guest {
+		t.Errorf("role after revert = %s, want guest", b.Role(2))
+	}
+
+	// the revert itself can be reverted
+	if _, err := b.Revert(3, u); err != nil || b.Role(2) != Operator {
+		t.Errorf("revert of revert: %v, role %s", err, b.Role(2))
+	}
+}
@@ -1,77 +1,206 @@
 package bot
 
 import (
+	"context"
 	"log"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
 
 type UpdateHandleFunc func(*Bot, tgbotapi.Update)
 
+// CmdHandleFunc handles a command with its arguments, parsed and validated as declared with Cmd.Args.
+type CmdHandleFunc func(b *Bot, update tgbotapi.Update, args Args)
+
+// Transport is the part of the Telegram Bot API the bot uses. *tgbotapi.BotAPI implements it, tests can point one to a fake server, see bottest.
+type Transport interface {
+	Send(c tgbotapi.Chattable) (tgbotapi.Message, error)
+	Request(c tgbotapi.Chattable) (*tgbotapi.APIResponse, error)
+	GetUpdatesChan(config tgbotapi.UpdateConfig) tgbotapi.UpdatesChannel
+	StopReceivingUpdates()
+	// MakeRequest calls a method tgbotapi has no config for.
+	MakeRequest(endpoint string, params tgbotapi.Params) (*tgbotapi.APIResponse, error)
+}
+
 type Cmd struct {
-	Handler     UpdateHandleFunc
+	Handler     CmdHandleFunc
 	Description string
 	Hide        bool
-}
+	Role        Role         // role needed to run the command
+	Params      []Param      // arguments of the command, see Args
+	Subs        []Subcommand // subcommands, see Sub
+	Scope       Scope        // kinds of chat the command works in
 
-type Wait struct {
-	Key  string
-	Hook UpdateHandleFunc
+	Descriptions map[string]string // description in the command menu by language, see Describe
+	bot          *Bot
 }
 
 type Subscriber struct {
-	Value map[string]interface{}
+	Value  map[string]interface{} `json:"value"`
+	Digest time.Duration          `json:"digest"`           // send low severity alerts as a digest every Digest instead of immediately, 0 to disable
+	Prefs  Prefs                  `json:"prefs"`            // which alerts to send and when
+	Thread int                    `json:"thread,omitempty"` // forum topic to post to, 0 for the general topic or chats without topics
+	Lang   string                 `json:"lang,omitempty"`   // language set with /lang, see i18n.Match
+	// language of the Telegram app of the user in private chats, used unless Lang is set
+	LanguageCode string `json:"language_code,omitempty"`
 }
 
 type Bot struct {
-	Bot         *tgbotapi.BotAPI
+	Bot         Transport
 	subscribers map[int64]Subscriber
-	cmd         map[string]Cmd
-	button      map[string]UpdateHandleFunc
-	wait        map[int64]Wait
+	cmd         map[string]*Cmd
+	button      map[string]*Button
+	forms       map[int64]*conversation
+	formID      int64 // of the last form started
+	mu          sync.Mutex
+	silences    map[int]*Silence
+	silenceID   int
+	alerts      map[string]*AlertRecord
+	alertID     int
+	secondary   map[int64]struct{}
+	queue       *queue
+	limiter     *limiter
+	digests     map[int64]*digest
+	store       Store
+	roles       map[int64]Role
+	rolesPath   string
+	audit       *audit
+	tokens      tokens
+	answered    map[string]bool // button presses answered with a toast
+	middlewares []Middleware
+	webhook     *webhook
+	username    string // of the bot, to tell /cmd@bot from commands for other bots
+	inline      *Inline
+	menu        menu
+	// description of /help in the command menu by language
+	helpDescriptions map[string]string
+	dashboards       map[string]*dashboard
+	dashboardID      int
+	languages        map[int64]string // language of the Telegram app of users by private chat
+	handlers         sync.WaitGroup   // handlers in progress
+	lastUpdate       atomic.Int64     // ID of the last update received
 }
 
-func New(bot *tgbotapi.BotAPI, err error) (*Bot, error) {
+func New(bot Transport, err error) (*Bot, error) {
 	if err != nil {
 		return nil, err
 	}
 	b := &Bot{
 		Bot:         bot,
 		subscribers: map[int64]Subscriber{},
-		cmd:         map[string]Cmd{},
-		button:      map[string]UpdateHandleFunc{},
-		wait:        map[int64]Wait{},
+		cmd:         map[string]*Cmd{},
+		button:      map[string]*Button{},
+		forms:       map[int64]*conversation{},
+		silences:    map[int]*Silence{},
+		alerts:      map[string]*AlertRecord{},
+		secondary:   map[int64]struct{}{},
+		queue:       newQueue(),
+		limiter:     newLimiter(),
+		digests:     map[int64]*digest{},
+		roles:       map[int64]Role{},
+		audit:       newAudit(),
+		tokens:      tokens{entries: map[string]token{}},
+		answered:    map[string]bool{},
+		dashboards:  map[string]*dashboard{},
+		languages:   map[int64]string{},
+		menu:        menu{synced: map[string]menuEntry{}},
+
+		helpDescriptions: map[string]string{},
+	}
+
+	if api, ok := bot.(*tgbotapi.BotAPI); ok && api != nil {
+		b.username = api.Self.UserName
 	}
 
-	b.Subscribe(901756183) // @simbafs, for testing
+	go b.runQueue()
+
+	b.AddButton("ack:{id}", ackButton).Require(Operator).Expire(keepAlerts)
+	b.AddButton("mute:{id}", muteButton).Require(Operator).Expire(keepAlerts)
+	b.AddButton("run:{cmd}", runButton).Require(Guest).Expire(time.Hour)
+	b.AddButton("form:{id}:{step}:{choice}", formButton).Require(Guest)
+	b.AddButton("dashboard:{id}", dashboardButton).Expire(maxDashboardLifetime)
+
+	b.AddReverter("role", revertRole)
+	b.AddReverter("mute", revertMute)
 
 	return b, nil
 }
 
-func (b *Bot) HandleUpdates() {
-	u := tgbotapi.NewUpdate(0)
-	u.Timeout = 60
-	updates := b.Bot.GetUpdatesChan(u)
+// HandleUpdates receives and handles updates until ctx is done, by webhook if UseWebhook was called or by long polling otherwise. It returns once the updates in progress are handled, with an error if updates could not be received, e.g. the webhook server failed to listen.
+func (b *Bot) HandleUpdates(ctx context.Context) error {
+	updates, err := b.updates()
+	if err != nil {
+		return err
+	}
 
-	for update := range updates {
-		if update.Message != nil {
-			if update.Message.IsCommand() {
-				go b.HandleCmds(update)
-			} else if update.Message.Text != "" {
-				if b.MatchWatting(update) {
-					// TODO
+	b.startMenu()
+	defer b.stopMenu()
 
-					continue
+	for {
+		select {
+		case <-ctx.Done():
+			b.stopUpdates()
+			if b.webhook != nil {
+				// Telegram was told these were received, they would be lost otherwise
+				for update := range updates {
+					b.handle(update)
 				}
+			}
+			b.handlers.Wait()
+			return nil
 
-				log.Printf("unknown message: %s\n", update.Message.Text)
-			} else {
-				log.Printf("unknown message: %v\n", update.Message)
+		case update, ok := <-updates:
+			if !ok {
+				b.handlers.Wait()
+				return b.updatesErr()
 			}
-		} else if update.CallbackQuery != nil {
-			go b.HandleButton(update)
+			b.handle(update)
+		}
+	}
+}
+
+// handle dispatches an update to its handler
+func (b *Bot) handle(update tgbotapi.Update) {
+	b.lastUpdate.Store(int64(update.UpdateID))
+	b.noteLanguage(update)
+
+	if update.Message != nil {
+		if update.Message.IsCommand() {
+			b.goServe(update, (*Bot).HandleCmds)
+		} else if update.Message.Text != "" {
+			if b.IsWaiting(update.Message.Chat.ID) {
+				b.serve(update, (*Bot).reply)
+				return
+			}
+
+			log.Printf("unknown message: %s\n", update.Message.Text)
 		} else {
-			log.Printf("unknown update: %v", update)
+			log.Printf("unknown message: %v\n", update.Message)
 		}
+	} else if update.CallbackQuery != nil {
+		b.goServe(update, (*Bot).HandleButton)
+	} else if update.InlineQuery != nil {
+		b.goServe(update, (*Bot).HandleInline)
+	} else {
+		log.Printf("unknown update: %v", update)
+	}
+}
+
+// goServe serves the update in a new goroutine that HandleUpdates waits for before returning
+func (b *Bot) goServe(update tgbotapi.Update, handle UpdateHandleFunc) {
+	b.handlers.Add(1)
+	go func() {
+		defer b.handlers.Done()
+		b.serve(update, handle)
+	}()
+}
+
+// reply feeds a text message to the form in progress in its chat
+func (b *Bot) reply(update tgbotapi.Update) {
+	if !b.MatchWatting(update) {
+		log.Printf("unknown message: %s\n", update.Message.Text)
 	}
 }
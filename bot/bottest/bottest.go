@@ -0,0 +1,407 @@
+// bottest fakes the Telegram Bot API in process to test the bot end to end.
+package bottest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	// Token is the bot token the server accepts.
+	Token = "123:test"
+	// BotID and BotName identify the fake bot.
+	BotID   = 1
+	BotName = "test_bot"
+
+	maxPoll = 200 * time.Millisecond // longest wait of getUpdates, so that the bot stops quickly
+	timeout = 5 * time.Second        // wait of Wait before failing the test
+)
+
+// Request is a call the bot made to the API.
+type Request struct {
+	Method string
+	Params map[string]string // form fields, files are not kept
+	Files  []string          // names of the fields of the uploaded files
+	Error  int               // error code the request was answered with, 0 if it succeeded
+}
+
+// ChatID returns the chat_id parameter.
+func (r Request) ChatID() int64 {
+	id, _ := strconv.ParseInt(r.Params["chat_id"], 10, 64)
+	return id
+}
+
+// Text returns the text parameter.
+func (r Request) Text() string {
+	return r.Params["text"]
+}
+
+// Buttons returns the callback data of the inline keyboard buttons of the request, row by row.
+func (r Request) Buttons() []string {
+	var markup tgbotapi.InlineKeyboardMarkup
+	json.Unmarshal([]byte(r.Params["reply_markup"]), &markup)
+
+	data := []string{}
+	for _, row := range markup.InlineKeyboard {
+		for _, btn := range row {
+			if btn.CallbackData != nil {
+				data = append(data, *btn.CallbackData)
+			}
+		}
+	}
+	return data
+}
+
+// Results returns the results of an answerInlineQuery request.
+func (r Request) Results() []map[string]interface{} {
+	results := []map[string]interface{}{}
+	json.Unmarshal([]byte(r.Params["results"]), &results)
+	return results
+}
+
+// Server is a fake Telegram Bot API server.
+type Server struct {
+	*httptest.Server
+	t         testing.TB
+	mu        sync.Mutex
+	requests  []Request
+	updates   []tgbotapi.Update
+	updateID  int
+	messageID int
+	callback  int              // last ID of callback and inline queries
+	languages map[int64]string // language_code of users, see Language
+	failures  map[int64]*failure
+	wake      chan struct{}
+}
+
+// NewServer starts a fake Telegram Bot API server, it is closed when the test ends.
+func NewServer(t testing.TB) *Server {
+	s := &Server{
+		t:         t,
+		languages: map[int64]string{},
+		failures:  map[int64]*failure{},
+		wake:      make(chan struct{}, 1),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// API returns a client of the server, pass it to bot.New.
+func (s *Server) API() (*tgbotapi.BotAPI, error) {
+	return tgbotapi.NewBotAPIWithAPIEndpoint(Token, s.URL+"/bot%s/%s")
+}
+
+// serve answers a call of a method, and records it if it is not about receiving updates
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	token, method, ok := strings.Cut(strings.TrimPrefix(r.URL.Path, "/bot"), "/")
+	if !ok || token != Token {
+		reply(w, nil, fmt.Errorf("unauthorized"))
+		return
+	}
+
+	req := Request{Method: method, Params: map[string]string{}}
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/") {
+		if err := r.ParseMultipartForm(32 << 20); err != nil {
+			reply(w, nil, err)
+			return
+		}
+		for k, v := range r.MultipartForm.Value {
+			req.Params[k] = v[0]
+		}
+		for k := range r.MultipartForm.File {
+			req.Files = append(req.Files, k)
+		}
+	} else {
+		if err := r.ParseForm(); err != nil {
+			reply(w, nil, err)
+			return
+		}
+		for k, v := range r.PostForm {
+			req.Params[k] = v[0]
+		}
+	}
+
+	switch method {
+	case "getMe":
+		reply(w, tgbotapi.User{ID: BotID, IsBot: true, FirstName: "Test", UserName: BotName}, nil)
+	case "getUpdates":
+		reply(w, s.poll(req), nil)
+	default:
+		s.mu.Lock()
+		f := s.failures[req.ChatID()]
+		if f != nil && f.left > 0 {
+			f.left--
+			req.Error = f.code
+		}
+		s.requests = append(s.requests, req)
+		s.mu.Unlock()
+
+		if req.Error != 0 {
+			f.reply(w)
+			return
+		}
+		reply(w, s.result(req), nil)
+	}
+}
+
+// failure is how the requests about a chat fail, see Fail
+type failure struct {
+	code       int
+	retryAfter int
+	left       int
+}
+
+// reply answers a request with the error as Telegram does
+func (f *failure) reply(w http.ResponseWriter) {
+	res := tgbotapi.APIResponse{Ok: false, ErrorCode: f.code, Description: http.StatusText(f.code)}
+	switch f.code {
+	case http.StatusForbidden:
+		res.Description = "Forbidden: bot was blocked by the user"
+	case http.StatusTooManyRequests:
+		res.Description = fmt.Sprintf("Too Many Requests: retry after %d", f.retryAfter)
+		res.Parameters = &tgbotapi.ResponseParameters{RetryAfter: f.retryAfter}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(f.code)
+	json.NewEncoder(w).Encode(res)
+}
+
+// Fail makes the next n requests about chatID fail with the HTTP error code, e.g. 403 as if the user blocked the bot or 502 as if Telegram was down. For 429, the bot is asked to retry after retryAfter seconds.
+func (s *Server) Fail(chatID int64, n int, code int, retryAfter int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures[chatID] = &failure{code: code, retryAfter: retryAfter, left: n}
+}
+
+// result returns what Telegram would return for req, the sent or edited message for send and edit methods
+func (s *Server) result(req Request) any {
+	if !strings.HasPrefix(req.Method, "send") && !strings.HasPrefix(req.Method, "edit") {
+		return true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id, _ := strconv.Atoi(req.Params["message_id"])
+	if id == 0 {
+		s.messageID++
+		id = s.messageID
+	}
+	m := tgbotapi.Message{
+		MessageID: id,
+		From:      &tgbotapi.User{ID: BotID, IsBot: true, UserName: BotName},
+		Chat:      &tgbotapi.Chat{ID: req.ChatID()},
+		Date:      int(time.Now().Unix()),
+		Text:      req.Text(),
+	}
+	if req.Method == "sendPhoto" {
+		m.Photo = []tgbotapi.PhotoSize{{FileID: fmt.Sprintf("photo%d", id), FileUniqueID: fmt.Sprintf("photo%d", id)}}
+	}
+	return m
+}
+
+// poll returns the updates from the offset of req, waiting a little for new ones if there are none. The updates before the offset are confirmed and dropped.
+func (s *Server) poll(req Request) []tgbotapi.Update {
+	offset, _ := strconv.Atoi(req.Params["offset"])
+	wait, _ := strconv.Atoi(req.Params["timeout"])
+
+	deadline := time.After(min(time.Duration(wait)*time.Second, maxPoll))
+	for {
+		s.mu.Lock()
+		pending := []tgbotapi.Update{}
+		for _, u := range s.updates {
+			if u.UpdateID >= offset {
+				pending = append(pending, u)
+			}
+		}
+		s.updates = pending
+		s.mu.Unlock()
+
+		if len(pending) > 0 {
+			return pending
+		}
+		select {
+		case <-s.wake:
+		case <-deadline:
+			return pending
+		}
+	}
+}
+
+func reply(w http.ResponseWriter, result any, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	if err != nil {
+		json.NewEncoder(w).Encode(tgbotapi.APIResponse{Ok: false, ErrorCode: http.StatusBadRequest, Description: err.Error()})
+		return
+	}
+	data, _ := json.Marshal(result)
+	json.NewEncoder(w).Encode(tgbotapi.APIResponse{Ok: true, Result: data})
+}
+
+// Update injects an update, it gets the next update ID.
+func (s *Server) Update(u tgbotapi.Update) {
+	s.mu.Lock()
+	s.updateID++
+	u.UpdateID = s.updateID
+	s.updates = append(s.updates, u)
+	s.mu.Unlock()
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Language sets the language of the Telegram app of userID, it is sent as language_code in the next updates of the user.
+func (s *Server) Language(userID int64, code string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.languages[userID] = code
+}
+
+// user returns the Telegram user with the given ID, s.mu must be held
+func (s *Server) user(id int64) *tgbotapi.User {
+	return &tgbotapi.User{ID: id, FirstName: fmt.Sprintf("user%d", id), UserName: fmt.Sprintf("user%d", id), LanguageCode: s.languages[id]}
+}
+
+// Message injects a text message sent by userID in their private chat, text starting with / is a command.
+func (s *Server) Message(userID int64, text string) {
+	s.message(&tgbotapi.Chat{ID: userID, Type: "private"}, userID, text)
+}
+
+// GroupMessage injects a text message sent by userID in the supergroup groupID, which should be negative.
+func (s *Server) GroupMessage(groupID int64, userID int64, text string) {
+	s.message(&tgbotapi.Chat{ID: groupID, Type: "supergroup", Title: "group"}, userID, text)
+}
+
+// message injects a text message sent by userID in chat
+func (s *Server) message(chat *tgbotapi.Chat, userID int64, text string) {
+	s.mu.Lock()
+	s.messageID++
+	m := &tgbotapi.Message{
+		MessageID: s.messageID,
+		From:      s.user(userID),
+		Chat:      chat,
+		Date:      int(time.Now().Unix()),
+		Text:      text,
+	}
+	s.mu.Unlock()
+
+	if strings.HasPrefix(text, "/") {
+		cmd, _, _ := strings.Cut(text, " ")
+		m.Entities = []tgbotapi.MessageEntity{{Type: "bot_command", Offset: 0, Length: len(cmd)}}
+	}
+
+	s.Update(tgbotapi.Update{Message: m})
+}
+
+// Press injects a press by userID of the button with the given callback data on a message of the bot in their private chat. It returns the ID of the callback query.
+func (s *Server) Press(userID int64, data string) string {
+	return s.press(&tgbotapi.Chat{ID: userID, Type: "private"}, userID, data)
+}
+
+// GroupPress injects a press by userID of the button with the given callback data on a message of the bot in the supergroup groupID. It returns the ID of the callback query.
+func (s *Server) GroupPress(groupID int64, userID int64, data string) string {
+	return s.press(&tgbotapi.Chat{ID: groupID, Type: "supergroup", Title: "group"}, userID, data)
+}
+
+// press injects a press by userID of a button on the last message in chat
+func (s *Server) press(chat *tgbotapi.Chat, userID int64, data string) string {
+	s.mu.Lock()
+	s.callback++
+	id := strconv.Itoa(s.callback)
+	messageID := s.messageID
+	from, bot := s.user(userID), s.user(BotID)
+	s.mu.Unlock()
+
+	s.Update(tgbotapi.Update{CallbackQuery: &tgbotapi.CallbackQuery{
+		ID:   id,
+		From: from,
+		Message: &tgbotapi.Message{
+			MessageID: messageID,
+			From:      bot,
+			Chat:      chat,
+		},
+		Data: data,
+	}})
+	return id
+}
+
+// Inline injects an inline query of userID, it returns the ID of the query.
+func (s *Server) Inline(userID int64, query string) string {
+	s.mu.Lock()
+	s.callback++
+	id := strconv.Itoa(s.callback)
+	from := s.user(userID)
+	s.mu.Unlock()
+
+	s.Update(tgbotapi.Update{InlineQuery: &tgbotapi.InlineQuery{
+		ID:    id,
+		From:  from,
+		Query: query,
+	}})
+	return id
+}
+
+// Requests returns the requests recorded so far.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Request{}, s.requests...)
+}
+
+// Wait waits for a request matching match and returns the first one, the test fails if none comes in time.
+func (s *Server) Wait(match func(Request) bool) Request {
+	s.t.Helper()
+
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		for _, r := range s.Requests() {
+			if match(r) {
+				return r
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	s.t.Fatalf("no matching request in %s, got %+v", timeout, s.Requests())
+	return Request{}
+}
+
+// WaitText waits for a message to chatID containing text that did not fail, see Fail.
+func (s *Server) WaitText(chatID int64, text string) Request {
+	s.t.Helper()
+
+	return s.Wait(func(r Request) bool {
+		return r.Method == "sendMessage" && r.ChatID() == chatID && strings.Contains(r.Text(), text) && r.Error == 0
+	})
+}
+
+// WaitMethod waits for a call of method about chatID.
+func (s *Server) WaitMethod(chatID int64, method string) Request {
+	s.t.Helper()
+
+	return s.Wait(func(r Request) bool {
+		return r.Method == method && r.ChatID() == chatID
+	})
+}
+
+// Reset forgets the recorded requests.
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests = nil
+}
@@ -1,20 +1,259 @@
 package bot
 
-import tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+import (
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+	"time"
 
-func (b *Bot) AddButton(data string, f UpdateHandleFunc) {
-	b.button[data] = f
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	maxCallbackData = 64             // Telegram's limit of the callback data of a button
+	tokenPrefix     = "~"            // callback data referring to the token table
+	tokenAge        = 24 * time.Hour // how long a token lives if its button does not expire
+	maxTokens       = 10000          // tokens kept at most, the oldest are dropped first
+)
+
+var (
+	errUnknownButton = errors.New("unknown button")
+	errExpired       = errors.New("this button has expired")
+	errInvalidButton = errors.New("invalid button")
+
+	// keys of the errors of route in the i18n catalogs
+	buttonErrors = map[error]string{
+		errUnknownButton: "button.unknown",
+		errExpired:       "button.expired",
+		errInvalidButton: "button.invalid",
+	}
+)
+
+// Button is a handler of inline keyboard buttons.
+type Button struct {
+	Handler UpdateHandleFunc
+	Role    Role
+	name    string
+	params  []string
+	ttl     time.Duration
 }
 
-func (b *Bot) HandleButton(update tgbotapi.Update) {
-	data := update.CallbackQuery.Data
-	f, ok := b.button[data]
+// AddButton adds a handler for the buttons matching pattern. A pattern is a name optionally followed by parameters, e.g. "plot:{metric}:{range}", see ButtonData and ButtonArgs. It requires the Viewer role unless set otherwise with Require.
+func (b *Bot) AddButton(pattern string, f UpdateHandleFunc) *Button {
+	seg := strings.Split(pattern, ":")
+	btn := &Button{
+		Handler: f,
+		Role:    Viewer,
+		name:    seg[0],
+	}
+	if btn.name == "" || strings.ContainsAny(btn.name, "{}@"+tokenPrefix) {
+		panic("invalid button name in " + pattern)
+	}
+	for _, p := range seg[1:] {
+		if !strings.HasPrefix(p, "{") || !strings.HasSuffix(p, "}") {
+			panic("invalid button parameter " + p + " in " + pattern)
+		}
+		btn.params = append(btn.params, strings.Trim(p, "{}"))
+	}
+
+	b.mu.Lock()
+	b.button[btn.name] = btn
+	b.mu.Unlock()
+	return btn
+}
+
+// Require sets the role needed to press the button.
+func (btn *Button) Require(role Role) *Button {
+	btn.Role = role
+	return btn
+}
+
+// Expire makes the buttons stop working d after they are created.
+func (btn *Button) Expire(d time.Duration) *Button {
+	btn.ttl = d
+	return btn
+}
+
+// token is callback data too long or complex to fit in a button
+type token struct {
+	name    string
+	values  []string
+	created time.Time
+	expires time.Time
+}
+
+// tokens is the server-side table of callback data that does not fit in a button
+type tokens struct {
+	next    int64
+	entries map[string]token
+	order   []string // IDs oldest first
+}
+
+// add stores tok and returns its ID, it must be called with b.mu held
+func (t *tokens) add(tok token) string {
+	// drop expired and excess tokens, roughly since tokens may live for different durations
+	for len(t.order) > 0 {
+		oldest, ok := t.entries[t.order[0]]
+		if ok && len(t.order) < maxTokens && time.Now().Before(oldest.expires) {
+			break
+		}
+		delete(t.entries, t.order[0])
+		t.order = t.order[1:]
+	}
+
+	t.next++
+	id := strconv.FormatInt(t.next, 36)
+	t.entries[id] = tok
+	t.order = append(t.order, id)
+	return id
+}
+
+// lookupButton returns the button registered as name
+func (b *Bot) lookupButton(name string) (*Button, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	btn, ok := b.button[name]
+	return btn, ok
+}
+
+// ButtonData returns the callback data of a button with the given name and parameter values, in the order of the pattern. Data that does not fit in 64 bytes is kept in a server-side token table.
+func (b *Bot) ButtonData(name string, values ...string) string {
+	btn, ok := b.lookupButton(name)
 	if !ok {
+		log.Printf("button data of unknown button %s\n", name)
+		btn = &Button{}
+	}
+	if len(values) != len(btn.params) && ok {
+		log.Printf("button %s takes %d parameters, got %d\n", name, len(btn.params), len(values))
+	}
+
+	now := time.Now()
+	data := strings.Join(append([]string{name}, values...), ":")
+	if btn.ttl > 0 {
+		data += "@" + strconv.FormatInt(now.Unix(), 36)
+	}
+
+	plain := len(data) <= maxCallbackData && !strings.HasPrefix(data, tokenPrefix)
+	for _, v := range values {
+		if strings.ContainsAny(v, ":@") {
+			plain = false
+		}
+	}
+	if plain {
+		return data
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ttl := btn.ttl
+	if ttl <= 0 {
+		ttl = tokenAge
+	}
+	return tokenPrefix + b.tokens.add(token{name: name, values: values, created: now, expires: now.Add(ttl)})
+}
+
+// NewButton returns an inline keyboard button with the given text running the button name with the given parameter values.
+func (b *Bot) NewButton(text string, name string, values ...string) tgbotapi.InlineKeyboardButton {
+	return tgbotapi.NewInlineKeyboardButtonData(text, b.ButtonData(name, values...))
+}
+
+// route finds the button of callback data and its arguments
+func (b *Bot) route(data string) (*Button, Args, error) {
+	var tok token
+
+	if id, ok := strings.CutPrefix(data, tokenPrefix); ok {
+		b.mu.Lock()
+		tok, ok = b.tokens.entries[id]
+		b.mu.Unlock()
+		if !ok || time.Now().After(tok.expires) {
+			return nil, nil, errExpired
+		}
+	} else {
+		if i := strings.LastIndex(data, "@"); i >= 0 {
+			ts, err := strconv.ParseInt(data[i+1:], 36, 64)
+			if err != nil {
+				return nil, nil, errInvalidButton
+			}
+			tok.created = time.Unix(ts, 0)
+			data = data[:i]
+		}
+		seg := strings.Split(data, ":")
+		tok.name, tok.values = seg[0], seg[1:]
+	}
+
+	btn, ok := b.lookupButton(tok.name)
+	if !ok {
+		return nil, nil, errUnknownButton
+	}
+	if len(tok.values) != len(btn.params) {
+		return nil, nil, errInvalidButton
+	}
+	if btn.ttl > 0 && !tok.created.IsZero() && time.Since(tok.created) > btn.ttl {
+		return nil, nil, errExpired
+	}
+
+	args := Args{}
+	for i, p := range btn.params {
+		args[p] = tok.values[i]
+	}
+	return btn, args, nil
+}
+
+// ButtonArgs returns the parameters of the button pressed in update by name, e.g. {"metric": "cpu", "range": "1h"} for the pattern "plot:{metric}:{range}".
+func (b *Bot) ButtonArgs(update tgbotapi.Update) Args {
+	_, args, err := b.route(update.CallbackQuery.Data)
+	if err != nil {
+		return Args{}
+	}
+	return args
+}
+
+// Toast answers the button press in update with a short notification, e.g. an error. The button is answered with an empty notification otherwise.
+func (b *Bot) Toast(update tgbotapi.Update, text string) {
+	q := update.CallbackQuery
+
+	b.mu.Lock()
+	if _, ok := b.answered[q.ID]; ok {
+		b.answered[q.ID] = true
+	}
+	b.mu.Unlock()
+
+	b.Request(tgbotapi.NewCallback(q.ID, text))
+}
+
+func (b *Bot) HandleButton(update tgbotapi.Update) {
+	q := update.CallbackQuery
+
+	b.mu.Lock()
+	b.answered[q.ID] = false
+	b.mu.Unlock()
+	defer func() {
+		b.mu.Lock()
+		delete(b.answered, q.ID)
+		b.mu.Unlock()
+	}()
+
+	btn, _, err := b.route(q.Data)
+	if err != nil {
+		b.Toast(update, b.T(update, buttonErrors[err]))
 		return
 	}
 
-	f(b, update)
+	if role := b.roleOf(update); role < btn.Role {
+		b.Toast(update, b.denied(btn.Role, role, update))
+		return
+	}
 
-	callback := tgbotapi.NewCallback(update.CallbackQuery.ID, "")
-	b.Request(callback)
+	btn.Handler(b, update)
+
+	// not answered when the handler panics, so that a middleware can answer with the error
+	b.mu.Lock()
+	answered := b.answered[q.ID]
+	b.mu.Unlock()
+	if !answered {
+		b.Request(tgbotapi.NewCallback(q.ID, ""))
+	}
 }
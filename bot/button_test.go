@@ -0,0 +1,50 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestButtonRouter(t *testing.T) {
+	b, _ := New(nil, nil)
+	b.AddButton("plot:{metric}:{range}", nil)
+	b.AddButton("old:{id}", nil).Expire(time.Minute)
+
+	data := b.ButtonData("plot", "cpu", "1h")
+	if data != "plot:cpu:1h" {
+		t.Errorf("data = %q", data)
+	}
+	btn, args, err := b.route(data)
+	if err != nil || btn.name != "plot" || args.String("metric") != "cpu" || args.String("range") != "1h" {
+		t.Errorf("route(%q) = %v, %v, %v", data, btn, args, err)
+	}
+
+	// too long or containing separators, kept in the token table
+	long := strings.Repeat("x", 70)
+	for _, metric := range []string{long, `disk{mount="/a:b"}`} {
+		data := b.ButtonData("plot", metric, "6h")
+		if len(data) > maxCallbackData || !strings.HasPrefix(data, tokenPrefix) {
+			t.Errorf("data = %q, want a token", data)
+		}
+		if _, args, err := b.route(data); err != nil || args.String("metric") != metric {
+			t.Errorf("route(%q) = %v, %v", data, args, err)
+		}
+	}
+
+	for data, want := range map[string]error{
+		"nope:1":       errUnknownButton,
+		"plot:cpu":     errInvalidButton,
+		"~zzz":         errExpired,
+		"old:1@1":      errExpired, // created in 1970
+		"old:1@not36!": errInvalidButton,
+	} {
+		if _, _, err := b.route(data); err != want {
+			t.Errorf("route(%q) = %v, want %v", data, err, want)
+		}
+	}
+
+	if _, args, err := b.route(b.ButtonData("old", "1")); err != nil || args.String("id") != "1" {
+		t.Errorf("fresh expiring button: %v, %v", args, err)
+	}
+}
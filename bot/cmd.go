@@ -1,9 +1,17 @@
 package bot
 
-import tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+import (
+	"errors"
+	"fmt"
+	"monitor/i18n"
+	"sort"
+	"strings"
 
-// AddCmd adds a command to the bot
-func (b *Bot) AddCmd(cmd string, description string, hide bool, f UpdateHandleFunc) {
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// AddCmd adds a command to the bot, it requires the Viewer role unless set otherwise with Require
+func (b *Bot) AddCmd(cmd string, description string, hide bool, f CmdHandleFunc) *Cmd {
 	// if cmd contain uppercase, panic
 	for _, c := range cmd {
 		if c >= 'A' && c <= 'Z' {
@@ -11,38 +19,148 @@ func (b *Bot) AddCmd(cmd string, description string, hide bool, f UpdateHandleFu
 		}
 	}
 
-	b.cmd[cmd] = Cmd{
+	c := &Cmd{
 		Handler:     f,
 		Description: description,
 		Hide:        hide,
+		Role:        Viewer,
+		Scope:       AnyChat,
+		bot:         b,
 	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.cmd[cmd] = c
+	b.scheduleMenu()
+	return c
+}
+
+// Require sets the role needed to run the command.
+func (c *Cmd) Require(role Role) *Cmd {
+	c.Role = role
+	c.changed()
+	return c
 }
 
 // HandleCmds handles commands from the incoming update
 func (b *Bot) HandleCmds(update tgbotapi.Update) {
-	cmd, ok := b.cmd[update.Message.Command()]
+	if !b.forMe(update) {
+		return
+	}
+
+	cmd, ok := b.lookupCmd(update.Message.Command())
 	if !ok {
-		b.Help(update)
-	} else {
-		cmd.Handler(b, update)
+		if update.Message.Command() == "help" {
+			b.Help(update)
+		} else {
+			b.unknownCmd(update)
+		}
+		return
+	}
+
+	if role := b.roleOf(update); role < cmd.Role {
+		b.SendMsg(update.Message.Chat.ID, b.denied(cmd.Role, role, update))
+		return
 	}
+
+	if cmd.Scope&scopeOf(update.Message.Chat) == 0 {
+		b.Reply(update, "cmd.scope", "cmd", update.Message.Command(), "scope", cmd.Scope.key())
+		return
+	}
+
+	handler, args := cmd.Handler, Args{}
+	if len(cmd.Params) > 0 || len(cmd.Subs) > 0 {
+		sub, parsed, err := cmd.parse(update.Message.CommandArguments())
+		if err != nil {
+			var e *ArgError
+			if errors.As(err, &e) && len(e.Suggestions) > 0 {
+				b.suggestArgs(update, e)
+				return
+			}
+			usage := cmd.Usage(update.Message.Command())
+			if sub != nil {
+				usage = sub.usage("/" + update.Message.Command())
+			}
+			b.Reply(update, "cmd.usage", "err", capitalize(b.Locale(update).Error(err)), "usage", usage)
+			return
+		}
+		args = parsed
+		if sub != nil {
+			handler = sub.Handler
+		}
+	}
+
+	handler(b, update, args)
 }
 
-// Help shows all available commands
+// capitalize returns s with the first letter in upper case
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+// describe returns the description of the command in the language of l
+func (c *Cmd) describe(l *i18n.Locale) string {
+	if d, ok := c.Descriptions[l.Lang()]; ok {
+		return d
+	}
+	return c.Description
+}
+
+// Help shows the available commands the user is allowed to run
 func (b *Bot) Help(update tgbotapi.Update) error {
-	res := "Available commands:\n/help - Show this message\n"
-	for cmd, C := range b.cmd {
-		if C.Hide {
+	role := b.roleOf(update)
+	l := b.Locale(update)
+
+	// usage of a command, e.g. /help set
+	name := strings.TrimPrefix(strings.TrimSpace(update.Message.CommandArguments()), "/")
+	if cmd, ok := b.lookupCmd(name); ok && update.Message.Command() == "help" {
+		_, err := b.SendMsg(update.Message.Chat.ID, fmt.Sprintf("%s\n%s", cmd.Usage(name), cmd.describe(l)))
+		return err
+	}
+
+	scope := scopeOf(update.Message.Chat)
+	cmds := b.Cmds()
+	names := []string{}
+	for cmd, C := range cmds {
+		if C.Hide || C.Role > role || C.Scope&scope == 0 {
 			continue
 		}
-		res += "/" + cmd + " - " + b.cmd[cmd].Description + "\n"
+		names = append(names, cmd)
+	}
+	sort.Strings(names)
+
+	res := l.T("help.header") + "\n"
+	for _, cmd := range names {
+		res += "/" + cmd + " - " + cmds[cmd].describe(l) + "\n"
+	}
+	if role == Guest {
+		res += "\n" + l.T("help.guest")
 	}
 
 	_, err := b.SendMsg(update.Message.Chat.ID, res)
 	return err
 }
 
-// Cmds returns all registered commands
-func (b *Bot) Cmds() map[string]Cmd {
-	return b.cmd
+// Cmds returns a copy of the registered commands
+func (b *Bot) Cmds() map[string]*Cmd {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	cmds := make(map[string]*Cmd, len(b.cmd))
+	for name, c := range b.cmd {
+		cmds[name] = c
+	}
+	return cmds
+}
+
+// lookupCmd returns the command registered as name
+func (b *Bot) lookupCmd(name string) (*Cmd, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c, ok := b.cmd[name]
+	return c, ok
 }
@@ -0,0 +1,145 @@
+package bot
+
+import (
+	"log"
+	"monitor/i18n"
+	"strconv"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Telegram limits how often a message can be edited, and dashboards should not run forever
+const (
+	minDashboardInterval = 10 * time.Second
+	maxDashboardLifetime = 24 * time.Hour
+)
+
+// dashboard is a message edited in place with fresh content
+type dashboard struct {
+	id        string
+	chatID    int64
+	messageID int
+	pinned    bool
+	locale    *i18n.Locale // of the chat, for the Stop button
+	stop      chan struct{}
+	once      sync.Once
+}
+
+// close stops the dashboard, it may be called several times
+func (d *dashboard) close() {
+	d.once.Do(func() { close(d.stop) })
+}
+
+// Dashboard sends the text of render to chatID and edits the message with a fresh text every interval, until lifetime has passed or someone taps its Stop button. The message is pinned while running if pin is true. A chat has at most one dashboard, starting another one stops the previous one.
+func (b *Bot) Dashboard(chatID int64, render func() string, interval time.Duration, lifetime time.Duration, pin bool) error {
+	if interval < minDashboardInterval {
+		return i18n.Errorf("dashboard.interval", "min", minDashboardInterval)
+	}
+	if lifetime <= 0 || lifetime > maxDashboardLifetime {
+		return i18n.Errorf("dashboard.lifetime", "max", maxDashboardLifetime)
+	}
+
+	b.mu.Lock()
+	b.dashboardID++
+	d := &dashboard{
+		id:     strconv.Itoa(b.dashboardID),
+		chatID: chatID,
+		pinned: pin,
+		stop:   make(chan struct{}),
+	}
+	b.mu.Unlock()
+	d.locale = b.ChatLocale(chatID)
+
+	text := render()
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ReplyMarkup = b.dashboardButtons(d)
+	m, err := b.Send(msg)
+	if err != nil {
+		return err
+	}
+	d.messageID = m.MessageID
+
+	if pin {
+		b.Request(tgbotapi.PinChatMessageConfig{
+			ChatID:              chatID,
+			MessageID:           m.MessageID,
+			DisableNotification: true,
+		})
+	}
+
+	b.mu.Lock()
+	for _, old := range b.dashboards {
+		if old.chatID == chatID {
+			old.close()
+		}
+	}
+	b.dashboards[d.id] = d
+	b.mu.Unlock()
+
+	go b.runDashboard(d, text, render, interval, lifetime)
+	return nil
+}
+
+// dashboardButtons returns the Stop button of d
+func (b *Bot) dashboardButtons(d *dashboard) tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(b.NewButton(d.locale.T("dashboard.stop"), "dashboard", d.id)))
+}
+
+// runDashboard edits d, showing text, every interval until it is stopped or lifetime has passed
+func (b *Bot) runDashboard(d *dashboard, text string, render func() string, interval time.Duration, lifetime time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	end := time.After(lifetime)
+
+loop:
+	for {
+		select {
+		case <-ticker.C:
+			next := render()
+			if next == text {
+				// Telegram rejects edits that change nothing
+				continue
+			}
+			text = next
+			edit := tgbotapi.NewEditMessageTextAndMarkup(d.chatID, d.messageID, text, b.dashboardButtons(d))
+			if _, err := b.Send(edit); err != nil {
+				// e.g. the message was deleted
+				if kind, _ := classify(err); kind == errPermanent || kind == errBlocked {
+					log.Printf("dashboard %s: %v, stopping\n", d.id, err)
+					break loop
+				}
+			}
+		case <-end:
+			break loop
+		case <-d.stop:
+			break loop
+		}
+	}
+
+	b.mu.Lock()
+	delete(b.dashboards, d.id)
+	b.mu.Unlock()
+
+	b.Send(tgbotapi.NewEditMessageText(d.chatID, d.messageID, text+"\n\n"+d.locale.T("dashboard.stopped", "at", time.Now())))
+	if d.pinned {
+		b.Request(tgbotapi.UnpinChatMessageConfig{ChatID: d.chatID, MessageID: d.messageID})
+	}
+}
+
+// dashboardButton handle the Stop button of dashboards
+func dashboardButton(b *Bot, update tgbotapi.Update) {
+	id := b.ButtonArgs(update).String("id")
+
+	b.mu.Lock()
+	d, ok := b.dashboards[id]
+	b.mu.Unlock()
+
+	if !ok {
+		b.Toast(update, b.T(update, "dashboard.gone"))
+		return
+	}
+	d.close()
+	b.Toast(update, b.T(update, "dashboard.stopping"))
+}
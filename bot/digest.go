@@ -0,0 +1,137 @@
+package bot
+
+import (
+	"fmt"
+	"monitor/alert"
+	"monitor/i18n"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	digestSeverity = alert.Warning // alerts of this severity or higher are never held for a digest
+	maxDigest      = 30            // maximum number of alerts listed in a digest
+)
+
+// digest is the alerts held for a subscriber in digest mode
+type digest struct {
+	Alerts []alert.Alert
+	Since  time.Time
+}
+
+// text returns the digest message in the language of l
+func (d *digest) text(l *i18n.Locale) string {
+	var sb strings.Builder
+
+	sb.WriteString(l.T("digest", "n", len(d.Alerts), "since", d.Since) + "\n")
+	for i, a := range d.Alerts {
+		if i == maxDigest {
+			sb.WriteString(l.T("more", "n", len(d.Alerts)-maxDigest) + "\n")
+			break
+		}
+		sb.WriteString(fmt.Sprintf("%s #%s %s\n", a.Time.Format(l.Clock), a.ID, AlertText(l, a)))
+	}
+
+	return sb.String()
+}
+
+// digest holds a for the digest of chatID if the subscriber is in digest mode and a is low severity. It returns false if a should be sent immediately.
+func (b *Bot) digest(chatID int64, a alert.Alert) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	s, ok := b.subscribers[chatID]
+	if !ok || s.Digest == 0 || a.Severity >= digestSeverity {
+		return false
+	}
+
+	d, ok := b.digests[chatID]
+	if !ok {
+		d = &digest{Since: time.Now()}
+		b.digests[chatID] = d
+	}
+	d.Alerts = append(d.Alerts, a)
+
+	return true
+}
+
+// SendDigests sends the digest of every subscriber whose digest interval has passed, or who left digest mode.
+func (b *Bot) SendDigests() {
+	b.sendDigests(false)
+}
+
+// sendDigests sends the digests that are due, or all of them if all is set
+func (b *Bot) sendDigests(all bool) {
+	now := time.Now()
+	due := map[int64]*digest{}
+
+	b.mu.Lock()
+	for chatID, d := range b.digests {
+		s, ok := b.subscribers[chatID]
+		if !ok {
+			delete(b.digests, chatID)
+			continue
+		}
+		if all || s.Digest == 0 || now.Sub(d.Since) >= s.Digest {
+			due[chatID] = d
+			delete(b.digests, chatID)
+		}
+	}
+	b.mu.Unlock()
+
+	for chatID, d := range due {
+		b.enqueue(chatID, d.text(b.ChatLocale(chatID)), nil)
+	}
+}
+
+// SetDigest sets the digest interval of a subscriber, 0 disables digest mode. It returns false if chatID is not subscribed.
+func (b *Bot) SetDigest(chatID int64, interval time.Duration) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	s, ok := b.subscribers[chatID]
+	if !ok {
+		return false
+	}
+
+	s.Digest = interval
+	b.subscribers[chatID] = s
+	b.persist(chatID)
+	return true
+}
+
+// CmdDigest handle /digest command, it shows the digest interval, or turns digest mode on with the "minutes" argument as interval.
+func CmdDigest(b *Bot, update tgbotapi.Update, args Args) {
+	chatID := update.Message.Chat.ID
+
+	s, ok := b.GetSubscriber(chatID)
+	if !ok {
+		b.Reply(update, "not_subscribed")
+		return
+	}
+
+	if !args.Has("minutes") {
+		b.Reply(update, "digest.show", "every", s.Digest)
+		return
+	}
+
+	n := args.Int("minutes")
+	if n <= 0 {
+		b.Reply(update, "digest.invalid", "arg", n)
+		return
+	}
+
+	b.SetDigest(chatID, time.Duration(n)*time.Minute)
+	b.Reply(update, "digest.on", "n", n)
+}
+
+// CmdDigestOff handle /digest off command, it turns digest mode off.
+func CmdDigestOff(b *Bot, update tgbotapi.Update, _ Args) {
+	if !b.SetDigest(update.Message.Chat.ID, 0) {
+		b.Reply(update, "not_subscribed")
+		return
+	}
+	b.Reply(update, "digest.off")
+}
@@ -0,0 +1,75 @@
+package bot
+
+import (
+	"context"
+	"monitor/alert"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestE2EDigest(t *testing.T) {
+	b, srv := startBot(t)
+	b.Subscribe(10)
+	b.Subscribe(11)
+	b.SetDigest(11, time.Hour)
+
+	// the alerts of one check go out as one message per chat
+	now := time.Now()
+	b.BroadcastAlerts(
+		alert.Alert{State: alert.Firing, Severity: alert.Info, Rule: "cpu_increase", Text: "cpu jumped", Time: now},
+		alert.Alert{State: alert.Firing, Severity: alert.Info, Rule: "mem_increase", Text: "mem jumped", Time: now},
+		alert.Alert{State: alert.Firing, Severity: alert.Critical, Rule: "disk", Text: "disk is full", Time: now},
+	)
+	all := srv.WaitText(10, "3 alerts:")
+	for _, text := range []string{"#1 cpu jumped", "#2 mem jumped", "#3 disk is full"} {
+		if !strings.Contains(all.Text(), text) {
+			t.Errorf("grouped message %q lacks %q", all.Text(), text)
+		}
+	}
+	if n := len(all.Buttons()); n != 9 {
+		t.Errorf("%d buttons, want a row of 3 per alert", n)
+	}
+
+	// info alerts wait for the digest, the others do not
+	critical := srv.WaitText(11, "disk is full")
+	if strings.Contains(critical.Text(), "jumped") {
+		t.Errorf("message %q has info alerts of a chat in digest mode", critical.Text())
+	}
+	b.SendDigests()
+	time.Sleep(200 * time.Millisecond)
+	if n := countText(srv, 11, "Digest"); n != 0 {
+		t.Fatal("digest sent before its interval")
+	}
+
+	b.mu.Lock()
+	b.digests[11].Since = now.Add(-time.Hour)
+	b.mu.Unlock()
+	b.SendDigests()
+	digest := srv.WaitText(11, "Digest: 2 alerts since").Text()
+	if !strings.Contains(digest, "#1 cpu jumped") || !strings.Contains(digest, "#2 mem jumped") || strings.Contains(digest, "disk") {
+		t.Errorf("digest %q, want the info alerts only", digest)
+	}
+
+	// leaving digest mode sends what is held
+	b.BroadcastAlerts(alert.Alert{State: alert.Firing, Severity: alert.Info, Rule: "load", Text: "load jumped", Time: now})
+	b.SetDigest(11, 0)
+	b.SendDigests()
+	srv.WaitText(11, "Digest: 1 alert since")
+
+	// shutting down sends the digests that are not due yet
+	b.SetDigest(11, time.Hour)
+	b.BroadcastAlerts(alert.Alert{State: alert.Firing, Severity: alert.Info, Rule: "net", Text: "net jumped", Time: now})
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := b.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+	srv.WaitText(11, "net jumped")
+
+	for _, chatID := range []int64{10, 11} {
+		if n := countText(srv, chatID, "jumped"); n != 3 {
+			t.Errorf("chat %d got %d messages with info alerts, want 3", chatID, n)
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"monitor/bot/bottest"
+	"strings"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// startBot starts a bot handling the updates of a fake Telegram server until the test ends
+func startBot(t *testing.T) (*Bot, *bottest.Server) {
+	srv := bottest.NewServer(t)
+	b, err := New(srv.API())
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.DisableLimits()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if err := b.HandleUpdates(ctx); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	t.Cleanup(func() {
+		cancel()
+		<-done
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		if err := b.Shutdown(ctx); err != nil {
+			t.Error(err)
+		}
+	})
+
+	return b, srv
+}
+
+func TestE2EButton(t *testing.T) {
+	b, srv := startBot(t)
+	b.Grant(10, Viewer)
+
+	b.AddCmd("greet", "", false, func(b *Bot, update tgbotapi.Update, _ Args) {
+		msg := tgbotapi.NewMessage(update.Message.Chat.ID, "Greet who?")
+		msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(b.NewButton("Bob", "greet", "bob")))
+		b.Send(msg)
+	})
+	b.AddButton("greet:{name}", func(b *Bot, update tgbotapi.Update) {
+		b.SendMsg(update.CallbackQuery.Message.Chat.ID, "Hello "+b.ButtonArgs(update).String("name"))
+	})
+	b.AddButton("secret:{name}", func(b *Bot, update tgbotapi.Update) {
+		b.SendMsg(update.CallbackQuery.Message.Chat.ID, "Secret")
+	}).Require(Admin)
+
+	srv.Message(10, "/greet")
+	buttons := srv.WaitText(10, "Greet who?").Buttons()
+	if len(buttons) != 1 || buttons[0] != "greet:bob" {
+		t.Fatalf("buttons = %v, want [greet:bob]", buttons)
+	}
+
+	id := srv.Press(10, buttons[0])
+	srv.WaitText(10, "Hello bob")
+	srv.Wait(func(r bottest.Request) bool {
+		return r.Method == "answerCallbackQuery" && r.Params["callback_query_id"] == id
+	})
+
+	// buttons needing a higher role are answered with a toast only
+	id = srv.Press(10, "secret:x")
+	toast := srv.Wait(func(r bottest.Request) bool {
+		return r.Method == "answerCallbackQuery" && r.Params["callback_query_id"] == id
+	})
+	if toast.Text() == "" {
+		t.Error("denied button press should be answered with a reason")
+	}
+	for _, r := range srv.Requests() {
+		if r.Text() == "Secret" {
+			t.Error("denied button handler was called")
+		}
+	}
+}
+
+func TestE2EArgs(t *testing.T) {
+	b, srv := startBot(t)
+	b.Grant(10, Viewer)
+
+	b.AddCmd("show", "", false, func(b *Bot, update tgbotapi.Update, args Args) {
+		b.SendMsg(update.Message.Chat.ID, "Showing "+args.String("metric"))
+	}).Args(EnumArg("metric", "", "cpu", "mem"))
+
+	srv.Message(10, "/show cpu")
+	srv.WaitText(10, "Showing cpu")
+
+	// commands run by a "did you mean" button get their arguments too
+	srv.Message(10, "/show memory")
+	buttons := srv.WaitText(10, "Did you mean").Buttons()
+	if len(buttons) != 1 {
+		t.Fatalf("buttons = %v, want one suggestion", buttons)
+	}
+	srv.Press(10, buttons[0])
+	srv.WaitText(10, "Showing mem")
+}
+
+func TestE2EForm(t *testing.T) {
+	b, srv := startBot(t)
+	b.Grant(10, Viewer)
+
+	b.AddCmd("cancel", "", false, CmdCancel)
+	b.AddCmd("order", "", false, func(b *Bot, update tgbotapi.Update, _ Args) {
+		b.Start(update, NewForm().
+			Ask(IntArg("count", "How many?")).
+			Ask(EnumArg("size", "Which size?", "small", "large")).
+			Finally(func(b *Bot, update tgbotapi.Update, answers Args) {
+				b.SendMsg(update.FromChat().ID, fmt.Sprintf("Ordered %d %s", answers.Int("count"), answers.String("size")))
+			}))
+	})
+
+	srv.Message(10, "/order")
+	ask := srv.WaitText(10, "How many?")
+	if ask.Params["reply_markup"] == "" {
+		t.Error("free text questions should ask for a reply")
+	}
+
+	srv.Message(10, "many")
+	srv.WaitText(10, "Invalid")
+
+	srv.Message(10, "2")
+	buttons := srv.WaitText(10, "Which size?").Buttons()
+	if len(buttons) != 2 {
+		t.Fatalf("buttons = %v, want one per choice", buttons)
+	}
+
+	srv.Press(10, buttons[1])
+	srv.WaitText(10, "Ordered 2 large")
+	if b.IsWaiting(10) {
+		t.Error("form should be done")
+	}
+
+	// in groups only the user who started the form can answer it
+	const group = -100
+	b.Grant(11, Viewer)
+	b.AddCmd("size", "", false, func(b *Bot, update tgbotapi.Update, _ Args) {
+		b.Start(update, NewForm().
+			Ask(EnumArg("size", "Which size?", "small", "large")).
+			Finally(func(b *Bot, update tgbotapi.Update, answers Args) {
+				b.SendMsg(update.FromChat().ID, "Ordered "+answers.String("size"))
+			}))
+	})
+	srv.Reset()
+	srv.GroupMessage(group, 10, "/size")
+	buttons = srv.WaitText(group, "Which size?").Buttons()
+	id := srv.GroupPress(group, 11, buttons[0])
+	srv.Wait(func(r bottest.Request) bool {
+		return r.Method == "answerCallbackQuery" && r.Params["callback_query_id"] == id && strings.Contains(r.Text(), "someone else")
+	})
+	for _, r := range srv.Requests() {
+		if r.Method == "editMessageReplyMarkup" || r.Text() == "small" {
+			t.Errorf("press of another user changed the form: %s %q", r.Method, r.Text())
+		}
+	}
+	srv.GroupPress(group, 10, buttons[0])
+	srv.WaitText(group, "small")
+	srv.WaitText(group, "Ordered small")
+
+	// buttons of an older form do not answer the current one, even at the same step
+	srv.Reset()
+	srv.GroupMessage(group, 10, "/size")
+	stale := srv.WaitText(group, "Which size?").Buttons()
+	srv.Reset()
+	srv.GroupMessage(group, 10, "/size")
+	srv.WaitText(group, "Which size?")
+	id = srv.GroupPress(group, 10, stale[1])
+	srv.Wait(func(r bottest.Request) bool {
+		return r.Method == "answerCallbackQuery" && r.Params["callback_query_id"] == id && strings.Contains(r.Text(), "not asked anymore")
+	})
+	if !b.IsWaiting(group) {
+		t.Error("stale button answered the form")
+	}
+	b.Cancel(group)
+
+	// checks may call the bot
+	b.AddCmd("name", "", false, func(b *Bot, update tgbotapi.Update, _ Args) {
+		b.Start(update, NewForm().
+			Ask(StringArg("name", "Name?")).
+			Check(func(answers Args) error {
+				if b.Role(10) < Viewer || !b.IsWaiting(10) {
+					return errors.New("not allowed")
+				}
+				return nil
+			}).
+			Finally(func(b *Bot, update tgbotapi.Update, answers Args) {
+				b.SendMsg(update.FromChat().ID, "Hello "+answers.String("name"))
+			}))
+	})
+	srv.Reset()
+	srv.Message(10, "/name")
+	srv.WaitText(10, "Name?")
+	srv.Message(10, "Ann")
+	srv.WaitText(10, "Hello Ann")
+
+	srv.Reset()
+	srv.Message(10, "/order")
+	srv.WaitText(10, "How many?")
+	srv.Message(10, "/cancel")
+	srv.WaitText(10, "Cancelled")
+	if b.IsWaiting(10) {
+		t.Error("/cancel should end the form")
+	}
+}
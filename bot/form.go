@@ -0,0 +1,322 @@
+package bot
+
+import (
+	"monitor/i18n"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// defaultFormTimeout is how long a form waits for an answer unless set otherwise with Form.Timeout
+const defaultFormTimeout = 10 * time.Minute
+
+// FormHandleFunc gets the answers of a completed form. update is the last answer, a message or a button press.
+type FormHandleFunc func(b *Bot, update tgbotapi.Update, answers Args)
+
+// step is a question of a form
+type step struct {
+	param Param
+	check func(answers Args) error
+	when  func(answers Args) bool
+}
+
+// Form is a conversation asking the user questions one after another, e.g.
+//
+//	NewForm().
+//		Ask(StringArg("name", "Name of the rule?")).
+//		Ask(EnumArg("severity", "Severity?", "info", "warning", "critical")).
+//		Finally(func(b *Bot, update tgbotapi.Update, answers Args) {})
+//
+// The question is the help of the argument, or the message of the i18n catalogs it is the key of. Answers are parsed like command arguments and the question is asked again until the answer is valid, choices are shown as buttons and optional questions are skipped by answering "-". A form can be started in many chats at once with Bot.Start.
+type Form struct {
+	steps   []step
+	timeout time.Duration
+	finally FormHandleFunc
+}
+
+// NewForm creates an empty form.
+func NewForm() *Form {
+	return &Form{
+		timeout: defaultFormTimeout,
+		finally: func(*Bot, tgbotapi.Update, Args) {},
+	}
+}
+
+// Ask adds a question, the answer is stored under the name of p.
+func (f *Form) Ask(p Param) *Form {
+	f.steps = append(f.steps, step{param: p})
+	return f
+}
+
+// Check validates the answer of the last question, the question is asked again if check returns an error. answers include the answer being checked.
+func (f *Form) Check(check func(answers Args) error) *Form {
+	f.steps[len(f.steps)-1].check = check
+	return f
+}
+
+// When only asks the last question if when returns true for the previous answers.
+func (f *Form) When(when func(answers Args) bool) *Form {
+	f.steps[len(f.steps)-1].when = when
+	return f
+}
+
+// Timeout sets how long the form waits for each answer before giving up.
+func (f *Form) Timeout(d time.Duration) *Form {
+	f.timeout = d
+	return f
+}
+
+// Finally sets the handler of the completed form.
+func (f *Form) Finally(h FormHandleFunc) *Form {
+	f.finally = h
+	return f
+}
+
+// next returns the index of the next question to ask from i on, len(f.steps) if the form is completed
+func (f *Form) next(i int, answers Args) int {
+	for ; i < len(f.steps); i++ {
+		if w := f.steps[i].when; w == nil || w(answers) {
+			return i
+		}
+	}
+	return i
+}
+
+// conversation is a form in progress in a chat
+type conversation struct {
+	form    *Form
+	step    int
+	answers Args
+	userID  int64  // only answers of this user count, 0 for anyone
+	id      string // in the data of its buttons, so that buttons of older forms do not answer it
+	timer   *time.Timer
+}
+
+// Start starts f in the chat of update, only the sender of update may answer. It replaces the form in progress in the chat.
+func (b *Bot) Start(update tgbotapi.Update, f *Form) {
+	chatID := update.FromChat().ID
+
+	c := &conversation{
+		form:    f,
+		answers: Args{},
+	}
+	if u := update.SentFrom(); u != nil {
+		c.userID = u.ID
+	}
+	c.step = f.next(0, c.answers)
+	if c.step == len(f.steps) {
+		f.finally(b, update, c.answers)
+		return
+	}
+
+	b.mu.Lock()
+	if old, ok := b.forms[chatID]; ok {
+		old.timer.Stop()
+	}
+	b.formID++
+	c.id = strconv.FormatInt(b.formID, 36)
+	c.timer = time.AfterFunc(f.timeout, func() { b.expire(chatID, c) })
+	b.forms[chatID] = c
+	b.mu.Unlock()
+
+	b.askStep(chatID, c.id, c.step, f.steps[c.step], "")
+}
+
+// expire ends the conversation c of chatID if it is still waiting
+func (b *Bot) expire(chatID int64, c *conversation) {
+	b.mu.Lock()
+	if b.forms[chatID] != c {
+		b.mu.Unlock()
+		return
+	}
+	delete(b.forms, chatID)
+	b.mu.Unlock()
+
+	b.SendMsg(chatID, b.ChatLocale(chatID).T("form.timeout"))
+}
+
+// askStep sends the question of s, the i-th step of the conversation id, prefixed by note, e.g. why the last answer was invalid
+func (b *Bot) askStep(chatID int64, id string, i int, s step, note string) {
+	p := s.param
+	l := b.ChatLocale(chatID)
+
+	text := p.Help
+	if i18n.English.Has(text) {
+		text = l.T(text)
+	}
+	if text == "" {
+		text = p.Name + "?"
+	}
+	if p.Optional {
+		text += "\n" + l.T("form.optional", "def", p.Def)
+	}
+	if note != "" {
+		text = note + "\n\n" + text
+	}
+
+	msg := tgbotapi.NewMessage(chatID, text)
+	if p.Kind == KindEnum {
+		rows := [][]tgbotapi.InlineKeyboardButton{}
+		for j, choice := range p.Choices {
+			button := b.NewButton(choice, "form", id, strconv.Itoa(i), strconv.Itoa(j))
+			rows = append(rows, tgbotapi.NewInlineKeyboardRow(button))
+		}
+		msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
+	} else {
+		msg.ReplyMarkup = tgbotapi.ForceReply{ForceReply: true, Selective: true}
+	}
+
+	b.Send(msg)
+}
+
+// answer feeds text to the conversation of chatID as the answer of the given step of the conversation id, or of the current step of any conversation if id is "". It returns false if the chat has no conversation, or it is not the turn of the sender of update.
+func (b *Bot) answer(chatID int64, id string, step int, text string, update tgbotapi.Update) bool {
+	b.mu.Lock()
+	c, ok := b.forms[chatID]
+	if !ok {
+		b.mu.Unlock()
+		return false
+	}
+	if u := update.SentFrom(); c.userID != 0 && (u == nil || u.ID != c.userID) {
+		b.mu.Unlock()
+		return false
+	}
+	if id != "" && (id != c.id || step != c.step) {
+		// a button of an answered question or of another form
+		b.mu.Unlock()
+		return true
+	}
+
+	i := c.step
+	s := c.form.steps[i]
+	p := s.param
+	// work on a copy, checks and conditions run without b.mu since they may call the bot
+	answers := make(Args, len(c.answers)+1)
+	for k, v := range c.answers {
+		answers[k] = v
+	}
+	b.mu.Unlock()
+
+	var err error
+	text = strings.TrimSpace(text)
+	if text == "-" && p.Optional {
+		delete(answers, p.Name)
+		if p.Def != "" {
+			answers[p.Name], err = p.parse(p.Def)
+		}
+	} else {
+		var v interface{}
+		if v, err = p.parse(text); err == nil {
+			answers[p.Name] = v
+		}
+	}
+	if err == nil && s.check != nil {
+		err = s.check(answers)
+	}
+	next := len(c.form.steps)
+	if err == nil {
+		next = c.form.next(i+1, answers)
+	}
+
+	b.mu.Lock()
+	if b.forms[chatID] != c || c.step != i {
+		// cancelled, expired or answered meanwhile
+		b.mu.Unlock()
+		return true
+	}
+	if err != nil {
+		b.mu.Unlock()
+		b.askStep(chatID, c.id, i, s, capitalize(b.ChatLocale(chatID).Error(err)))
+		return true
+	}
+
+	c.answers, c.step = answers, next
+	if next == len(c.form.steps) {
+		c.timer.Stop()
+		delete(b.forms, chatID)
+		b.mu.Unlock()
+		c.form.finally(b, update, answers)
+		return true
+	}
+	c.timer.Reset(c.form.timeout)
+	b.mu.Unlock()
+
+	b.askStep(chatID, c.id, next, c.form.steps[next], "")
+	return true
+}
+
+// formButton handle the choice buttons of forms
+func formButton(b *Bot, update tgbotapi.Update) {
+	q := update.CallbackQuery
+	if q.Message == nil {
+		return
+	}
+	chatID := q.Message.Chat.ID
+
+	args := b.ButtonArgs(update)
+	i, err1 := strconv.Atoi(args.String("step"))
+	j, err2 := strconv.Atoi(args.String("choice"))
+	if err1 != nil || err2 != nil {
+		b.Toast(update, b.T(update, "button.invalid"))
+		return
+	}
+
+	b.mu.Lock()
+	c, ok := b.forms[chatID]
+	if !ok || args.String("id") != c.id || i != c.step || j < 0 || j >= len(c.form.steps[i].param.Choices) {
+		b.mu.Unlock()
+		b.Toast(update, b.T(update, "form.gone"))
+		return
+	}
+	if u := update.SentFrom(); c.userID != 0 && (u == nil || u.ID != c.userID) {
+		b.mu.Unlock()
+		b.Toast(update, b.T(update, "form.not_yours"))
+		return
+	}
+	choice := c.form.steps[i].param.Choices[j]
+	b.mu.Unlock()
+
+	// remove the buttons so that the question can not be answered twice
+	b.Request(tgbotapi.NewEditMessageReplyMarkup(chatID, q.Message.MessageID, tgbotapi.NewInlineKeyboardMarkup()))
+	b.SendMsg(chatID, choice)
+
+	b.answer(chatID, c.id, i, choice, update)
+}
+
+// Cancel stops the form in progress in the chat, it returns false if there is none.
+func (b *Bot) Cancel(chatID int64) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c, ok := b.forms[chatID]
+	if ok {
+		c.timer.Stop()
+		delete(b.forms, chatID)
+	}
+	return ok
+}
+
+// IsWaiting checks if a form is in progress in the chat.
+func (b *Bot) IsWaiting(chatID int64) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	_, ok := b.forms[chatID]
+	return ok
+}
+
+// MatchWatting feeds a text message to the form in progress in its chat, it returns false if the message is not an answer.
+func (b *Bot) MatchWatting(update tgbotapi.Update) bool {
+	return b.answer(update.Message.Chat.ID, "", -1, update.Message.Text, update)
+}
+
+// CmdCancel handle /cancel command, it stops the form in progress.
+func CmdCancel(b *Bot, update tgbotapi.Update, _ Args) {
+	if b.Cancel(update.Message.Chat.ID) {
+		b.Reply(update, "form.cancelled")
+	} else {
+		b.Reply(update, "form.nothing")
+	}
+}
@@ -0,0 +1,179 @@
+package bot
+
+import (
+	"sort"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// maxSuggestions is the maximum number of "did you mean" suggestions
+const maxSuggestions = 3
+
+// distance returns the Levenshtein distance between a and b
+func distance(a string, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+
+	return prev[len(rb)]
+}
+
+// resolve matches word against candidates. An exact match or the only candidate word is a prefix of is resolved, otherwise the closest candidates are suggested.
+func resolve(word string, candidates []string) (resolved string, suggestions []string) {
+	prefixed := []string{}
+	for _, c := range candidates {
+		if c == word {
+			return c, nil
+		}
+		if strings.HasPrefix(c, word) {
+			prefixed = append(prefixed, c)
+		}
+	}
+	if len(prefixed) == 1 {
+		return prefixed[0], nil
+	}
+	if len(prefixed) > 1 {
+		sort.Strings(prefixed)
+		return "", prefixed[:min(len(prefixed), maxSuggestions)]
+	}
+
+	// typos, and words a candidate is a prefix of, e.g. "memory" for "mem"
+	maxDist := max(1, len([]rune(word))/3)
+	type match struct {
+		c string
+		d int
+	}
+	matches := []match{}
+	for _, c := range candidates {
+		if d := distance(word, c); d <= maxDist {
+			matches = append(matches, match{c, d})
+		} else if strings.HasPrefix(word, c) {
+			matches = append(matches, match{c, len(word) - len(c)})
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].d != matches[j].d {
+			return matches[i].d < matches[j].d
+		}
+		return matches[i].c < matches[j].c
+	})
+
+	for i := 0; i < len(matches) && i < maxSuggestions; i++ {
+		suggestions = append(suggestions, matches[i].c)
+	}
+	return "", suggestions
+}
+
+// cmdNames returns the commands the user who sent update may run and find in the help
+func (b *Bot) cmdNames(update tgbotapi.Update) []string {
+	role := b.roleOf(update)
+
+	names := []string{"help"}
+	for name, cmd := range b.Cmds() {
+		if !cmd.Hide && cmd.Role <= role {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// withCommand returns a copy of update with the command and its arguments replaced by text, e.g. "/status"
+func withCommand(update tgbotapi.Update, text string) tgbotapi.Update {
+	m := *update.Message
+	m.Text = text
+
+	name, _, _ := strings.Cut(text, " ")
+	m.Entities = []tgbotapi.MessageEntity{{Type: "bot_command", Offset: 0, Length: len(name)}}
+
+	update.Message = &m
+	return update
+}
+
+// suggestButtons returns the inline keyboard with a button running each command
+func (b *Bot) suggestButtons(texts []string) tgbotapi.InlineKeyboardMarkup {
+	rows := [][]tgbotapi.InlineKeyboardButton{}
+	for _, text := range texts {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(b.NewButton(text, "run", text)))
+	}
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// didYouMean replies with the suggested commands as buttons running them
+func (b *Bot) didYouMean(update tgbotapi.Update, prefix string, texts []string) {
+	msg := tgbotapi.NewMessage(update.Message.Chat.ID, prefix+b.T(update, "cmd.did_you_mean", "cmds", texts))
+	msg.ReplyMarkup = b.suggestButtons(texts)
+	b.Send(msg)
+}
+
+// unknownCmd handles a command that is not registered, a unique prefix of a command runs it, otherwise similar commands are suggested
+func (b *Bot) unknownCmd(update tgbotapi.Update) {
+	name := update.Message.Command()
+	args := update.Message.CommandArguments()
+
+	resolved, suggestions := resolve(name, b.cmdNames(update))
+	if resolved != "" {
+		b.HandleCmds(withCommand(update, strings.TrimSpace("/"+resolved+" "+args)))
+		return
+	}
+	if len(suggestions) == 0 {
+		b.Help(update)
+		return
+	}
+
+	texts := []string{}
+	for _, s := range suggestions {
+		texts = append(texts, strings.TrimSpace("/"+s+" "+args))
+	}
+	b.didYouMean(update, b.T(update, "cmd.unknown", "cmd", name), texts)
+}
+
+// suggestArgs replies with the command with the invalid argument replaced by each suggestion
+func (b *Bot) suggestArgs(update tgbotapi.Update, e *ArgError) {
+	words := strings.Fields(update.Message.CommandArguments())
+
+	texts := []string{}
+	for _, s := range e.Suggestions {
+		fixed := append([]string{}, words...)
+		for i, w := range fixed {
+			if w == e.Value || strings.HasSuffix(w, "="+e.Value) {
+				fixed[i] = strings.TrimSuffix(w, e.Value) + s
+				break
+			}
+		}
+		texts = append(texts, "/"+update.Message.Command()+" "+strings.Join(fixed, " "))
+	}
+
+	b.didYouMean(update, b.T(update, "cmd.invalid_arg", "name", e.Param.Name, "value", e.Value), texts)
+}
+
+// runButton handle the buttons of "did you mean" replies, it runs the command in the callback data as if the user sent it
+func runButton(b *Bot, update tgbotapi.Update) {
+	q := update.CallbackQuery
+	if q.Message == nil {
+		return
+	}
+
+	msg := &tgbotapi.Message{
+		MessageID: q.Message.MessageID,
+		From:      q.From,
+		Chat:      q.Message.Chat,
+		Date:      q.Message.Date,
+	}
+	b.HandleCmds(withCommand(tgbotapi.Update{Message: msg}, b.ButtonArgs(update).String("cmd")))
+}
@@ -0,0 +1,56 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDistance(t *testing.T) {
+	for _, c := range []struct {
+		a, b string
+		want int
+	}{
+		{"", "abc", 3},
+		{"status", "status", 0},
+		{"stauts", "status", 2},
+		{"silence", "silences", 1},
+		{"kitten", "sitting", 3},
+	} {
+		if got := distance(c.a, c.b); got != c.want {
+			t.Errorf("distance(%q, %q) = %d, want %d", c.a, c.b, got, c.want)
+		}
+	}
+}
+
+func TestResolve(t *testing.T) {
+	cmds := []string{"status", "subscribe", "set", "silences", "history", "help"}
+
+	for _, c := range []struct {
+		word     string
+		resolved string
+		suggest  string
+	}{
+		{"status", "status", ""},
+		{"st", "status", ""},
+		{"hi", "history", ""},
+		{"s", "", "set,silences,status"},
+		{"statsu", "", "status"},
+		{"histroy", "", "history"},
+		{"setting", "", "set"},
+		{"xyz", "", ""},
+	} {
+		resolved, suggestions := resolve(c.word, cmds)
+		if resolved != c.resolved || strings.Join(suggestions, ",") != c.suggest {
+			t.Errorf("resolve(%q) = %q, %v, want %q, %s", c.word, resolved, suggestions, c.resolved, c.suggest)
+		}
+	}
+
+	args, err := (&Cmd{}).Args(EnumArg("metric", "", "cpu", "mem")).Parse("me")
+	if err != nil || args.String("metric") != "mem" {
+		t.Errorf("unique prefix of an enum: %v, %v", args, err)
+	}
+	_, err = (&Cmd{}).Args(EnumArg("metric", "", "cpu", "mem")).Parse("memory")
+	if e, ok := err.(*ArgError); !ok || strings.Join(e.Suggestions, ",") != "mem" {
+		t.Errorf("suggestions for memory: %v", err)
+	}
+}
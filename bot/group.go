@@ -0,0 +1,152 @@
+package bot
+
+import (
+	"monitor/i18n"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Scope is the kinds of chat a command works in.
+type Scope int
+
+const (
+	InPrivate Scope = 1 << iota // private chats with the bot
+	InGroups                    // groups and supergroups, including forums
+	AnyChat   = InPrivate | InGroups
+)
+
+func (s Scope) String() string {
+	switch s {
+	case InPrivate:
+		return "private chats"
+	case InGroups:
+		return "groups"
+	case AnyChat:
+		return "any chat"
+	}
+	return "no chat"
+}
+
+// key returns the key of the name of the scope in the i18n catalogs
+func (s Scope) key() string {
+	switch s {
+	case InPrivate:
+		return "private"
+	case InGroups:
+		return "groups"
+	case AnyChat:
+		return "any"
+	}
+	return "none"
+}
+
+// scopeOf returns the scope chat belongs to
+func scopeOf(chat *tgbotapi.Chat) Scope {
+	switch {
+	case chat == nil:
+		return 0
+	case chat.IsPrivate():
+		return InPrivate
+	case chat.IsGroup(), chat.IsSuperGroup():
+		return InGroups
+	}
+	return 0
+}
+
+// In sets the kinds of chat the command works in, any chat by default.
+func (c *Cmd) In(scope Scope) *Cmd {
+	c.Scope = scope
+	c.changed()
+	return c
+}
+
+// forMe checks if the command in update is for this bot. In groups, /cmd@otherbot is for another bot and so are unknown commands without @, since several bots may share the group.
+func (b *Bot) forMe(update tgbotapi.Update) bool {
+	cmd, at, ok := strings.Cut(update.Message.CommandWithAt(), "@")
+	if ok {
+		return b.username == "" || strings.EqualFold(at, b.username)
+	}
+	if scopeOf(update.Message.Chat) != InGroups {
+		return true
+	}
+	_, known := b.lookupCmd(cmd)
+	return known || cmd == "help"
+}
+
+// thread returns the forum topic alerts to chatID go to, 0 for the general one
+func (b *Bot) thread(chatID int64) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.subscribers[chatID].Thread
+}
+
+// SetThread sets the forum topic the messages to the subscriber chatID go to, 0 for the general topic. It returns false if chatID is not subscribed.
+func (b *Bot) SetThread(chatID int64, thread int) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	s, ok := b.subscribers[chatID]
+	if !ok {
+		return false
+	}
+
+	s.Thread = thread
+	b.subscribers[chatID] = s
+	b.persist(chatID)
+	return true
+}
+
+// groupSettings returns the group settings of the subscriber s in the language of l
+func groupSettings(l *i18n.Locale, s Subscriber) string {
+	return l.T("group.settings", "topic", s.Thread, "classes", strings.Join(s.Prefs.Classes, ", "), "prefs", s.Prefs.text(l))
+}
+
+// CmdGroup handle /group command, it shows and changes the settings of a subscribed group: the forum topic alerts are posted to and the host classes the group gets alerts of. It expects the optional "setting" and "value" arguments.
+func CmdGroup(b *Bot, update tgbotapi.Update, args Args) {
+	chatID := update.Message.Chat.ID
+
+	s, ok := b.GetSubscriber(chatID)
+	if !ok {
+		b.Reply(update, "not_subscribed")
+		return
+	}
+
+	l := b.Locale(update)
+	if !args.Has("setting") {
+		b.SendMsg(chatID, groupSettings(l, s))
+		return
+	}
+	value := args.String("value")
+
+	switch args.String("setting") {
+	case "topic":
+		thread := 0
+		if value != "off" && value != "" {
+			// the topic ID is in the links to its messages, t.me/c/<chat>/<topic>/<message>
+			n, err := strconv.Atoi(value)
+			if err != nil || n <= 0 {
+				b.SendMsg(chatID, l.T("group.invalid_topic", "value", value))
+				return
+			}
+			thread = n
+		}
+		b.SetThread(chatID, thread)
+
+	case "class":
+		s.Prefs.Classes = nil
+		if value != "*" && value != "all" && value != "" {
+			for _, v := range strings.Split(value, ",") {
+				if v = strings.TrimSpace(v); v != "" {
+					s.Prefs.Classes = append(s.Prefs.Classes, v)
+				}
+			}
+		}
+		b.SetPrefs(chatID, s.Prefs)
+	}
+
+	s, _ = b.GetSubscriber(chatID)
+	b.SendMsg(chatID, l.T("saved")+"\n"+groupSettings(l, s))
+}
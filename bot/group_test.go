@@ -0,0 +1,102 @@
+package bot
+
+import (
+	"monitor/alert"
+	"monitor/bot/bottest"
+	"strings"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestE2EGroup(t *testing.T) {
+	b, srv := startBot(t)
+	b.Grant(10, Viewer)
+	const group = -100
+
+	b.AddCmd("ping", "", false, func(b *Bot, update tgbotapi.Update, _ Args) {
+		b.SendMsg(update.Message.Chat.ID, "pong "+update.Message.CommandArguments())
+	})
+	b.AddCmd("private", "Only in private chats", false, func(b *Bot, update tgbotapi.Update, _ Args) {
+		b.SendMsg(update.Message.Chat.ID, "private")
+	}).In(InPrivate)
+
+	// commands for other bots are ignored, so are unknown commands that may be for them
+	srv.GroupMessage(group, 10, "/ping@other_bot 1")
+	srv.GroupMessage(group, 10, "/start")
+	srv.GroupMessage(group, 10, "/ping@"+bottest.BotName+" 2")
+	srv.WaitText(group, "pong 2")
+
+	srv.GroupMessage(group, 10, "/private")
+	srv.WaitText(group, "only works in private chats")
+
+	srv.GroupMessage(group, 10, "/help")
+	if help := srv.WaitText(group, "Available commands").Text(); strings.Contains(help, "/private") {
+		t.Errorf("group help lists private commands:\n%s", help)
+	}
+
+	for _, r := range srv.Requests() {
+		if r.Text() == "pong 1" || strings.Contains(r.Text(), "start") {
+			t.Errorf("answered a command for another bot: %q", r.Text())
+		}
+	}
+}
+
+func TestE2EThread(t *testing.T) {
+	b, srv := startBot(t)
+	const group = -100
+
+	b.Subscribe(group)
+	if !b.SetThread(group, 7) || b.SetThread(-200, 7) {
+		t.Fatal("SetThread should only set the topic of subscribers")
+	}
+	b.SetPrefs(group, Prefs{Classes: []string{"db"}})
+
+	now := time.Now()
+	b.BroadcastAlerts(alert.Alert{State: alert.Firing, Severity: alert.Warning, Rule: "web", Class: "web", Text: "web is down", Time: now})
+	b.BroadcastAlerts(alert.Alert{State: alert.Firing, Severity: alert.Warning, Rule: "db", Class: "db", Text: "db is down", Time: now})
+
+	msg := srv.WaitText(group, "db is down")
+	if msg.Params["message_thread_id"] != "7" {
+		t.Errorf("message_thread_id = %q, want 7", msg.Params["message_thread_id"])
+	}
+	if len(msg.Buttons()) == 0 {
+		t.Error("alert buttons are missing")
+	}
+	for _, r := range srv.Requests() {
+		if r.Text() == "web is down" {
+			t.Error("the group got an alert of another host class")
+		}
+	}
+}
+
+func TestE2EGroupSettings(t *testing.T) {
+	b, srv := startBot(t)
+	b.Grant(10, Operator)
+	const group = -100
+
+	b.AddCmd("group", "", false, CmdGroup).Args(
+		EnumArg("setting", "", "topic", "class").Opt(),
+		TextArg("value", "").Opt(),
+	).In(InGroups)
+
+	srv.GroupMessage(group, 10, "/group")
+	srv.WaitText(group, "Not subscribed")
+	b.Subscribe(group)
+
+	srv.GroupMessage(group, 10, "/group topic 5")
+	srv.WaitText(group, "Topic: 5")
+	srv.GroupMessage(group, 10, "/group class db, web")
+	srv.WaitText(group, "Host classes: db, web")
+
+	s, _ := b.GetSubscriber(group)
+	if s.Thread != 5 || strings.Join(s.Prefs.Classes, ",") != "db,web" {
+		t.Errorf("got topic %d and classes %v", s.Thread, s.Prefs.Classes)
+	}
+
+	srv.GroupMessage(group, 10, "/group topic general")
+	srv.WaitText(group, "Invalid topic")
+	srv.GroupMessage(group, 10, "/group topic off")
+	srv.WaitText(group, "Topic: general")
+}
@@ -0,0 +1,79 @@
+package bot
+
+import (
+	"errors"
+	"log"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// inlineCacheTime is how long Telegram may cache the results of an inline query in seconds, short since the values change
+const inlineCacheTime = 10
+
+// InlineHandleFunc returns the results of an inline query, e.g. tgbotapi.InlineQueryResultArticle, at most 50.
+type InlineHandleFunc func(b *Bot, query *tgbotapi.InlineQuery) ([]interface{}, error)
+
+// Inline is the handler of inline queries.
+type Inline struct {
+	Handler InlineHandleFunc
+	Role    Role // role needed to get results
+}
+
+// Inline sets the handler of inline queries like "@bot cpu". Users need role to get results, Viewer by default.
+func (b *Bot) Inline(f InlineHandleFunc) *Inline {
+	b.inline = &Inline{Handler: f, Role: Viewer}
+	return b.inline
+}
+
+// Require sets the role needed to get results.
+func (i *Inline) Require(role Role) *Inline {
+	i.Role = role
+	return i
+}
+
+// HandleInline answers an inline query with the results of the handler set by Inline.
+func (b *Bot) HandleInline(update tgbotapi.Update) {
+	q := update.InlineQuery
+
+	answer := tgbotapi.InlineConfig{
+		InlineQueryID: q.ID,
+		Results:       []interface{}{},
+		CacheTime:     inlineCacheTime,
+		IsPersonal:    true,
+	}
+
+	switch {
+	case b.inline == nil:
+		return
+	case b.roleOf(update) < b.inline.Role:
+		// offer to open the private chat, where /help tells what to do
+		answer.SwitchPMText = b.UserLocale(q.From).T("inline.denied")
+		answer.SwitchPMParameter = "help"
+	default:
+		results, err := b.inline.Handler(b, q)
+		if err != nil {
+			log.Printf("inline query %q: %v\n", q.Query, err)
+		}
+		answer.Results = append(answer.Results, results...)
+	}
+
+	if _, err := b.Request(answer); err != nil {
+		log.Printf("answer inline query: %v\n", err)
+	}
+}
+
+// UploadPhoto uploads a photo by sending it to chatID and returns its file ID, e.g. for tgbotapi.InlineQueryResultCachedPhoto.
+func (b *Bot) UploadPhoto(chatID int64, file tgbotapi.RequestFileData) (string, error) {
+	msg := tgbotapi.NewPhoto(chatID, file)
+	msg.DisableNotification = true
+
+	m, err := b.Send(msg)
+	if err != nil {
+		return "", err
+	}
+	if len(m.Photo) == 0 {
+		return "", errors.New("no photo in the sent message")
+	}
+	// the last size is the original one
+	return m.Photo[len(m.Photo)-1].FileID, nil
+}
@@ -0,0 +1,124 @@
+package bot
+
+import (
+	"monitor/i18n"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// noteLanguage records the language of the Telegram app of the sender of a private message, alerts to the chat are sent in it unless set otherwise with /lang
+func (b *Bot) noteLanguage(update tgbotapi.Update) {
+	u, chat := update.SentFrom(), update.FromChat()
+	if u == nil || chat == nil || !chat.IsPrivate() || u.LanguageCode == "" {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.languages[chat.ID] = u.LanguageCode
+	if s, ok := b.subscribers[chat.ID]; ok && s.LanguageCode != u.LanguageCode {
+		s.LanguageCode = u.LanguageCode
+		b.subscribers[chat.ID] = s
+		b.persist(chat.ID)
+	}
+}
+
+// Locale returns the locale to reply to update in: the language set with /lang in the chat, or else the language of the Telegram app of the sender.
+func (b *Bot) Locale(update tgbotapi.Update) *i18n.Locale {
+	if chat := update.FromChat(); chat != nil {
+		if s, ok := b.GetSubscriber(chat.ID); ok && s.Lang != "" {
+			return i18n.Match(s.Lang)
+		}
+	}
+	return b.UserLocale(update.SentFrom())
+}
+
+// UserLocale returns the locale of the Telegram app of u, e.g. to answer inline queries.
+func (b *Bot) UserLocale(u *tgbotapi.User) *i18n.Locale {
+	if u == nil {
+		return i18n.Default
+	}
+	if u.LanguageCode != "" {
+		return i18n.Match(u.LanguageCode)
+	}
+	return b.ChatLocale(u.ID)
+}
+
+// ChatLocale returns the locale of messages to chatID that are not replies, e.g. alerts: the language set with /lang, or else the language of the Telegram app of the user in private chats.
+func (b *Bot) ChatLocale(chatID int64) *i18n.Locale {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	s := b.subscribers[chatID]
+	for _, lang := range []string{s.Lang, s.LanguageCode, b.languages[chatID]} {
+		if lang != "" {
+			return i18n.Match(lang)
+		}
+	}
+	return i18n.Default
+}
+
+// T returns the message key in the locale of the reply to update, see i18n.Locale.T.
+func (b *Bot) T(update tgbotapi.Update, key string, args ...interface{}) string {
+	return b.Locale(update).T(key, args...)
+}
+
+// Reply sends the message key in the locale of update to its chat, see T.
+func (b *Bot) Reply(update tgbotapi.Update, key string, args ...interface{}) (tgbotapi.Message, error) {
+	return b.SendMsg(update.FromChat().ID, b.T(update, key, args...))
+}
+
+// SetLang sets the language of a subscriber, "" to follow their Telegram app. It returns false if chatID is not subscribed.
+func (b *Bot) SetLang(chatID int64, lang string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	s, ok := b.subscribers[chatID]
+	if !ok {
+		return false
+	}
+
+	s.Lang = lang
+	b.subscribers[chatID] = s
+	b.persist(chatID)
+	return true
+}
+
+// LangChoices returns the arguments of /lang: the tags of the locales and auto.
+func LangChoices() []string {
+	choices := []string{}
+	for _, l := range i18n.Locales() {
+		choices = append(choices, strings.ToLower(l.Tag))
+	}
+	return append(choices, "auto")
+}
+
+// CmdLang handle /lang command, it shows or sets the language of the chat. It expects the optional "lang" argument, see LangChoices.
+func CmdLang(b *Bot, update tgbotapi.Update, args Args) {
+	chatID := update.Message.Chat.ID
+
+	s, ok := b.GetSubscriber(chatID)
+	if !ok {
+		b.Reply(update, "not_subscribed")
+		return
+	}
+
+	lang := args.String("lang")
+	if lang == "" {
+		names := []string{}
+		for _, l := range i18n.Locales() {
+			names = append(names, strings.ToLower(l.Tag)+" - "+l.Name)
+		}
+		b.Reply(update, "lang.show", "name", b.ChatLocale(chatID).Name, "auto", s.Lang == "", "langs", names)
+		return
+	}
+
+	if lang == "auto" {
+		lang = ""
+	}
+	b.SetLang(chatID, lang)
+	l := b.ChatLocale(chatID)
+	b.SendMsg(chatID, l.T("lang.set", "name", l.Name, "auto", lang == ""))
+}
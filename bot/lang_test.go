@@ -0,0 +1,41 @@
+package bot
+
+import (
+	"monitor/alert"
+	"monitor/i18n"
+	"testing"
+	"time"
+)
+
+func TestE2ELang(t *testing.T) {
+	b, srv := startBot(t)
+	b.Grant(10, Viewer)
+	b.AddCmd("lang", "", false, CmdLang).Args(EnumArg("lang", "", LangChoices()...).Opt())
+
+	// replies follow the Telegram app of the sender
+	srv.Language(10, "zh-hant")
+	srv.Message(10, "/lang")
+	srv.WaitText(10, "尚未訂閱")
+
+	// and so do alerts to the chat
+	b.Subscribe(10)
+	high := func(rule string) alert.Alert {
+		a := alert.Alert{State: alert.Firing, Severity: alert.Warning, Rule: rule, Metric: "cpu", Time: time.Now(), Msg: "alert.high", Args: i18n.Args("metric", "cpu", "value", 91.5)}
+		a.Text = i18n.Default.Format(a.Msg, a.Args)
+		return a
+	}
+	b.BroadcastAlerts(high("a"))
+	srv.WaitText(10, "偵測到CPU使用率過高：91.50%")
+
+	// unless set otherwise with /lang
+	srv.Message(10, "/lang en")
+	srv.WaitText(10, "Language set to English")
+	if s, _ := b.GetSubscriber(10); s.Lang != "en" || s.LanguageCode != "zh-hant" {
+		t.Errorf("lang = %q, language_code = %q, want en and zh-hant", s.Lang, s.LanguageCode)
+	}
+	b.BroadcastAlerts(high("b"))
+	srv.WaitText(10, "High CPU usage detected: 91.50%")
+
+	srv.Message(10, "/lang auto")
+	srv.WaitText(10, "語言已設為繁體中文，跟隨你的 Telegram 應用程式")
+}
@@ -0,0 +1,129 @@
+package bot
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Telegram allows about 30 messages per second overall, one message per second in a private chat and 20 messages per minute in a group.
+const (
+	globalInterval  = time.Second / 30
+	privateInterval = time.Second
+	groupInterval   = 3 * time.Second
+)
+
+// limiter spaces out messages to respect Telegram's rate limits
+type limiter struct {
+	mu    sync.Mutex
+	next  time.Time           // earliest time of the next message
+	chats map[int64]time.Time // earliest time of the next message per chat
+	off   bool                // messages are not spaced out, see DisableLimits
+}
+
+func newLimiter() *limiter {
+	return &limiter{
+		chats: map[int64]time.Time{},
+	}
+}
+
+// reserve reserves a slot for a message to chatID and returns how long to wait before sending it. A chatID of 0 only counts towards the global limit.
+func (l *limiter) reserve(chatID int64) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.off {
+		return 0
+	}
+
+	now := time.Now()
+	t := now
+	if l.next.After(t) {
+		t = l.next
+	}
+	if c := l.chats[chatID]; chatID != 0 && c.After(t) {
+		t = c
+	}
+
+	l.next = t.Add(globalInterval)
+	if chatID != 0 {
+		interval := privateInterval
+		if chatID < 0 {
+			interval = groupInterval
+		}
+		l.chats[chatID] = t.Add(interval)
+	}
+
+	// forget idle chats
+	for id, c := range l.chats {
+		if c.Before(now) {
+			delete(l.chats, id)
+		}
+	}
+
+	return t.Sub(now)
+}
+
+// DisableLimits makes the bot send messages as soon as it can instead of spacing them out for Telegram's rate limits, e.g. against a fake server in tests.
+func (b *Bot) DisableLimits() {
+	b.limiter.mu.Lock()
+	defer b.limiter.mu.Unlock()
+	b.limiter.off = true
+}
+
+// chatOf returns the chat a Chattable is sent to, or 0 if it is unknown
+func chatOf(c tgbotapi.Chattable) int64 {
+	switch c := c.(type) {
+	case tgbotapi.MessageConfig:
+		return c.ChatID
+	case tgbotapi.PhotoConfig:
+		return c.ChatID
+	case tgbotapi.DocumentConfig:
+		return c.ChatID
+	case tgbotapi.EditMessageTextConfig:
+		return c.ChatID
+	case tgbotapi.EditMessageReplyMarkupConfig:
+		return c.ChatID
+	}
+	return 0
+}
+
+// sendError classifies a failed request
+type sendError int
+
+const (
+	errTemporary sendError = iota // network error or server error, worth retrying
+	errRateLimit                  // 429 Too Many Requests, retry after the given time
+	errPermanent                  // the request itself is wrong, e.g. the message is too long
+	errBlocked                    // the chat is gone for good, e.g. the user blocked the bot
+)
+
+// classify tells what to do about err, retryAfter is set for errRateLimit. Uploads fail with no error code, so the description of the error counts as well.
+func classify(err error) (kind sendError, retryAfter time.Duration) {
+	var e *tgbotapi.Error
+	if !errors.As(err, &e) {
+		return errTemporary, 0
+	}
+
+	switch {
+	case e.Code == 429 || e.RetryAfter > 0:
+		return errRateLimit, time.Duration(e.RetryAfter) * time.Second
+	case e.Code == 403 || strings.HasPrefix(e.Message, "Forbidden:"):
+		return errBlocked, 0
+	case strings.Contains(e.Message, "chat not found"):
+		return errBlocked, 0
+	case e.Code >= 400 && e.Code < 500 || strings.HasPrefix(e.Message, "Bad Request:"):
+		return errPermanent, 0
+	}
+
+	return errTemporary, 0
+}
+
+// backoff returns the delay before the next attempt after the given number of failed attempts
+func backoff(attempts int) time.Duration {
+	d := time.Second << min(attempts, 10)
+	return min(d, 10*time.Minute)
+}
@@ -0,0 +1,89 @@
+package bot
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestClassify(t *testing.T) {
+	for _, c := range []struct {
+		err        error
+		kind       sendError
+		retryAfter time.Duration
+	}{
+		{errors.New("connection reset"), errTemporary, 0},
+		{&tgbotapi.Error{Code: 502, Message: "Bad Gateway"}, errTemporary, 0},
+		{&tgbotapi.Error{Code: 429, ResponseParameters: tgbotapi.ResponseParameters{RetryAfter: 5}}, errRateLimit, 5 * time.Second},
+		{&tgbotapi.Error{Code: 403, Message: "Forbidden: bot was blocked by the user"}, errBlocked, 0},
+		{&tgbotapi.Error{Code: 400, Message: "Bad Request: chat not found"}, errBlocked, 0},
+		{&tgbotapi.Error{Code: 400, Message: "Bad Request: message is too long"}, errPermanent, 0},
+		// uploads fail without an error code
+		{&tgbotapi.Error{Message: "Too Many Requests: retry after 7", ResponseParameters: tgbotapi.ResponseParameters{RetryAfter: 7}}, errRateLimit, 7 * time.Second},
+		{&tgbotapi.Error{Message: "Forbidden: bot was blocked by the user"}, errBlocked, 0},
+		{&tgbotapi.Error{Message: "Bad Request: chat not found"}, errBlocked, 0},
+		{&tgbotapi.Error{Message: "Bad Request: PHOTO_INVALID_DIMENSIONS"}, errPermanent, 0},
+		{&tgbotapi.Error{Message: "Internal Server Error"}, errTemporary, 0},
+	} {
+		kind, retryAfter := classify(c.err)
+		if kind != c.kind || retryAfter != c.retryAfter {
+			t.Errorf("classify(%v) = %d, %s, want %d, %s", c.err, kind, retryAfter, c.kind, c.retryAfter)
+		}
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	for attempts, want := range map[int]time.Duration{
+		0:  time.Second,
+		1:  2 * time.Second,
+		3:  8 * time.Second,
+		9:  512 * time.Second,
+		10: 10 * time.Minute,
+		50: 10 * time.Minute,
+	} {
+		if got := backoff(attempts); got != want {
+			t.Errorf("backoff(%d) = %s, want %s", attempts, got, want)
+		}
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	// within is true if d is want, give or take the time the test takes
+	within := func(d, want time.Duration) bool {
+		return d <= want && d > want-50*time.Millisecond
+	}
+
+	l := newLimiter()
+	if d := l.reserve(10); d != 0 {
+		t.Errorf("first message waits %s", d)
+	}
+	if d := l.reserve(10); !within(d, privateInterval) {
+		t.Errorf("second message to a private chat waits %s, want %s", d, privateInterval)
+	}
+
+	l = newLimiter()
+	l.reserve(-100)
+	if d := l.reserve(-100); !within(d, groupInterval) {
+		t.Errorf("second message to a group waits %s, want %s", d, groupInterval)
+	}
+
+	// other chats only wait for the global limit
+	l = newLimiter()
+	l.reserve(10)
+	if d := l.reserve(11); !within(d, globalInterval) {
+		t.Errorf("message to another chat waits %s, want %s", d, globalInterval)
+	}
+	if d := l.reserve(0); !within(d, 2*globalInterval) {
+		t.Errorf("message to an unknown chat waits %s, want %s", d, 2*globalInterval)
+	}
+
+	// disabled limits never wait
+	l = newLimiter()
+	l.off = true
+	l.reserve(-100)
+	if d := l.reserve(-100); d != 0 {
+		t.Errorf("message waits %s with limits disabled", d)
+	}
+}
@@ -0,0 +1,230 @@
+package bot
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	menuDelay          = time.Second // wait for more changes before updating the command menu
+	maxMenuDescription = 256         // longest command description Telegram takes
+	helpDescription    = "Show the commands or the usage of one"
+)
+
+// menu is the state of the command menus registered with Telegram
+type menu struct {
+	mu     sync.Mutex // serializes the updates
+	on     bool       // update on changes, set once HandleUpdates starts, guarded by Bot.mu
+	timer  *time.Timer
+	synced map[string]menuEntry // registered menus by scope and language
+}
+
+// menuEntry is a registered command menu
+type menuEntry struct {
+	scope tgbotapi.BotCommandScope
+	lang  string
+	data  string // JSON of the commands, to tell if they changed
+}
+
+// menuScope is the audience of a command menu, see menuScopes
+type menuScope struct {
+	key   string
+	scope tgbotapi.BotCommandScope
+	chats Scope // kind of chat of the audience
+	role  Role  // role of the audience
+}
+
+// Describe sets the description of the command in the menu of users whose Telegram app is in the given language, a two-letter ISO 639-1 code such as "zh".
+func (c *Cmd) Describe(lang string, description string) *Cmd {
+	if c.Descriptions == nil {
+		c.Descriptions = map[string]string{}
+	}
+	c.Descriptions[lang] = description
+	c.changed()
+	return c
+}
+
+// changed updates the command menu after a change of c
+func (c *Cmd) changed() {
+	if c.bot == nil {
+		return
+	}
+	c.bot.mu.Lock()
+	defer c.bot.mu.Unlock()
+	c.bot.scheduleMenu()
+}
+
+// scheduleMenu updates the command menu soon if it is kept in sync, it must be called with b.mu held
+func (b *Bot) scheduleMenu() {
+	if !b.menu.on {
+		return
+	}
+	if b.menu.timer == nil {
+		b.menu.timer = time.AfterFunc(menuDelay, b.SyncMenu)
+		return
+	}
+	b.menu.timer.Reset(menuDelay)
+}
+
+// startMenu registers the command menus and keeps them in sync with the commands and roles from now on
+func (b *Bot) startMenu() {
+	b.mu.Lock()
+	b.menu.on = true
+	b.mu.Unlock()
+
+	b.SyncMenu()
+}
+
+// stopMenu stops keeping the command menus in sync
+func (b *Bot) stopMenu() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.menu.on = false
+	if b.menu.timer != nil {
+		b.menu.timer.Stop()
+	}
+}
+
+// menuScopes returns the audiences of command menus: private chats and groups get the commands of viewers, and users with a higher role get theirs in their private chat
+func (b *Bot) menuScopes() []menuScope {
+	scopes := []menuScope{
+		{key: "private", scope: tgbotapi.NewBotCommandScopeAllPrivateChats(), chats: InPrivate, role: Viewer},
+		{key: "groups", scope: tgbotapi.NewBotCommandScopeAllGroupChats(), chats: InGroups, role: Viewer},
+	}
+
+	for id, role := range b.roles {
+		if role > Viewer {
+			scopes = append(scopes, menuScope{
+				key:   fmt.Sprintf("chat %d", id),
+				scope: tgbotapi.NewBotCommandScopeChat(id),
+				chats: InPrivate,
+				role:  role,
+			})
+		}
+	}
+
+	return scopes
+}
+
+// menuCommands returns the menu of the audience s in lang, it must be called with b.mu held
+func (b *Bot) menuCommands(s menuScope, lang string) []tgbotapi.BotCommand {
+	names := []string{}
+	for name, c := range b.cmd {
+		if !c.Hide && c.Role <= s.role && c.Scope&s.chats != 0 {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	commands := []tgbotapi.BotCommand{{Command: "help", Description: helpDescription}}
+	if d, ok := b.helpDescriptions[lang]; ok {
+		commands[0].Description = d
+	}
+	for _, name := range names {
+		c := b.cmd[name]
+		d, ok := c.Descriptions[lang]
+		if !ok {
+			d = c.Description
+		}
+		if d == "" {
+			// Telegram rejects empty descriptions
+			d = name
+		}
+		d = truncate(d, maxMenuDescription)
+		commands = append(commands, tgbotapi.BotCommand{Command: name, Description: d})
+	}
+
+	return commands
+}
+
+// truncate shortens s to n characters with an ellipsis, Telegram counts the length in characters and cutting bytes could split one
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
+
+// menuLanguages returns the languages commands are described in besides the default one, it must be called with b.mu held
+func (b *Bot) menuLanguages() []string {
+	seen := map[string]bool{}
+	for _, c := range b.cmd {
+		for lang := range c.Descriptions {
+			seen[lang] = true
+		}
+	}
+	for lang := range b.helpDescriptions {
+		seen[lang] = true
+	}
+
+	langs := []string{}
+	for lang := range seen {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// DescribeHelp sets the description of /help in the menu of users whose Telegram app is in lang, see Cmd.Describe.
+func (b *Bot) DescribeHelp(lang string, description string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.helpDescriptions[lang] = description
+	b.scheduleMenu()
+}
+
+// SyncMenu registers the command menus that changed since the last time with Telegram. It is called when HandleUpdates starts and after commands or roles change, failures are logged and retried on the next change.
+func (b *Bot) SyncMenu() {
+	b.menu.mu.Lock()
+	defer b.menu.mu.Unlock()
+
+	wanted := map[string]menuEntry{}
+	menus := map[string][]tgbotapi.BotCommand{}
+
+	b.mu.Lock()
+	langs := append([]string{""}, b.menuLanguages()...)
+	for _, s := range b.menuScopes() {
+		for _, lang := range langs {
+			key := s.key + " " + lang
+			commands := b.menuCommands(s, lang)
+			data, _ := json.Marshal(commands)
+			wanted[key] = menuEntry{scope: s.scope, lang: lang, data: string(data)}
+			menus[key] = commands
+		}
+	}
+	b.mu.Unlock()
+
+	for key, e := range wanted {
+		if b.menu.synced[key].data == e.data {
+			continue
+		}
+		config := tgbotapi.NewSetMyCommandsWithScopeAndLanguage(e.scope, e.lang, menus[key]...)
+		if _, err := b.Request(config); err != nil {
+			log.Printf("set command menu %s: %v\n", strings.TrimSpace(key), err)
+			continue
+		}
+		b.menu.synced[key] = e
+	}
+
+	// menus nobody should see anymore, e.g. of revoked users
+	for key, e := range b.menu.synced {
+		if _, ok := wanted[key]; ok {
+			continue
+		}
+		if _, err := b.Request(tgbotapi.NewDeleteMyCommandsWithScopeAndLanguage(e.scope, e.lang)); err != nil {
+			log.Printf("delete command menu %s: %v\n", strings.TrimSpace(key), err)
+			continue
+		}
+		delete(b.menu.synced, key)
+	}
+}
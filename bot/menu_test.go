@@ -0,0 +1,87 @@
+package bot
+
+import (
+	"encoding/json"
+	"monitor/bot/bottest"
+	"strings"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// menuOf waits for a setMyCommands request of the given scope type and language, and returns the commands
+func menuOf(srv *bottest.Server, scope string, lang string, has string) []tgbotapi.BotCommand {
+	var commands []tgbotapi.BotCommand
+	srv.Wait(func(r bottest.Request) bool {
+		if r.Method != "setMyCommands" || !strings.Contains(r.Params["scope"], `"type":"`+scope+`"`) || r.Params["language_code"] != lang {
+			return false
+		}
+		commands = nil
+		json.Unmarshal([]byte(r.Params["commands"]), &commands)
+		for _, c := range commands {
+			if c.Command == has {
+				return true
+			}
+		}
+		return false
+	})
+	return commands
+}
+
+func TestE2EMenu(t *testing.T) {
+	noop := func(*Bot, tgbotapi.Update, Args) {}
+
+	b, srv := startBot(t)
+	b.AddCmd("status", "Get status", false, noop).Describe("zh", "查看狀態")
+	b.AddCmd("order", "Order", false, noop).In(InPrivate)
+	b.AddCmd("debug", "Debug", true, noop)
+	b.AddCmd("grant", "Give a role", false, noop).Require(Admin)
+
+	private := menuOf(srv, "all_private_chats", "", "order")
+	names := []string{}
+	for _, c := range private {
+		names = append(names, c.Command)
+	}
+	if got := strings.Join(names, " "); got != "help order status" {
+		t.Errorf("private menu = %s, want help order status", got)
+	}
+
+	for _, c := range menuOf(srv, "all_group_chats", "", "status") {
+		if c.Command == "order" {
+			t.Error("private only command in the group menu")
+		}
+	}
+
+	for _, c := range menuOf(srv, "all_private_chats", "zh", "status") {
+		if c.Command == "status" && c.Description != "查看狀態" {
+			t.Errorf("zh description = %q, want 查看狀態", c.Description)
+		}
+	}
+
+	// admins get their commands in their private chat
+	b.Grant(10, Admin)
+	menuOf(srv, "chat", "", "grant")
+
+	b.Grant(10, Guest)
+	srv.Wait(func(r bottest.Request) bool {
+		return r.Method == "deleteMyCommands" && strings.Contains(r.Params["scope"], `"chat_id":10`)
+	})
+}
+
+func TestTruncate(t *testing.T) {
+	for _, c := range []struct {
+		s    string
+		n    int
+		want string
+	}{
+		{"status", 6, "status"},
+		{"status", 5, "stat…"},
+		{"查看狀態", 4, "查看狀態"},
+		{"查看狀態", 3, "查看…"},
+		{strings.Repeat("態", maxMenuDescription+1), maxMenuDescription, strings.Repeat("態", maxMenuDescription-1) + "…"},
+	} {
+		if got := truncate(c.s, c.n); got != c.want {
+			t.Errorf("truncate(%q, %d) = %q, want %q", c.s, c.n, got, c.want)
+		}
+	}
+}
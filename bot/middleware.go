@@ -0,0 +1,139 @@
+package bot
+
+import (
+	"log"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Middleware wraps the handling of commands, button presses, form replies and inline queries, e.g. to log them. It calls next to go on with the update, or not to drop it.
+type Middleware func(next UpdateHandleFunc) UpdateHandleFunc
+
+// Use adds middlewares, the first one added sees the updates first.
+func (b *Bot) Use(m ...Middleware) {
+	b.middlewares = append(b.middlewares, m...)
+}
+
+// serve passes update through the middlewares to handle
+func (b *Bot) serve(update tgbotapi.Update, handle func(*Bot, tgbotapi.Update)) {
+	h := UpdateHandleFunc(handle)
+	for i := len(b.middlewares) - 1; i >= 0; i-- {
+		h = b.middlewares[i](h)
+	}
+	h(b, update)
+}
+
+// describe returns what the update is about, e.g. "/set cpu_threshold 90" or "button plot:cpu:1h"
+func describe(update tgbotapi.Update) string {
+	switch {
+	case update.Message != nil && update.Message.IsCommand():
+		return update.Message.Text
+	case update.Message != nil:
+		return "reply " + update.Message.Text
+	case update.CallbackQuery != nil:
+		return "button " + update.CallbackQuery.Data
+	case update.InlineQuery != nil:
+		return "inline " + update.InlineQuery.Query
+	}
+	return "update"
+}
+
+// replyError tells the user who sent update that handling it failed
+func (b *Bot) replyError(update tgbotapi.Update, text string) {
+	if update.CallbackQuery != nil {
+		b.Toast(update, text)
+		return
+	}
+	if c := update.FromChat(); c != nil {
+		b.SendMsg(c.ID, text)
+	}
+}
+
+// Recover is a middleware recovering from panics in handlers, it logs the panic and tells the user something went wrong.
+func Recover(next UpdateHandleFunc) UpdateHandleFunc {
+	return func(b *Bot, update tgbotapi.Update) {
+		defer func() {
+			if r := recover(); r != nil {
+				log.Printf("panic handling %s: %v\n%s", describe(update), r, debug.Stack())
+				b.replyError(update, b.T(update, "error.panic"))
+			}
+		}()
+		next(b, update)
+	}
+}
+
+// Logger is a middleware logging every update with its sender and how long handling it took, also when the handler panics.
+func Logger(next UpdateHandleFunc) UpdateHandleFunc {
+	return func(b *Bot, update tgbotapi.Update) {
+		start := time.Now()
+		defer func() {
+			chatID := int64(0)
+			if c := update.FromChat(); c != nil {
+				chatID = c.ID
+			}
+			log.Printf("%s from %s in %d took %s\n", describe(update), UserName(update), chatID, time.Since(start).Round(time.Millisecond))
+		}()
+		next(b, update)
+	}
+}
+
+// RateLimit returns a middleware allowing every user at most n updates per interval, the others are dropped with a warning.
+func RateLimit(n int, interval time.Duration) Middleware {
+	var mu sync.Mutex
+	seen := map[int64][]time.Time{} // recent updates per user
+	warned := map[int64]time.Time{}
+
+	return func(next UpdateHandleFunc) UpdateHandleFunc {
+		return func(b *Bot, update tgbotapi.Update) {
+			u := update.SentFrom()
+			if u == nil {
+				next(b, update)
+				return
+			}
+
+			now := time.Now()
+			mu.Lock()
+			// forget updates out of the interval, and idle users
+			for id, times := range seen {
+				i := 0
+				for i < len(times) && now.Sub(times[i]) >= interval {
+					i++
+				}
+				if i == len(times) {
+					delete(seen, id)
+					delete(warned, id)
+				} else {
+					seen[id] = times[i:]
+				}
+			}
+
+			limited := len(seen[u.ID]) >= n
+			warn := false
+			if limited {
+				// warn once per interval
+				if now.Sub(warned[u.ID]) >= interval {
+					warned[u.ID] = now
+					warn = true
+				}
+			} else {
+				seen[u.ID] = append(seen[u.ID], now)
+			}
+			mu.Unlock()
+
+			if !limited {
+				next(b, update)
+				return
+			}
+
+			log.Printf("rate limited %s: %s\n", UserName(update), describe(update))
+			if warn {
+				b.replyError(update, b.T(update, "error.rate_limit", "n", n, "interval", interval))
+			} else if update.CallbackQuery != nil {
+				b.Toast(update, b.T(update, "error.rate_limit_toast"))
+			}
+		}
+	}
+}
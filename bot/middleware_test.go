@@ -0,0 +1,77 @@
+package bot
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// noChat returns a message update sent by the given user without a chat to reply to
+func noChat(userID int64) tgbotapi.Update {
+	return tgbotapi.Update{Message: &tgbotapi.Message{From: &tgbotapi.User{ID: userID}}}
+}
+
+func TestMiddleware(t *testing.T) {
+	b, _ := New(nil, nil)
+
+	order := []string{}
+	mark := func(name string) Middleware {
+		return func(next UpdateHandleFunc) UpdateHandleFunc {
+			return func(b *Bot, update tgbotapi.Update) {
+				order = append(order, name)
+				next(b, update)
+			}
+		}
+	}
+	b.Use(mark("a"), mark("b"))
+	b.Use(Recover)
+
+	b.serve(noChat(1), func(*Bot, tgbotapi.Update) {
+		order = append(order, "handler")
+		panic("boom")
+	})
+
+	if got := strings.Join(order, ","); got != "a,b,handler" {
+		t.Errorf("order = %s", got)
+	}
+}
+
+func TestLoggerPanic(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	// with Recover first, Logger sees the panic go by
+	b, _ := New(nil, nil)
+	b.Use(Recover, Logger)
+	b.serve(noChat(1), func(*Bot, tgbotapi.Update) {
+		panic("boom")
+	})
+
+	if !strings.Contains(buf.String(), "took") {
+		t.Errorf("the update was not logged:\n%s", buf.String())
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	b, _ := New(nil, nil)
+	b.Use(RateLimit(2, time.Hour))
+
+	handled := map[int64]int{}
+	count := func(b *Bot, update tgbotapi.Update) {
+		handled[update.SentFrom().ID]++
+	}
+	for i := 0; i < 5; i++ {
+		b.serve(noChat(1), count)
+	}
+	b.serve(noChat(2), count)
+
+	if handled[1] != 2 || handled[2] != 1 {
+		t.Errorf("handled = %v, want 2 for user 1 and 1 for user 2", handled)
+	}
+}
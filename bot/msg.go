@@ -1,6 +1,15 @@
 package bot
 
-import tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+import (
+	"fmt"
+	"log"
+	"monitor/alert"
+	"monitor/i18n"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
 
 // Message
 
@@ -8,9 +17,47 @@ func (b *Bot) Request(c tgbotapi.Chattable) (*tgbotapi.APIResponse, error) {
 	return b.Bot.Request(c)
 }
 
-// Send sends a Chattable to a user.
+// Send sends a Chattable to a user. It waits for Telegram's rate limits, retries when Telegram asks to slow down or the network fails, and unsubscribes chats that blocked the bot.
 func (b *Bot) Send(msg tgbotapi.Chattable) (tgbotapi.Message, error) {
-	return b.Bot.Send(msg)
+	chatID := chatOf(msg)
+
+	var m tgbotapi.Message
+	var err error
+	for i := 0; i < sendRetries; i++ {
+		time.Sleep(b.limiter.reserve(chatID))
+
+		m, err = b.Bot.Send(msg)
+		if err == nil {
+			return m, nil
+		}
+
+		kind, retryAfter := classify(err)
+		if kind == errRateLimit {
+			time.Sleep(retryAfter)
+		} else if kind == errTemporary {
+			time.Sleep(backoff(i))
+		} else {
+			break
+		}
+	}
+
+	log.Printf("send to %d: %v\n", chatID, err)
+	kind, _ := classify(err)
+	if kind == errPermanent || kind == errBlocked {
+		text := ""
+		if m, ok := msg.(tgbotapi.MessageConfig); ok {
+			text = m.Text
+		}
+		b.queue.mu.Lock()
+		b.queue.fail(chatID, text, err)
+		b.queue.save()
+		b.queue.mu.Unlock()
+	}
+	if kind == errBlocked && chatID != 0 {
+		b.dropChat(chatID, err)
+	}
+
+	return m, err
 }
 
 // SendMsg sends a message to a user.
@@ -18,9 +65,88 @@ func (b *Bot) SendMsg(chatID int64, msg string) (tgbotapi.Message, error) {
 	return b.Send(tgbotapi.NewMessage(chatID, msg))
 }
 
-// Broadcast sends a message to all subscribers.
+// BroadcastAlert sends an alert with its buttons to all subscribers unless a silence matches it.
+func (b *Bot) BroadcastAlert(a alert.Alert) {
+	b.BroadcastAlerts(a)
+}
+
+// BroadcastAlerts sends alerts raised together to all subscribers, as one message per subscriber if there are several. Alerts matching a silence are suppressed, each subscriber only gets the alerts their preferences allow, and low severity alerts go to the digest of subscribers in digest mode.
+func (b *Bot) BroadcastAlerts(alerts ...alert.Alert) {
+	send := []alert.Alert{}
+	for _, a := range alerts {
+		a = b.record(a)
+		if b.silence(a) {
+			continue
+		}
+		send = append(send, a)
+	}
+	if len(send) == 0 {
+		return
+	}
+
+	now := time.Now()
+	for _, chatID := range b.subscriberIDs() {
+		immediate := []alert.Alert{}
+		for _, a := range send {
+			if b.wants(chatID, a, now) && !b.digest(chatID, a) {
+				immediate = append(immediate, a)
+			}
+		}
+		b.sendAlerts(chatID, immediate)
+	}
+}
+
+// AlertText returns the text of a in the language of l, alerts without a message key only have their Text.
+func AlertText(l *i18n.Locale, a alert.Alert) string {
+	if a.Msg == "" {
+		return a.Text
+	}
+	return l.Format(a.Msg, a.Args)
+}
+
+// sendAlerts queues a message with the alerts and their buttons to chatID
+func (b *Bot) sendAlerts(chatID int64, alerts []alert.Alert) {
+	l := b.ChatLocale(chatID)
+
+	switch len(alerts) {
+	case 0:
+		return
+	case 1:
+		markup := b.alertButtons(l, alerts[0])
+		b.enqueue(chatID, AlertText(l, alerts[0]), &markup)
+		return
+	}
+
+	var sb strings.Builder
+	sb.WriteString(l.T("alerts", "n", len(alerts)) + "\n")
+	for _, a := range alerts {
+		sb.WriteString(fmt.Sprintf("\n#%s %s\n", a.ID, AlertText(l, a)))
+	}
+
+	markup := b.groupButtons(l, alerts)
+	b.enqueue(chatID, sb.String(), &markup)
+}
+
+// enqueue queues a message to chatID, it is delivered in the background with retries
+func (b *Bot) enqueue(chatID int64, text string, markup *tgbotapi.InlineKeyboardMarkup) {
+	b.queue.push(chatID, b.thread(chatID), text, markup)
+}
+
+// Broadcast queues a message to all subscribers, alerts should go through BroadcastAlert so that silences apply.
 func (b *Bot) Boradcast(msg string) {
-	for chatID := range b.subscribers {
-		b.SendMsg(chatID, msg)
+	for _, chatID := range b.subscriberIDs() {
+		b.enqueue(chatID, msg, nil)
+	}
+}
+
+// BroadcastT queues the message key to all subscribers, each in their language, see i18n.Locale.T.
+func (b *Bot) BroadcastT(key string, args ...interface{}) {
+	b.broadcastf(func(l *i18n.Locale) string { return l.T(key, args...) })
+}
+
+// broadcastf queues a message to all subscribers, text returns it in the language of each
+func (b *Bot) broadcastf(text func(l *i18n.Locale) string) {
+	for _, chatID := range b.subscriberIDs() {
+		b.enqueue(chatID, text(b.ChatLocale(chatID)), nil)
 	}
 }
@@ -0,0 +1,235 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"monitor/alert"
+	"monitor/i18n"
+	"slices"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Prefs are the alert preferences of a subscriber. The zero value receives every alert at any time.
+type Prefs struct {
+	MinSeverity alert.Severity `json:"min_severity"`       // alerts below this severity are not sent
+	Metrics     []string       `json:"metrics,omitempty"`  // rules or metrics to receive alerts of, all if empty
+	QuietFrom   int            `json:"quiet_from"`         // start of the quiet hours in minutes after midnight
+	QuietTo     int            `json:"quiet_to"`           // end of the quiet hours, no quiet hours if equal to QuietFrom
+	Timezone    string         `json:"timezone,omitempty"` // IANA name of the timezone of the quiet hours, UTC if empty
+	Classes     []string       `json:"classes,omitempty"`  // classes of the hosts to receive alerts of, all if empty
+}
+
+// location returns the timezone of the quiet hours
+func (p Prefs) location() *time.Location {
+	loc, err := time.LoadLocation(p.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// Quiet checks if now is in the quiet hours.
+func (p Prefs) Quiet(now time.Time) bool {
+	if p.QuietFrom == p.QuietTo {
+		return false
+	}
+
+	t := now.In(p.location())
+	m := t.Hour()*60 + t.Minute()
+
+	if p.QuietFrom < p.QuietTo {
+		return m >= p.QuietFrom && m < p.QuietTo
+	}
+	// quiet hours over midnight, e.g. 22:00-07:00
+	return m >= p.QuietFrom || m < p.QuietTo
+}
+
+// Allow checks if a should be sent to the subscriber at now. Critical alerts are sent during quiet hours, the others are dropped.
+func (p Prefs) Allow(a alert.Alert, now time.Time) bool {
+	if a.Severity < p.MinSeverity {
+		return false
+	}
+
+	if len(p.Metrics) > 0 {
+		match := false
+		for _, m := range p.Metrics {
+			if a.Matches(m) {
+				match = true
+				break
+			}
+		}
+		if !match {
+			return false
+		}
+	}
+
+	if len(p.Classes) > 0 && !slices.Contains(p.Classes, a.Class) {
+		return false
+	}
+
+	return a.Severity >= alert.Critical || !p.Quiet(now)
+}
+
+// quietHours returns the quiet hours in text, empty if there are none
+func (p Prefs) quietHours() string {
+	if p.QuietFrom == p.QuietTo {
+		return ""
+	}
+	tz := p.Timezone
+	if tz == "" {
+		tz = "UTC"
+	}
+	return fmt.Sprintf("%s-%s %s", clock(p.QuietFrom), clock(p.QuietTo), tz)
+}
+
+// text returns the preferences in the language of l
+func (p Prefs) text(l *i18n.Locale) string {
+	return l.T("prefs", "severity", p.MinSeverity.String(), "metrics", strings.Join(p.Metrics, ", "), "quiet", p.quietHours())
+}
+
+// clock formats minutes after midnight as hh:mm
+func clock(m int) string {
+	return fmt.Sprintf("%02d:%02d", m/60, m%60)
+}
+
+// parseClock parses hh:mm into minutes after midnight
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, use hh:mm", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// wants checks if the subscriber chatID wants a at now
+func (b *Bot) wants(chatID int64, a alert.Alert, now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	s, ok := b.subscribers[chatID]
+	return ok && s.Prefs.Allow(a, now)
+}
+
+// recipients returns the subscribers that want a at now
+func (b *Bot) recipients(a alert.Alert, now time.Time) []int64 {
+	ids := []int64{}
+	for _, chatID := range b.subscriberIDs() {
+		if b.wants(chatID, a, now) {
+			ids = append(ids, chatID)
+		}
+	}
+	return ids
+}
+
+// SetPrefs sets the alert preferences of a subscriber. It returns false if chatID is not subscribed.
+func (b *Bot) SetPrefs(chatID int64, p Prefs) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	s, ok := b.subscribers[chatID]
+	if !ok {
+		return false
+	}
+
+	s.Prefs = p
+	b.subscribers[chatID] = s
+	b.persist(chatID)
+	return true
+}
+
+// parseQuiet parses quiet hours like 22:00-07:00, or "off" for none
+func parseQuiet(s string) (from int, to int, err error) {
+	if s == "off" {
+		return 0, 0, nil
+	}
+
+	f, t, ok := strings.Cut(s, "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid quiet hours %q, use hh:mm-hh:mm or off", s)
+	}
+	if from, err = parseClock(f); err != nil {
+		return 0, 0, err
+	}
+	if to, err = parseClock(t); err != nil {
+		return 0, 0, err
+	}
+	return from, to, nil
+}
+
+// prefsForm returns the form of /prefs in the language of l, the defaults are the current preferences p
+func prefsForm(l *i18n.Locale, chatID int64, p Prefs) *Form {
+	metrics := "*"
+	if len(p.Metrics) > 0 {
+		metrics = strings.Join(p.Metrics, ", ")
+	}
+	quiet := "off"
+	if p.QuietFrom != p.QuietTo {
+		quiet = clock(p.QuietFrom) + "-" + clock(p.QuietTo)
+	}
+	tz := p.Timezone
+	if tz == "" {
+		tz = "UTC"
+	}
+
+	return NewForm().
+		Ask(EnumArg("severity", l.T("prefs.ask_severity"), "info", "warning", "critical").Default(p.MinSeverity.String())).
+		Ask(TextArg("metrics", l.T("prefs.ask_metrics")).Default(metrics)).
+		Ask(TextArg("quiet", l.T("prefs.ask_quiet")).Default(quiet)).
+		Check(func(answers Args) error {
+			if _, _, err := parseQuiet(answers.String("quiet")); err != nil {
+				return errors.New(l.T("prefs.invalid_quiet", "value", answers.String("quiet")))
+			}
+			return nil
+		}).
+		Ask(StringArg("timezone", l.T("prefs.ask_timezone")).Default(tz)).
+		When(func(answers Args) bool {
+			return answers.String("quiet") != "off"
+		}).
+		Check(func(answers Args) error {
+			if _, err := time.LoadLocation(answers.String("timezone")); err != nil {
+				return errors.New(l.T("prefs.invalid_timezone", "value", answers.String("timezone")))
+			}
+			return nil
+		}).
+		Finally(func(b *Bot, update tgbotapi.Update, answers Args) {
+			p.MinSeverity.UnmarshalText([]byte(answers.String("severity")))
+
+			p.Metrics = nil
+			if m := answers.String("metrics"); m != "*" && m != "all" {
+				for _, v := range strings.Split(m, ",") {
+					if v = strings.TrimSpace(v); v != "" {
+						p.Metrics = append(p.Metrics, v)
+					}
+				}
+			}
+
+			p.QuietFrom, p.QuietTo, _ = parseQuiet(answers.String("quiet"))
+			if answers.Has("timezone") {
+				p.Timezone = answers.String("timezone")
+			}
+
+			if !b.SetPrefs(chatID, p) {
+				b.SendMsg(chatID, l.T("prefs.unsubscribed"))
+				return
+			}
+			b.SendMsg(chatID, l.T("saved")+"\n"+p.text(l))
+		})
+}
+
+// CmdPrefs handle /prefs command, it walks the subscriber through their alert preferences.
+func CmdPrefs(b *Bot, update tgbotapi.Update, _ Args) {
+	chatID := update.Message.Chat.ID
+
+	s, ok := b.GetSubscriber(chatID)
+	if !ok {
+		b.Reply(update, "not_subscribed")
+		return
+	}
+
+	l := b.Locale(update)
+	b.SendMsg(chatID, l.T("prefs.show", "prefs", s.Prefs.text(l)))
+	b.Start(update, prefsForm(l, chatID, s.Prefs))
+}
@@ -0,0 +1,98 @@
+package bot
+
+import (
+	"monitor/alert"
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func TestQuiet(t *testing.T) {
+	// utc returns the time of day on a winter or summer day in UTC
+	utc := func(month time.Month, hour, min int) time.Time {
+		return time.Date(2024, month, 1, hour, min, 0, 0, time.UTC)
+	}
+	night := Prefs{QuietFrom: 22 * 60, QuietTo: 7 * 60, Timezone: "Asia/Taipei"}
+	office := Prefs{QuietFrom: 9 * 60, QuietTo: 17 * 60}
+	newYork := Prefs{QuietFrom: 23 * 60, QuietTo: 6 * 60, Timezone: "America/New_York"}
+
+	for _, c := range []struct {
+		name string
+		p    Prefs
+		now  time.Time
+		want bool
+	}{
+		{"before start over midnight", night, utc(1, 13, 59), false}, // 21:59 in Taipei
+		{"start over midnight", night, utc(1, 14, 0), true},          // 22:00
+		{"before midnight", night, utc(1, 15, 59), true},             // 23:59
+		{"after midnight", night, utc(1, 16, 0), true},               // 00:00
+		{"before end over midnight", night, utc(1, 22, 59), true},    // 06:59
+		{"end over midnight", night, utc(1, 23, 0), false},           // 07:00
+		{"same day start", office, utc(1, 9, 0), true},
+		{"same day end", office, utc(1, 17, 0), false},
+		{"same day night", office, utc(1, 2, 0), false},
+		{"standard time", newYork, utc(1, 3, 30), false},       // 22:30 EST
+		{"daylight saving time", newYork, utc(7, 3, 30), true}, // 23:30 EDT
+		{"none", Prefs{QuietFrom: 60, QuietTo: 60}, utc(1, 1, 0), false},
+		{"unknown timezone is UTC", Prefs{QuietFrom: 22 * 60, QuietTo: 7 * 60, Timezone: "Mars/Olympus"}, utc(1, 23, 0), true},
+	} {
+		if got := c.p.Quiet(c.now); got != c.want {
+			t.Errorf("%s: Quiet(%s) = %v, want %v", c.name, c.now.In(c.p.location()).Format("15:04 MST"), got, c.want)
+		}
+	}
+}
+
+func TestAllow(t *testing.T) {
+	quiet := time.Date(2024, 1, 1, 23, 0, 0, 0, time.UTC)
+	day := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	night := Prefs{QuietFrom: 22 * 60, QuietTo: 7 * 60}
+
+	info := alert.Alert{Severity: alert.Info, Rule: "cpu_increase", Metric: "cpu"}
+	warning := alert.Alert{Severity: alert.Warning, Rule: "cpu_threshold", Metric: "cpu", Class: "web"}
+	critical := alert.Alert{Severity: alert.Critical, Rule: "high_disk", Class: "db"}
+
+	for _, c := range []struct {
+		name string
+		p    Prefs
+		a    alert.Alert
+		now  time.Time
+		want bool
+	}{
+		{"zero value", Prefs{}, info, quiet, true},
+		{"below minimum severity", Prefs{MinSeverity: alert.Warning}, info, day, false},
+		{"minimum severity", Prefs{MinSeverity: alert.Warning}, warning, day, true},
+		{"above minimum severity", Prefs{MinSeverity: alert.Warning}, critical, day, true},
+		{"metric", Prefs{Metrics: []string{"cpu"}}, info, day, true},
+		{"rule", Prefs{Metrics: []string{"mem", "high_disk"}}, critical, day, true},
+		{"other metric", Prefs{Metrics: []string{"mem"}}, warning, day, false},
+		{"all metrics", Prefs{Metrics: []string{"*"}}, warning, day, true},
+		{"class", Prefs{Classes: []string{"db"}}, critical, day, true},
+		{"other class", Prefs{Classes: []string{"db"}}, warning, day, false},
+		{"warning in quiet hours", night, warning, quiet, false},
+		{"warning out of quiet hours", night, warning, day, true},
+		{"critical in quiet hours", night, critical, quiet, true},
+		{"critical of another metric in quiet hours", Prefs{Metrics: []string{"cpu"}, QuietFrom: 22 * 60, QuietTo: 7 * 60}, critical, quiet, false},
+	} {
+		if got := c.p.Allow(c.a, c.now); got != c.want {
+			t.Errorf("%s: Allow = %v, want %v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestParseQuiet(t *testing.T) {
+	for s, want := range map[string][2]int{
+		"off":          {0, 0},
+		"22:00-07:00":  {22 * 60, 7 * 60},
+		"9:30 - 17:00": {9*60 + 30, 17 * 60},
+	} {
+		from, to, err := parseQuiet(s)
+		if err != nil || from != want[0] || to != want[1] {
+			t.Errorf("parseQuiet(%q) = %d, %d, %v, want %v", s, from, to, err, want)
+		}
+	}
+	for _, s := range []string{"", "22:00", "22:00-25:00", "night"} {
+		if _, _, err := parseQuiet(s); err == nil {
+			t.Errorf("parseQuiet(%q) should fail", s)
+		}
+	}
+}
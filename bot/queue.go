@@ -0,0 +1,365 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	maxAttempts = 10  // attempts before a queued message is given up
+	maxFailures = 100 // failed deliveries kept
+	sendRetries = 3   // attempts of a synchronous Send
+)
+
+// job is a queued outbound message
+type job struct {
+	ID       int64                          `json:"id"`
+	ChatID   int64                          `json:"chat_id"`
+	Thread   int                            `json:"thread,omitempty"`
+	Text     string                         `json:"text"`
+	Markup   *tgbotapi.InlineKeyboardMarkup `json:"markup,omitempty"`
+	Attempts int                            `json:"attempts"`
+	NextTry  time.Time                      `json:"next_try"`
+}
+
+// params returns the parameters of sendMessage for j, tgbotapi does not know forum topics yet
+func (j *job) params() (tgbotapi.Params, error) {
+	params := tgbotapi.Params{}
+	params.AddNonZero64("chat_id", j.ChatID)
+	params.AddNonZero("message_thread_id", j.Thread)
+	params["text"] = j.Text
+	if j.Markup != nil {
+		if err := params.AddInterface("reply_markup", j.Markup); err != nil {
+			return nil, err
+		}
+	}
+	return params, nil
+}
+
+func (j *job) chattable() tgbotapi.Chattable {
+	msg := tgbotapi.NewMessage(j.ChatID, j.Text)
+	if j.Markup != nil {
+		msg.ReplyMarkup = *j.Markup
+	}
+	return msg
+}
+
+// Failure is a message that could not be delivered.
+type Failure struct {
+	ChatID int64     `json:"chat_id"`
+	Text   string    `json:"text"`
+	Err    string    `json:"error"`
+	Time   time.Time `json:"time"`
+}
+
+// queueState is the saved part of the queue
+type queueState struct {
+	NextID int64     `json:"next_id"`
+	Jobs   []*job    `json:"jobs"`
+	Failed []Failure `json:"failed"`
+}
+
+// queue is the outbound message queue, saved to path after every change if path is not empty
+type queue struct {
+	queueState
+	mu      sync.Mutex
+	path    string
+	wake    chan struct{}
+	stop    chan struct{} // closed to stop the worker once the due messages are sent
+	stopped chan struct{} // closed when the worker stopped
+	once    sync.Once
+}
+
+func newQueue() *queue {
+	return &queue{
+		queueState: queueState{
+			Jobs:   []*job{},
+			Failed: []Failure{},
+		},
+		wake:    make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+}
+
+// load reads the queue saved at path and keeps saving there
+func (q *queue) load(path string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.path = path
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var saved queueState
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	q.NextID = max(q.NextID, saved.NextID)
+	q.Jobs = append(saved.Jobs, q.Jobs...)
+	q.Failed = append(saved.Failed, q.Failed...)
+	q.notify()
+
+	return nil
+}
+
+// save writes the queue to its path, it must be called with q.mu held
+func (q *queue) save() {
+	if q.path == "" {
+		return
+	}
+
+	data, err := json.Marshal(q.queueState)
+	if err != nil {
+		log.Printf("queue: %v\n", err)
+		return
+	}
+
+	// write to a temporary file first so that a crash never leaves a broken queue
+	tmp := q.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		log.Printf("queue: %v\n", err)
+		return
+	}
+	if err := os.Rename(tmp, q.path); err != nil {
+		log.Printf("queue: %v\n", err)
+	}
+}
+
+// notify wakes the worker up
+func (q *queue) notify() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// push queues a message to the topic thread of chatID
+func (q *queue) push(chatID int64, thread int, text string, markup *tgbotapi.InlineKeyboardMarkup) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.NextID++
+	q.Jobs = append(q.Jobs, &job{
+		ID:      q.NextID,
+		ChatID:  chatID,
+		Thread:  thread,
+		Text:    text,
+		Markup:  markup,
+		NextTry: time.Now(),
+	})
+	q.save()
+	q.notify()
+}
+
+// next returns the job to send next and how long to wait for it, job is nil if the queue is empty
+func (q *queue) next() (*job, time.Duration) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.Jobs) == 0 {
+		return nil, 0
+	}
+
+	sort.SliceStable(q.Jobs, func(i, j int) bool {
+		if !q.Jobs[i].NextTry.Equal(q.Jobs[j].NextTry) {
+			return q.Jobs[i].NextTry.Before(q.Jobs[j].NextTry)
+		}
+		return q.Jobs[i].ID < q.Jobs[j].ID
+	})
+
+	j := q.Jobs[0]
+	return j, time.Until(j.NextTry)
+}
+
+// remove removes the job from the queue
+func (q *queue) remove(j *job) {
+	for i, v := range q.Jobs {
+		if v == j {
+			q.Jobs = append(q.Jobs[:i], q.Jobs[i+1:]...)
+			return
+		}
+	}
+}
+
+// fail records a failed delivery, it must be called with q.mu held
+func (q *queue) fail(chatID int64, text string, err error) {
+	q.Failed = append(q.Failed, Failure{
+		ChatID: chatID,
+		Text:   text,
+		Err:    err.Error(),
+		Time:   time.Now(),
+	})
+	if len(q.Failed) > maxFailures {
+		q.Failed = q.Failed[len(q.Failed)-maxFailures:]
+	}
+}
+
+// done updates the queue with the result of sending j, it returns true if the chat of j is gone for good
+func (q *queue) done(j *job, err error) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	defer q.save()
+
+	if err == nil {
+		q.remove(j)
+		return false
+	}
+
+	j.Attempts++
+	kind, retryAfter := classify(err)
+	switch {
+	case kind == errRateLimit:
+		j.NextTry = time.Now().Add(retryAfter)
+		return false
+	case kind == errTemporary && j.Attempts < maxAttempts:
+		j.NextTry = time.Now().Add(backoff(j.Attempts))
+		return false
+	}
+
+	q.remove(j)
+	q.fail(j.ChatID, j.Text, err)
+
+	if kind != errBlocked {
+		return false
+	}
+
+	// the other messages to the chat would fail as well
+	jobs := q.Jobs[:0]
+	for _, v := range q.Jobs {
+		if v.ChatID != j.ChatID {
+			jobs = append(jobs, v)
+		}
+	}
+	q.Jobs = jobs
+
+	return true
+}
+
+// runQueue sends the queued messages until Shutdown, it sends the messages that are due before stopping
+func (b *Bot) runQueue() {
+	defer close(b.queue.stopped)
+
+	for {
+		j, wait := b.queue.next()
+		if j == nil || wait > 0 {
+			var retry <-chan time.Time
+			if j != nil {
+				retry = time.After(wait)
+			}
+			select {
+			case <-retry:
+			case <-b.queue.wake:
+			case <-b.queue.stop:
+				// messages waiting for a retry stay in the saved queue
+				return
+			}
+			continue
+		}
+
+		time.Sleep(b.limiter.reserve(j.ChatID))
+		err := b.sendJob(j)
+		if err != nil {
+			log.Printf("send to %d: %v\n", j.ChatID, err)
+		}
+
+		if b.queue.done(j, err) {
+			b.dropChat(j.ChatID, err)
+		}
+	}
+}
+
+// sendJob sends j, to its forum topic if it has one
+func (b *Bot) sendJob(j *job) error {
+	if j.Thread == 0 {
+		_, err := b.Bot.Send(j.chattable())
+		return err
+	}
+
+	params, err := j.params()
+	if err != nil {
+		return err
+	}
+	_, err = b.Bot.MakeRequest("sendMessage", params)
+	return err
+}
+
+// dropChat unsubscribes a chat the bot can no longer send messages to
+func (b *Bot) dropChat(chatID int64, err error) {
+	log.Printf("chat %d is unreachable, unsubscribing: %v\n", chatID, err)
+
+	b.Unsubscribe(chatID)
+
+	b.mu.Lock()
+	if _, ok := b.secondary[chatID]; ok {
+		delete(b.secondary, chatID)
+		b.saveState()
+	}
+	b.mu.Unlock()
+}
+
+// Shutdown sends the pending digests and stops the outbound queue after sending the messages that are due, or when ctx is done. The unsent messages are kept for the next start if PersistQueue was called.
+func (b *Bot) Shutdown(ctx context.Context) error {
+	b.queue.once.Do(func() {
+		b.sendDigests(true)
+		close(b.queue.stop)
+	})
+
+	select {
+	case <-b.queue.stopped:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	b.queue.mu.Lock()
+	defer b.queue.mu.Unlock()
+	b.queue.save()
+	return nil
+}
+
+// PersistQueue loads the outbound queue saved at path and saves it there from now on, so queued messages survive a restart.
+func (b *Bot) PersistQueue(path string) error {
+	return b.queue.load(path)
+}
+
+// Failures returns the recent deliveries that failed permanently.
+func (b *Bot) Failures() []Failure {
+	b.queue.mu.Lock()
+	defer b.queue.mu.Unlock()
+
+	return append([]Failure{}, b.queue.Failed...)
+}
+
+// CmdFailures handle /failures command, it lists the recent deliveries that failed permanently.
+func CmdFailures(b *Bot, update tgbotapi.Update, _ Args) {
+	failures := b.Failures()
+	if len(failures) == 0 {
+		b.Reply(update, "failures.none")
+		return
+	}
+
+	var sb strings.Builder
+	sb.WriteString(b.T(update, "failures.header") + "\n")
+	for _, f := range failures {
+		sb.WriteString(fmt.Sprintf("%s chat %d: %s\n", f.Time.Format("2006-01-02 15:04"), f.ChatID, f.Err))
+	}
+
+	b.SendMsg(update.Message.Chat.ID, sb.String())
+}
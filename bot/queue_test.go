@@ -0,0 +1,175 @@
+package bot
+
+import (
+	"context"
+	"monitor/bot/bottest"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestShutdown(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "outbox.json")
+
+	b, _ := New(nil, nil)
+	if err := b.PersistQueue(path); err != nil {
+		t.Fatal(err)
+	}
+
+	// a message waiting for a retry is not sent on shutdown but kept for the next start
+	b.queue.mu.Lock()
+	b.queue.Jobs = append(b.queue.Jobs, &job{ID: 1, ChatID: 1, Text: "hello", NextTry: time.Now().Add(time.Hour)})
+	b.queue.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := b.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Shutdown(ctx); err != nil {
+		t.Errorf("second Shutdown: %v", err)
+	}
+
+	q := newQueue()
+	if err := q.load(path); err != nil {
+		t.Fatal(err)
+	}
+	if len(q.Jobs) != 1 || q.Jobs[0].Text != "hello" {
+		t.Errorf("saved jobs %+v, want the unsent message", q.Jobs)
+	}
+}
+
+func TestQueueDone(t *testing.T) {
+	q := newQueue()
+	q.push(10, 0, "a", nil)
+	q.push(10, 0, "b", nil)
+	q.push(11, 0, "c", nil)
+
+	// server errors are retried with a backoff until maxAttempts
+	j, _ := q.next()
+	if q.done(j, &tgbotapi.Error{Code: 502, Message: "Bad Gateway"}) {
+		t.Fatal("a server error should not drop the chat")
+	}
+	if j.Attempts != 1 || time.Until(j.NextTry) < backoff(1)-time.Second || len(q.Jobs) != 3 {
+		t.Errorf("attempts %d, next try in %s, %d jobs, want a retry in %s", j.Attempts, time.Until(j.NextTry), len(q.Jobs), backoff(1))
+	}
+	j.Attempts = maxAttempts - 1
+	q.done(j, &tgbotapi.Error{Code: 502, Message: "Bad Gateway"})
+	if len(q.Jobs) != 2 || len(q.Failed) != 1 {
+		t.Fatalf("%d jobs and %d failures after %d attempts, want the job given up", len(q.Jobs), len(q.Failed), maxAttempts)
+	}
+
+	// 429 waits as long as asked, however many attempts were made
+	j, _ = q.next()
+	j.Attempts = maxAttempts
+	q.done(j, &tgbotapi.Error{Code: 429, ResponseParameters: tgbotapi.ResponseParameters{RetryAfter: 30}})
+	if wait := time.Until(j.NextTry); len(q.Jobs) != 2 || wait < 29*time.Second || wait > 30*time.Second {
+		t.Errorf("%d jobs, next try in %s, want a retry in 30s", len(q.Jobs), wait)
+	}
+
+	// a blocked chat drops the other messages to it
+	if !q.done(j, &tgbotapi.Error{Code: 403, Message: "Forbidden: bot was blocked by the user"}) {
+		t.Fatal("403 should drop the chat")
+	}
+	if len(q.Jobs) != 1 || q.Jobs[0].ChatID != 11 || len(q.Failed) != 2 {
+		t.Errorf("jobs %+v, %d failures, want the message to 11 only", q.Jobs, len(q.Failed))
+	}
+
+	// a wrong request is given up at once
+	j, _ = q.next()
+	if q.done(j, &tgbotapi.Error{Code: 400, Message: "Bad Request: message is too long"}) || len(q.Jobs) != 0 || len(q.Failed) != 3 {
+		t.Errorf("%d jobs and %d failures after a 400, want the job given up", len(q.Jobs), len(q.Failed))
+	}
+}
+
+func TestE2EQueue(t *testing.T) {
+	b, srv := startBot(t)
+	for _, id := range []int64{10, 11, 12, 13} {
+		b.Subscribe(id)
+	}
+	b.mu.Lock()
+	b.secondary[13] = struct{}{}
+	b.mu.Unlock()
+
+	srv.Fail(11, 1, http.StatusBadGateway, 0)
+	srv.Fail(12, 1, http.StatusTooManyRequests, 1)
+	srv.Fail(13, 1, http.StatusForbidden, 0)
+
+	start := time.Now()
+	b.Boradcast("hello")
+	srv.WaitText(10, "hello")
+	srv.WaitText(12, "hello")
+	if d := time.Since(start); d < time.Second {
+		t.Errorf("429 retried after %s, want after retry_after", d)
+	}
+	srv.WaitText(11, "hello")
+	if d := time.Since(start); d < backoff(1) {
+		t.Errorf("502 retried after %s, want after %s", d, backoff(1))
+	}
+
+	attempts := map[int64]int{}
+	for _, r := range srv.Requests() {
+		if r.Method == "sendMessage" {
+			attempts[r.ChatID()]++
+		}
+	}
+	if attempts[10] != 1 || attempts[11] != 2 || attempts[12] != 2 || attempts[13] != 1 {
+		t.Errorf("attempts per chat %v, want 1, 2, 2 and 1", attempts)
+	}
+
+	// the blocked chat is unsubscribed and its failure recorded
+	if _, ok := b.GetSubscriber(13); ok {
+		t.Error("blocked chat still subscribed")
+	}
+	if ids := b.secondaryIDs(); len(ids) != 0 {
+		t.Errorf("secondary %v, want the blocked chat removed", ids)
+	}
+	failures := b.Failures()
+	if len(failures) != 1 || failures[0].ChatID != 13 || !strings.Contains(failures[0].Err, "blocked") {
+		t.Errorf("failures %+v, want the blocked chat", failures)
+	}
+}
+
+func TestQueueRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "outbox.json")
+	srv := bottest.NewServer(t)
+
+	b, err := New(srv.API())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := b.PersistQueue(path); err != nil {
+		t.Fatal(err)
+	}
+
+	srv.Fail(11, 1, http.StatusForbidden, 0)
+	b.SendMsg(11, "blocked")
+	b.queue.mu.Lock()
+	b.queue.Jobs = append(b.queue.Jobs, &job{ID: 1, ChatID: 10, Text: "hello", NextTry: time.Now().Add(500 * time.Millisecond)})
+	b.queue.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := b.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// the next start sends the saved message once due and remembers the failure
+	b, err = New(srv.API())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := b.PersistQueue(path); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { b.Shutdown(context.Background()) })
+
+	srv.WaitText(10, "hello")
+	if failures := b.Failures(); len(failures) != 1 || failures[0].ChatID != 11 {
+		t.Errorf("failures %+v, want the blocked chat", failures)
+	}
+}
@@ -0,0 +1,264 @@
+package bot
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Role is what a Telegram user is allowed to do, every role can do what the roles below it can.
+type Role int
+
+const (
+	Guest    Role = iota // anyone, may only see the help
+	Viewer               // may subscribe and look at the server
+	Operator             // may also acknowledge and mute alerts and change rules
+	Admin                // may also change the config, run debug commands and grant roles
+)
+
+var roleNames = map[Role]string{
+	Guest:    "guest",
+	Viewer:   "viewer",
+	Operator: "operator",
+	Admin:    "admin",
+}
+
+func (r Role) String() string {
+	return roleNames[r]
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (r Role) MarshalText() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (r *Role) UnmarshalText(text []byte) error {
+	for k, v := range roleNames {
+		if v == string(text) {
+			*r = k
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown role %q", text)
+}
+
+// auditValue returns the role as recorded in the audit log, empty for Guest
+func (r Role) auditValue() string {
+	if r == Guest {
+		return ""
+	}
+	return r.String()
+}
+
+// Role returns the role of the user with the given Telegram user ID.
+func (b *Bot) Role(userID int64) Role {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.roles[userID]
+}
+
+// Roles returns the role of every user who has one.
+func (b *Bot) Roles() map[int64]Role {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	res := map[int64]Role{}
+	for k, v := range b.roles {
+		res[k] = v
+	}
+	return res
+}
+
+// Grant gives a user a role, Guest revokes the role of the user.
+func (b *Bot) Grant(userID int64, role Role) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if role == Guest {
+		delete(b.roles, userID)
+	} else {
+		b.roles[userID] = role
+	}
+	b.saveRoles()
+	b.scheduleMenu()
+}
+
+// roleOf returns the role of the user who sent the update
+func (b *Bot) roleOf(update tgbotapi.Update) Role {
+	u := update.SentFrom()
+	if u == nil {
+		return Guest
+	}
+	return b.Role(u.ID)
+}
+
+// denied tells the user who sent update that they need role
+func (b *Bot) denied(role Role, have Role, update tgbotapi.Update) string {
+	id := int64(0)
+	if u := update.SentFrom(); u != nil {
+		id = u.ID
+	}
+	return b.T(update, "denied", "role", role.String(), "have", have.String(), "id", id)
+}
+
+// PersistRoles loads the roles saved at path and saves them there after every change.
+func (b *Bot) PersistRoles(path string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.rolesPath = path
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		b.saveRoles()
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	saved := map[int64]Role{}
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	for k, v := range saved {
+		if _, ok := b.roles[k]; !ok {
+			b.roles[k] = v
+		}
+	}
+	b.saveRoles()
+
+	return nil
+}
+
+// saveRoles writes the roles to their path, it must be called with b.mu held
+func (b *Bot) saveRoles() {
+	if b.rolesPath == "" {
+		return
+	}
+
+	data, err := json.MarshalIndent(b.roles, "", "  ")
+	if err != nil {
+		log.Printf("roles: %v\n", err)
+		return
+	}
+
+	tmp := b.rolesPath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		log.Printf("roles: %v\n", err)
+		return
+	}
+	if err := os.Rename(tmp, b.rolesPath); err != nil {
+		log.Printf("roles: %v\n", err)
+	}
+}
+
+// ParseAdmins parses a comma separated list of Telegram user IDs, e.g. the BOT_ADMINS environment variable.
+func ParseAdmins(s string) ([]int64, error) {
+	ids := []int64{}
+	for _, v := range strings.Split(s, ",") {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid user ID %q", v)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// targetUser returns the user ID a /grant or /revoke command is about, either the "user" argument or the sender of the message it replies to. ok is false if there is neither.
+func targetUser(update tgbotapi.Update, args Args) (id int64, ok bool) {
+	if args.Has("user") {
+		return int64(args.Int("user")), true
+	}
+	if r := update.Message.ReplyToMessage; r != nil && r.From != nil {
+		return r.From.ID, true
+	}
+	return 0, false
+}
+
+// CmdGrant handle /grant command, it gives the user of the "user" argument, or of the message replied to, the role of the "role" argument.
+func CmdGrant(b *Bot, update tgbotapi.Update, args Args) {
+	id, ok := targetUser(update, args)
+	if !ok {
+		b.Reply(update, "role.grant_usage")
+		return
+	}
+
+	var role Role
+	if err := role.UnmarshalText([]byte(args.String("role"))); err != nil {
+		b.Reply(update, "role.grant_usage")
+		return
+	}
+
+	prev := b.Role(id)
+	b.Grant(id, role)
+	b.Audit(update, "role", strconv.FormatInt(id, 10), prev.auditValue(), role.auditValue())
+	b.Reply(update, "role.granted", "id", id, "role", role.String(), "prev", prev.String())
+}
+
+// CmdRevoke handle /revoke command, it removes the role of the user of the "user" argument, or of the message replied to.
+func CmdRevoke(b *Bot, update tgbotapi.Update, args Args) {
+	id, ok := targetUser(update, args)
+	if !ok {
+		b.Reply(update, "role.revoke_usage")
+		return
+	}
+
+	if u := update.SentFrom(); u != nil && u.ID == id {
+		b.Reply(update, "role.revoke_self")
+		return
+	}
+
+	prev := b.Role(id)
+	if prev == Guest {
+		b.Reply(update, "role.no_role", "id", id)
+		return
+	}
+
+	b.Grant(id, Guest)
+	b.Audit(update, "role", strconv.FormatInt(id, 10), prev.auditValue(), "")
+	b.Reply(update, "role.revoked", "id", id, "role", prev.String())
+}
+
+// CmdRoles handle /roles command, it lists the users with a role.
+func CmdRoles(b *Bot, update tgbotapi.Update, _ Args) {
+	roles := b.Roles()
+	if len(roles) == 0 {
+		b.Reply(update, "role.empty")
+		return
+	}
+
+	ids := make([]int64, 0, len(roles))
+	for id := range roles {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if roles[ids[i]] != roles[ids[j]] {
+			return roles[ids[i]] > roles[ids[j]]
+		}
+		return ids[i] < ids[j]
+	})
+
+	l := b.Locale(update)
+	var sb strings.Builder
+	sb.WriteString(l.T("role.header") + "\n")
+	for _, id := range ids {
+		sb.WriteString(fmt.Sprintf("%d: %s\n", id, l.T("role."+roles[id].String())))
+	}
+
+	b.SendMsg(update.Message.Chat.ID, sb.String())
+}
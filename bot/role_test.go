@@ -0,0 +1,41 @@
+package bot
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestRoles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "roles.json")
+
+	b, _ := New(nil, nil)
+	if err := b.PersistRoles(path); err != nil {
+		t.Fatal(err)
+	}
+	b.Grant(1, Admin)
+	b.Grant(2, Operator)
+	b.Grant(3, Viewer)
+	b.Grant(3, Guest)
+
+	b, _ = New(nil, nil)
+	b.Grant(2, Viewer) // granted before loading, e.g. from BOT_ADMINS, wins
+	if err := b.PersistRoles(path); err != nil {
+		t.Fatal(err)
+	}
+
+	for id, want := range map[int64]Role{1: Admin, 2: Viewer, 3: Guest, 4: Guest} {
+		if got := b.Role(id); got != want {
+			t.Errorf("Role(%d) = %s, want %s", id, got, want)
+		}
+	}
+}
+
+func TestParseAdmins(t *testing.T) {
+	ids, err := ParseAdmins(" 1, 22 ,,333")
+	if err != nil || len(ids) != 3 || ids[0] != 1 || ids[1] != 22 || ids[2] != 333 {
+		t.Errorf("ParseAdmins = %v, %v", ids, err)
+	}
+	if _, err := ParseAdmins("1,abc"); err == nil {
+		t.Error("ParseAdmins should reject non numeric IDs")
+	}
+}
@@ -0,0 +1,228 @@
+package bot
+
+import (
+	"fmt"
+	"monitor/alert"
+	"monitor/i18n"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// maxSummary is the maximum number of suppressed alerts listed when a silence ends
+const maxSummary = 10
+
+// Silence suppresses the alerts of a rule or metric until it expires.
+type Silence struct {
+	ID         int
+	Matcher    string // rule or metric name, "*" for all alerts
+	Reason     string
+	CreatedBy  string
+	CreatedAt  time.Time
+	Until      time.Time
+	Suppressed []alert.Alert
+	timer      *time.Timer
+}
+
+// Mute creates a silence for the alerts matching matcher that lasts d.
+func (b *Bot) Mute(matcher string, d time.Duration, reason string, createdBy string) *Silence {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.silenceID++
+	s := &Silence{
+		ID:        b.silenceID,
+		Matcher:   matcher,
+		Reason:    reason,
+		CreatedBy: createdBy,
+		CreatedAt: time.Now(),
+		Until:     time.Now().Add(d),
+	}
+
+	id := s.ID
+	s.timer = time.AfterFunc(d, func() {
+		b.Unmute(id)
+	})
+
+	b.silences[s.ID] = s
+	b.saveState()
+	return s
+}
+
+// Unmute ends the silence with the given ID and broadcasts the summary of the alerts it suppressed. It returns false if there is no such silence.
+func (b *Bot) Unmute(id int) bool {
+	b.mu.Lock()
+	s, ok := b.silences[id]
+	delete(b.silences, id)
+	if ok {
+		b.saveState()
+	}
+	b.mu.Unlock()
+
+	if !ok {
+		return false
+	}
+
+	s.timer.Stop()
+	b.broadcastf(s.summary)
+	return true
+}
+
+// Silences returns a copy of all active silences sorted by ID.
+func (b *Bot) Silences() []Silence {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	res := make([]Silence, 0, len(b.silences))
+	for _, s := range b.silences {
+		c := *s
+		c.Suppressed = append([]alert.Alert{}, s.Suppressed...)
+		res = append(res, c)
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].ID < res[j].ID
+	})
+
+	return res
+}
+
+// silence records a in the first silence matching it, it returns false if no silence matches
+func (b *Bot) silence(a alert.Alert) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, s := range b.silences {
+		if a.Matches(s.Matcher) {
+			s.Suppressed = append(s.Suppressed, a)
+			b.saveState()
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchSilence checks if a silence matches a without recording it
+func (b *Bot) matchSilence(a alert.Alert) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, s := range b.silences {
+		if a.Matches(s.Matcher) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// text returns the silence in the language of l
+func (s Silence) text(l *i18n.Locale) string {
+	return l.T("silence", "id", s.ID, "matcher", s.Matcher, "until", s.Until, "by", s.CreatedBy, "reason", s.Reason)
+}
+
+func (s Silence) String() string {
+	res := fmt.Sprintf("#%d %s until %s by %s", s.ID, s.Matcher, s.Until.Format("2006-01-02 15:04"), s.CreatedBy)
+	if s.Reason != "" {
+		res += ": " + s.Reason
+	}
+	return res
+}
+
+// auditValue returns the silence as recorded in the audit log
+func (s Silence) auditValue() string {
+	return fmt.Sprintf("%s until %s", s.Matcher, s.Until.Format("2006-01-02 15:04"))
+}
+
+// summary returns the message sent when the silence ends in the language of l
+func (s *Silence) summary(l *i18n.Locale) string {
+	var sb strings.Builder
+
+	sb.WriteString(l.T("silence.ended", "silence", s.text(l)) + "\n")
+	if len(s.Suppressed) == 0 {
+		sb.WriteString(l.T("silence.none_suppressed"))
+		return sb.String()
+	}
+
+	sb.WriteString(l.T("silence.suppressed", "n", len(s.Suppressed)) + "\n")
+	for i, a := range s.Suppressed {
+		if i == maxSummary {
+			sb.WriteString(l.T("more", "n", len(s.Suppressed)-maxSummary) + "\n")
+			break
+		}
+		sb.WriteString(fmt.Sprintf("%s %s\n", a.Time.Format(l.Clock), AlertText(l, a)))
+	}
+
+	return sb.String()
+}
+
+// UserName returns a readable name of the sender of the message in update.
+func UserName(update tgbotapi.Update) string {
+	u := update.SentFrom()
+	switch {
+	case u == nil:
+		return "unknown"
+	case u.UserName != "":
+		return "@" + u.UserName
+	default:
+		return fmt.Sprintf("%s (%d)", u.FirstName, u.ID)
+	}
+}
+
+// CmdMute handle /mute command, it expects the "target", "duration" and "reason" arguments.
+func CmdMute(b *Bot, update tgbotapi.Update, args Args) {
+
+	d := args.Duration("duration")
+	if d <= 0 {
+		b.Reply(update, "silence.invalid_duration", "d", d)
+		return
+	}
+
+	s := b.Mute(args.String("target"), d, args.String("reason"), UserName(update))
+	b.Audit(update, "mute", strconv.Itoa(s.ID), "", s.auditValue())
+	b.Reply(update, "silence.muted", "silence", s.text(b.Locale(update)))
+}
+
+// CmdUnmute handle /unmute command, it ends silences by ID or by rule or metric given as the "silence" argument.
+func CmdUnmute(b *Bot, update tgbotapi.Update, args Args) {
+	arg := args.String("silence")
+
+	l := b.Locale(update)
+	ended := []string{}
+	for _, s := range b.Silences() {
+		if strconv.Itoa(s.ID) == strings.TrimPrefix(arg, "#") || s.Matcher == arg {
+			if b.Unmute(s.ID) {
+				b.Audit(update, "unmute", strconv.Itoa(s.ID), s.auditValue(), "")
+				ended = append(ended, s.text(l))
+			}
+		}
+	}
+
+	if len(ended) == 0 {
+		b.Reply(update, "silence.no_match", "arg", arg)
+		return
+	}
+	b.Reply(update, "silence.unmuted", "n", len(ended), "silences", ended)
+}
+
+// CmdSilences handle /silences command, it lists active silences.
+func CmdSilences(b *Bot, update tgbotapi.Update, _ Args) {
+	silences := b.Silences()
+	if len(silences) == 0 {
+		b.Reply(update, "silence.none")
+		return
+	}
+
+	l := b.Locale(update)
+	var sb strings.Builder
+	sb.WriteString(l.T("silence.header") + "\n")
+	for _, s := range silences {
+		sb.WriteString(l.T("silence.item", "silence", s.text(l), "n", len(s.Suppressed)) + "\n")
+	}
+
+	b.SendMsg(update.Message.Chat.ID, sb.String())
+}
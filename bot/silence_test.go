@@ -0,0 +1,71 @@
+package bot
+
+import (
+	"monitor/alert"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSilenceMatch(t *testing.T) {
+	b, _ := New(nil, nil)
+	b.Mute("cpu_threshold", time.Hour, "", "alice")
+
+	fire := alert.Alert{State: alert.Firing, Rule: "cpu_threshold", Metric: "cpu"}
+	if !b.matchSilence(fire) || b.matchSilence(alert.Alert{Rule: "mem_threshold", Metric: "mem"}) {
+		t.Fatal("a silence should match the alerts of its rule only")
+	}
+	if s := b.Silences()[0]; len(s.Suppressed) != 0 {
+		t.Error("matchSilence should not record the alert")
+	}
+	if !b.silence(fire) || len(b.Silences()[0].Suppressed) != 1 {
+		t.Error("silence should record the alert")
+	}
+
+	// by metric and for all alerts
+	b.Mute("mem", time.Hour, "", "alice")
+	b.Mute("*", time.Hour, "", "alice")
+	for _, a := range []alert.Alert{{Rule: "mem_threshold", Metric: "mem"}, {Rule: "disk"}} {
+		if !b.matchSilence(a) {
+			t.Errorf("no silence matches %+v", a)
+		}
+	}
+
+	if !b.Unmute(1) || b.Unmute(1) || len(b.Silences()) != 2 {
+		t.Error("a silence should end once")
+	}
+}
+
+func TestE2ESilence(t *testing.T) {
+	b, srv := startBot(t)
+	b.Grant(10, Operator)
+	b.Subscribe(10)
+	b.AddCmd("unmute", "", false, CmdUnmute).Args(StringArg("silence", ""))
+
+	// suppressed alerts are summed up when the silence expires
+	b.Mute("cpu", 300*time.Millisecond, "deploy", "alice")
+	b.BroadcastAlerts(alert.Alert{State: alert.Firing, Severity: alert.Warning, Rule: "cpu", Text: "cpu is high", Time: time.Now()})
+	b.BroadcastAlerts(alert.Alert{State: alert.Firing, Severity: alert.Warning, Rule: "mem", Text: "mem is high", Time: time.Now()})
+	srv.WaitText(10, "mem is high")
+
+	summary := srv.WaitText(10, "Silence ended: #1 cpu").Text()
+	if !strings.Contains(summary, "deploy") || !strings.Contains(summary, "1 alert was suppressed:") || !strings.Contains(summary, "cpu is high") {
+		t.Errorf("summary %q, want the reason and the suppressed alert", summary)
+	}
+	if len(b.Silences()) != 0 {
+		t.Error("the silence should be gone once expired")
+	}
+	if countText(srv, 10, "cpu is high") != 1 {
+		t.Error("the muted alert was sent")
+	}
+
+	// /unmute tells what it ended
+	b.Mute("disk", time.Hour, "", "alice")
+	b.Mute("disk", time.Hour, "", "alice")
+	srv.Message(10, "/unmute disk")
+	srv.WaitText(10, "Ended 2 silences:\n#2 disk")
+	srv.WaitText(10, "Silence ended: #3 disk")
+
+	srv.Message(10, "/unmute disk")
+	srv.WaitText(10, "No silence matches disk")
+}
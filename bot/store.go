@@ -0,0 +1,226 @@
+package bot
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Store keeps the subscribers and the state of the bot across restarts. Implementations must be safe for concurrent use.
+type Store interface {
+	// Load returns all saved subscribers by chat ID.
+	Load() (map[int64]Subscriber, error)
+	// Save saves the subscriber of chatID, replacing the saved one.
+	Save(chatID int64, s Subscriber) error
+	// Delete removes the subscriber of chatID.
+	Delete(chatID int64) error
+	// LoadState returns the saved state, the zero State if none was saved.
+	LoadState() (State, error)
+	// SaveState saves st, replacing the saved state.
+	SaveState(st State) error
+}
+
+// State is what the bot keeps besides the subscribers.
+type State struct {
+	Secondary []int64   `json:"secondary"`  // chats escalated alerts go to, see CmdSecondary
+	Silences  []Silence `json:"silences"`   // active silences
+	SilenceID int       `json:"silence_id"` // ID of the last silence
+}
+
+// JSONStore is a Store saving all subscribers in one JSON file, and the state in another next to it.
+type JSONStore struct {
+	mu          sync.Mutex
+	path        string
+	statePath   string
+	subscribers map[string]Subscriber // JSON object keys are strings
+}
+
+// NewJSONStore creates a JSONStore saving the subscribers to path and the state to path with a "-state" suffix, e.g. subscribers-state.json. The files are created on the first save.
+func NewJSONStore(path string) *JSONStore {
+	return &JSONStore{
+		path:        path,
+		statePath:   strings.TrimSuffix(path, ".json") + "-state.json",
+		subscribers: map[string]Subscriber{},
+	}
+}
+
+// Load implements Store.
+func (s *JSONStore) Load() (map[int64]Subscriber, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return map[int64]Subscriber{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	saved := map[string]Subscriber{}
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return nil, fmt.Errorf("%s: %w", s.path, err)
+	}
+
+	res := map[int64]Subscriber{}
+	for k, v := range saved {
+		chatID, err := strconv.ParseInt(k, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid chat ID %q", s.path, k)
+		}
+		if v.Value == nil {
+			v.Value = map[string]interface{}{}
+		}
+		res[chatID] = v
+	}
+	s.subscribers = saved
+
+	return res, nil
+}
+
+// Save implements Store.
+func (s *JSONStore) Save(chatID int64, sub Subscriber) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.subscribers[strconv.FormatInt(chatID, 10)] = sub
+	return s.write()
+}
+
+// Delete implements Store.
+func (s *JSONStore) Delete(chatID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.subscribers, strconv.FormatInt(chatID, 10))
+	return s.write()
+}
+
+// LoadState implements Store.
+func (s *JSONStore) LoadState() (State, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var st State
+	data, err := os.ReadFile(s.statePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return st, nil
+	}
+	if err != nil {
+		return st, err
+	}
+	if err := json.Unmarshal(data, &st); err != nil {
+		return st, fmt.Errorf("%s: %w", s.statePath, err)
+	}
+	return st, nil
+}
+
+// SaveState implements Store.
+func (s *JSONStore) SaveState(st State) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return writeJSON(s.statePath, st)
+}
+
+// write writes all subscribers to the file, it must be called with s.mu held
+func (s *JSONStore) write() error {
+	return writeJSON(s.path, s.subscribers)
+}
+
+// writeJSON writes v to path as indented JSON
+func writeJSON(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// write to a temporary file first so that a crash never leaves a broken file
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// UseStore loads the subscribers and the state saved in store and saves every change to them there from now on. Silences that ended while the bot was stopped end right away.
+func (b *Bot) UseStore(store Store) error {
+	subscribers, err := store.Load()
+	if err != nil {
+		return err
+	}
+	st, err := store.LoadState()
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.store = store
+	for chatID, s := range subscribers {
+		b.subscribers[chatID] = s
+	}
+	for _, id := range st.Secondary {
+		b.secondary[id] = struct{}{}
+	}
+	for _, s := range st.Silences {
+		s := s
+		if _, ok := b.silences[s.ID]; ok {
+			continue
+		}
+		id := s.ID
+		s.timer = time.AfterFunc(max(time.Until(s.Until), 0), func() {
+			b.Unmute(id)
+		})
+		b.silences[id] = &s
+	}
+	b.silenceID = max(b.silenceID, st.SilenceID)
+
+	return nil
+}
+
+// saveState saves the secondary chats and the silences to the store, it must be called with b.mu held
+func (b *Bot) saveState() {
+	if b.store == nil {
+		return
+	}
+
+	st := State{Secondary: []int64{}, Silences: []Silence{}, SilenceID: b.silenceID}
+	for id := range b.secondary {
+		st.Secondary = append(st.Secondary, id)
+	}
+	sort.Slice(st.Secondary, func(i, j int) bool { return st.Secondary[i] < st.Secondary[j] })
+	for _, s := range b.silences {
+		st.Silences = append(st.Silences, *s)
+	}
+	sort.Slice(st.Silences, func(i, j int) bool { return st.Silences[i].ID < st.Silences[j].ID })
+
+	if err := b.store.SaveState(st); err != nil {
+		log.Printf("store: %v\n", err)
+	}
+}
+
+// persist saves the subscriber of chatID to the store, or deletes it if it is not subscribed. It must be called with b.mu held.
+func (b *Bot) persist(chatID int64) {
+	if b.store == nil {
+		return
+	}
+
+	var err error
+	if s, ok := b.subscribers[chatID]; ok {
+		err = b.store.Save(chatID, s)
+	} else {
+		err = b.store.Delete(chatID)
+	}
+	if err != nil {
+		log.Printf("store: %v\n", err)
+	}
+}
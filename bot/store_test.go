@@ -0,0 +1,84 @@
+package bot
+
+import (
+	"monitor/alert"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestJSONStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "subscribers.json")
+	s := NewJSONStore(path)
+
+	if subs, err := s.Load(); err != nil || len(subs) != 0 {
+		t.Fatalf("Load of a missing file = %v, %v", subs, err)
+	}
+
+	var wg sync.WaitGroup
+	for i := int64(1); i <= 10; i++ {
+		wg.Add(1)
+		go func(chatID int64) {
+			defer wg.Done()
+			s.Save(chatID, Subscriber{Value: map[string]interface{}{}})
+		}(i)
+	}
+	wg.Wait()
+
+	s.Save(-42, Subscriber{
+		Value:  map[string]interface{}{"name": "ops"},
+		Digest: time.Hour,
+		Prefs:  Prefs{MinSeverity: alert.Critical, Metrics: []string{"cpu"}, QuietFrom: 22 * 60, QuietTo: 7 * 60, Timezone: "Asia/Taipei"},
+	})
+	s.Delete(5)
+
+	subs, err := NewJSONStore(path).Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(subs) != 10 {
+		t.Errorf("got %d subscribers, want 10", len(subs))
+	}
+	if _, ok := subs[5]; ok {
+		t.Error("deleted subscriber was loaded")
+	}
+
+	got := subs[-42]
+	if got.Value["name"] != "ops" || got.Digest != time.Hour || got.Prefs.MinSeverity != alert.Critical || got.Prefs.Timezone != "Asia/Taipei" {
+		t.Errorf("got %+v", got)
+	}
+}
+
+func TestStoreState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "subscribers.json")
+
+	b, _ := New(nil, nil)
+	if err := b.UseStore(NewJSONStore(path)); err != nil {
+		t.Fatal(err)
+	}
+	b.Mute("cpu", time.Hour, "maintenance", "@ops")
+	b.Mute("mem", time.Millisecond, "", "@ops")
+	b.silence(alert.Alert{Rule: "cpu", Text: "cpu is high"})
+	b.mu.Lock()
+	b.secondary[-100] = struct{}{}
+	b.saveState()
+	b.mu.Unlock()
+
+	// a restart keeps the secondary chats and the silences that did not end
+	time.Sleep(50 * time.Millisecond)
+	b, _ = New(nil, nil)
+	if err := b.UseStore(NewJSONStore(path)); err != nil {
+		t.Fatal(err)
+	}
+	if ids := b.secondaryIDs(); len(ids) != 1 || ids[0] != -100 {
+		t.Errorf("secondary chats %v, want [-100]", ids)
+	}
+	silences := b.Silences()
+	if len(silences) != 1 || silences[0].ID != 1 || silences[0].Matcher != "cpu" || len(silences[0].Suppressed) != 1 {
+		t.Fatalf("silences %+v, want the one on cpu", silences)
+	}
+	if s := b.Mute("disk", time.Hour, "", "@ops"); s.ID != 3 {
+		t.Errorf("new silence #%d, want #3", s.ID)
+	}
+}
@@ -2,28 +2,46 @@ package bot
 
 // Subscribe adds a user to the list of subscribers.
 func (b *Bot) Subscribe(chatID int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
 	b.subscribers[chatID] = Subscriber{
-		Value: map[string]interface{}{},
+		Value:        map[string]interface{}{},
+		LanguageCode: b.languages[chatID],
 	}
+	b.persist(chatID)
 }
 
 // UnSubscribe removes a user from the list of subscribers.
 func (b *Bot) Unsubscribe(chatID int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
 	delete(b.subscribers, chatID)
+	b.persist(chatID)
 }
 
 // IsSubscribed checks if a user is subscribed.
 func (b *Bot) IsSubscribed(chatID int64) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
 	_, ok := b.subscribers[chatID]
 	return ok
 }
 
 // N returns the number of subscribers.
 func (b *Bot) N() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
 	return len(b.subscribers)
 }
 
 func (b *Bot) GetSubscriber(chatID int64) (Subscriber, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
 	s, ok := b.subscribers[chatID]
 	return s, ok
 }
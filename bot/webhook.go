@@ -0,0 +1,203 @@
+package bot
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// secretHeader is the header Telegram sends the secret token of the webhook in
+const secretHeader = "X-Telegram-Bot-Api-Secret-Token"
+
+// Webhook configures receiving updates by webhook instead of long polling.
+type Webhook struct {
+	URL      string // public URL Telegram posts the updates to, e.g. https://example.com/telegram
+	Listen   string // address of the embedded server, e.g. :8080
+	Secret   string // secret token Telegram sends with every update, random if empty
+	CertFile string // certificate and key to serve HTTPS, plain HTTP behind a reverse proxy if empty
+	KeyFile  string
+}
+
+// webhook receives updates posted by Telegram
+type webhook struct {
+	Webhook
+	server  *http.Server
+	updates chan tgbotapi.Update
+	done    chan struct{} // closed on shutdown, requests stop waiting to send updates
+	sending sync.RWMutex  // held by requests sending updates, updates is closed under the write lock
+	closed  sync.Once
+	err     error // why the server stopped on its own, set before updates is closed
+}
+
+// UseWebhook makes HandleUpdates receive updates by webhook instead of long polling. The webhook is set when HandleUpdates starts and deleted when it stops.
+func (b *Bot) UseWebhook(w Webhook) error {
+	if w.URL == "" {
+		return errors.New("webhook URL is empty")
+	}
+	if w.Listen == "" {
+		w.Listen = ":8080"
+	}
+	if w.Secret == "" {
+		buf := make([]byte, 32)
+		if _, err := rand.Read(buf); err != nil {
+			return err
+		}
+		w.Secret = hex.EncodeToString(buf)
+	}
+
+	wh := &webhook{
+		Webhook: w,
+		updates: make(chan tgbotapi.Update, 100),
+		done:    make(chan struct{}),
+	}
+	wh.server = &http.Server{
+		Addr:              w.Listen,
+		Handler:           wh,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	b.webhook = wh
+	return nil
+}
+
+// ServeHTTP accepts an update from Telegram if it carries the secret token
+func (w *webhook) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(rw, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(r.Header.Get(secretHeader)), []byte(w.Secret)) != 1 {
+		http.Error(rw, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var update tgbotapi.Update
+	if err := json.NewDecoder(http.MaxBytesReader(rw, r.Body, 1<<20)).Decode(&update); err != nil {
+		http.Error(rw, "invalid update", http.StatusBadRequest)
+		return
+	}
+
+	w.sending.RLock()
+	defer w.sending.RUnlock()
+
+	// Telegram retries updates that are not answered with 2xx. done is checked alone first, updates may be closed already and select picks any ready case.
+	select {
+	case <-w.done:
+		http.Error(rw, "shutting down", http.StatusServiceUnavailable)
+		return
+	default:
+	}
+	select {
+	case w.updates <- update:
+	case <-w.done:
+		http.Error(rw, "shutting down", http.StatusServiceUnavailable)
+	case <-r.Context().Done():
+		http.Error(rw, "busy", http.StatusServiceUnavailable)
+	}
+}
+
+// listen sets the webhook and serves it until stop
+func (w *webhook) listen(bot Transport) (tgbotapi.UpdatesChannel, error) {
+	// tgbotapi does not know secret_token yet
+	_, err := bot.MakeRequest("setWebhook", tgbotapi.Params{
+		"url":          w.URL,
+		"secret_token": w.Secret,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("set webhook: %w", err)
+	}
+
+	go func() {
+		var err error
+		if w.CertFile != "" && w.KeyFile != "" {
+			err = w.server.ListenAndServeTLS(w.CertFile, w.KeyFile)
+		} else {
+			err = w.server.ListenAndServe()
+		}
+		if !errors.Is(err, http.ErrServerClosed) {
+			w.err = fmt.Errorf("webhook server: %w", err)
+			// the webhook is set, Telegram would keep posting to a server that is not there
+			w.stop(bot)
+		}
+	}()
+
+	log.Printf("receiving updates on %s by webhook %s", w.Listen, w.URL)
+	return w.updates, nil
+}
+
+// stop deletes the webhook and shuts the server down
+func (w *webhook) stop(bot Transport) {
+	if _, err := bot.Request(tgbotapi.DeleteWebhookConfig{}); err != nil {
+		log.Printf("delete webhook: %v", err)
+	}
+	w.shutdown()
+}
+
+// shutdown waits for the requests in progress and closes the updates channel once no request can send to it
+func (w *webhook) shutdown() {
+	w.closed.Do(func() {
+		close(w.done)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := w.server.Shutdown(ctx); err != nil {
+			log.Printf("webhook server: %v", err)
+		}
+
+		w.sending.Lock()
+		close(w.updates)
+		w.sending.Unlock()
+	})
+}
+
+// updates returns the channel of updates, by webhook if UseWebhook was called or by long polling otherwise
+func (b *Bot) updates() (tgbotapi.UpdatesChannel, error) {
+	if b.webhook != nil {
+		return b.webhook.listen(b.Bot)
+	}
+
+	// getUpdates fails while a webhook is set, e.g. after switching modes
+	if _, err := b.Bot.Request(tgbotapi.DeleteWebhookConfig{}); err != nil {
+		return nil, fmt.Errorf("delete webhook: %w", err)
+	}
+
+	u := tgbotapi.NewUpdate(0)
+	u.Timeout = 60
+	return b.Bot.GetUpdatesChan(u), nil
+}
+
+// updatesErr returns why the updates channel was closed before HandleUpdates was stopped, nil if it was not an error
+func (b *Bot) updatesErr() error {
+	if b.webhook != nil {
+		return b.webhook.err
+	}
+	return nil
+}
+
+// stopUpdates stops receiving updates, the webhook is deleted in webhook mode
+func (b *Bot) stopUpdates() {
+	if b.webhook != nil {
+		b.webhook.stop(b.Bot)
+		return
+	}
+
+	b.Bot.StopReceivingUpdates()
+
+	// long polling confirms updates with the offset of the next request, confirm the handled ones so they are not received again after a restart
+	if last := b.lastUpdate.Load(); last != 0 {
+		u := tgbotapi.NewUpdate(int(last) + 1)
+		u.Limit = 1
+		if _, err := b.Bot.Request(u); err != nil {
+			log.Printf("confirm updates: %v", err)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package bot
+
+import (
+	"context"
+	"monitor/bot/bottest"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestWebhook(t *testing.T) {
+	b, _ := New(nil, nil)
+	if err := b.UseWebhook(Webhook{}); err == nil {
+		t.Error("UseWebhook should reject an empty URL")
+	}
+	if err := b.UseWebhook(Webhook{URL: "https://example.com/telegram"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(b.webhook.Secret) != 64 || b.webhook.Listen != ":8080" {
+		t.Errorf("secret %q, listen %q, want a random secret and :8080", b.webhook.Secret, b.webhook.Listen)
+	}
+
+	srv := httptest.NewServer(b.webhook)
+	defer srv.Close()
+
+	post := func(secret, body string) int {
+		req, _ := http.NewRequest(http.MethodPost, srv.URL, strings.NewReader(body))
+		req.Header.Set(secretHeader, secret)
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		return res.StatusCode
+	}
+
+	update := `{"update_id": 1, "message": {"message_id": 2, "text": "/status", "chat": {"id": 3}}}`
+	for _, c := range []struct {
+		secret, body string
+		want         int
+	}{
+		{"", update, http.StatusUnauthorized},
+		{"wrong", update, http.StatusUnauthorized},
+		{b.webhook.Secret, "{", http.StatusBadRequest},
+		{b.webhook.Secret, update, http.StatusOK},
+	} {
+		if got := post(c.secret, c.body); got != c.want {
+			t.Errorf("secret %q, body %q: status %d, want %d", c.secret, c.body, got, c.want)
+		}
+	}
+
+	if res, _ := http.Get(srv.URL); res.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("GET: status %d, want %d", res.StatusCode, http.StatusMethodNotAllowed)
+	}
+
+	if len(b.webhook.updates) != 1 {
+		t.Fatalf("got %d updates, want 1", len(b.webhook.updates))
+	}
+	if u := <-b.webhook.updates; u.UpdateID != 1 || u.Message.Text != "/status" {
+		t.Errorf("unexpected update %+v", u)
+	}
+
+	// a request waiting for room in the channel is turned away on shutdown, not sent to a closed channel
+	for len(b.webhook.updates) < cap(b.webhook.updates) {
+		b.webhook.updates <- tgbotapi.Update{}
+	}
+	status := make(chan int)
+	go func() { status <- post(b.webhook.Secret, update) }()
+	time.Sleep(100 * time.Millisecond)
+	b.webhook.shutdown()
+	if got := <-status; got != http.StatusServiceUnavailable {
+		t.Errorf("status %d during shutdown, want %d", got, http.StatusServiceUnavailable)
+	}
+	if got := post(b.webhook.Secret, update); got != http.StatusServiceUnavailable {
+		t.Errorf("status %d after shutdown, want %d", got, http.StatusServiceUnavailable)
+	}
+}
+
+func TestWebhookListenError(t *testing.T) {
+	// the address is taken, so the webhook server can not listen
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	srv := bottest.NewServer(t)
+	b, err := New(srv.API())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := b.UseWebhook(Webhook{URL: "https://example.com/telegram", Listen: l.Addr().String()}); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err = b.HandleUpdates(ctx)
+	if err == nil || !strings.Contains(err.Error(), "address already in use") {
+		t.Errorf("HandleUpdates returned %v, want the listen error", err)
+	}
+	if ctx.Err() != nil {
+		t.Error("HandleUpdates should return as soon as the server fails")
+	}
+	srv.Wait(func(r bottest.Request) bool { return r.Method == "deleteWebhook" })
+}
@@ -0,0 +1,200 @@
+// collector samples system metrics into labelled series, e.g. disk{mount="/"}.
+package collector
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"monitor/history"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Labels identify a series among the series with the same name.
+type Labels map[string]string
+
+// String returns the labels in the form {key="value",...}, or an empty string if there is no label.
+func (l Labels) String() string {
+	if len(l) == 0 {
+		return ""
+	}
+
+	keys := make([]string, 0, len(l))
+	for k := range l {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	seg := make([]string, len(keys))
+	for i, k := range keys {
+		seg[i] = fmt.Sprintf("%s=%q", k, l[k])
+	}
+
+	return "{" + strings.Join(seg, ",") + "}"
+}
+
+// Match checks if the labels contain all the labels in selector.
+func (l Labels) Match(selector Labels) bool {
+	for k, v := range selector {
+		if l[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// Sample is a single value of a series.
+type Sample struct {
+	Name   string
+	Labels Labels
+	Value  float64
+}
+
+// Key returns the unique key of the series of the sample.
+func (s Sample) Key() string {
+	return s.Name + s.Labels.String()
+}
+
+// Func samples one or more series, it should give up when ctx is done.
+type Func func(ctx context.Context) ([]Sample, error)
+
+// Series is a named history.
+type Series struct {
+	Name    string
+	Labels  Labels
+	History *history.History
+}
+
+// Key returns the unique key of the series.
+func (s *Series) Key() string {
+	return s.Name + s.Labels.String()
+}
+
+// Registry keeps the collectors and the history of every series they produced.
+type Registry struct {
+	LiveTime time.Duration
+	mu       sync.RWMutex
+	funcs    []Func
+	series   map[string]*Series
+}
+
+// New creates a new Registry, histories created by the registry keep records for liveTime.
+func New(liveTime time.Duration) *Registry {
+	return &Registry{
+		LiveTime: liveTime,
+		series:   map[string]*Series{},
+	}
+}
+
+// Register adds collectors to the registry.
+func (r *Registry) Register(funcs ...Func) *Registry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.funcs = append(r.funcs, funcs...)
+	return r
+}
+
+// Track uses an existing history for the series with the given name and labels.
+func (r *Registry) Track(name string, labels Labels, h *history.History) *Registry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s := &Series{Name: name, Labels: labels, History: h}
+	r.series[s.Key()] = s
+	return r
+}
+
+// Collect runs all collectors and returns their samples. Collectors that fail are logged and skipped, the remaining ones are skipped once ctx is done.
+func (r *Registry) Collect(ctx context.Context) []Sample {
+	r.mu.RLock()
+	funcs := r.funcs
+	r.mu.RUnlock()
+
+	samples := []Sample{}
+	for _, f := range funcs {
+		if ctx.Err() != nil {
+			break
+		}
+		s, err := f(ctx)
+		if err != nil {
+			log.Printf("collect: %v\n", err)
+			continue
+		}
+		samples = append(samples, s...)
+	}
+
+	return samples
+}
+
+// Record appends the samples to the history of their series, creating the series if needed.
+func (r *Registry) Record(samples []Sample) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, s := range samples {
+		key := s.Key()
+		series, ok := r.series[key]
+		if !ok {
+			series = &Series{
+				Name:    s.Name,
+				Labels:  s.Labels,
+				History: history.New(r.LiveTime, key),
+			}
+			r.series[key] = series
+		}
+		series.History.Append(s.Value)
+	}
+}
+
+// Find returns the series with the given name whose labels match selector.
+func (r *Registry) Find(name string, selector Labels) []*Series {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	res := []*Series{}
+	for _, s := range r.series {
+		if s.Name == name && s.Labels.Match(selector) {
+			res = append(res, s)
+		}
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Key() < res[j].Key()
+	})
+
+	return res
+}
+
+// History returns the history of the only series with the given name that matches selector.
+func (r *Registry) History(name string, selector Labels) (*history.History, error) {
+	series := r.Find(name, selector)
+
+	switch len(series) {
+	case 0:
+		return nil, fmt.Errorf("no series %s%s", name, selector)
+	case 1:
+		return series[0].History, nil
+	default:
+		return nil, fmt.Errorf("%s%s matches %d series, add labels to select one", name, selector, len(series))
+	}
+}
+
+// All returns all series sorted by key.
+func (r *Registry) All() []*Series {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	res := make([]*Series, 0, len(r.series))
+	for _, s := range r.series {
+		res = append(res, s)
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Key() < res[j].Key()
+	})
+
+	return res
+}
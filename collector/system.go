@@ -0,0 +1,76 @@
+package collector
+
+import (
+	"context"
+	"time"
+
+	"github.com/shirou/gopsutil/cpu"
+	"github.com/shirou/gopsutil/disk"
+	"github.com/shirou/gopsutil/mem"
+	"github.com/shirou/gopsutil/net"
+)
+
+// CPU samples the CPU usage percentage as "cpu".
+func CPU(ctx context.Context) ([]Sample, error) {
+	p, err := cpu.PercentWithContext(ctx, time.Second, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return []Sample{{Name: "cpu", Value: p[0]}}, nil
+}
+
+// Memory samples the memory usage percentage as "mem".
+func Memory(ctx context.Context) ([]Sample, error) {
+	m, err := mem.VirtualMemoryWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return []Sample{{Name: "mem", Value: m.UsedPercent}}, nil
+}
+
+// Network samples the total received and sent bytes as "net_rx" and "net_tx".
+func Network(ctx context.Context) ([]Sample, error) {
+	c, err := net.IOCountersWithContext(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	if len(c) == 0 {
+		return nil, nil
+	}
+
+	return []Sample{
+		{Name: "net_rx", Value: float64(c[0].BytesRecv)},
+		{Name: "net_tx", Value: float64(c[0].BytesSent)},
+	}, nil
+}
+
+// Disk samples the usage percentage of every mounted partition as "disk" with a "mount" label.
+func Disk(ctx context.Context) ([]Sample, error) {
+	partitions, err := disk.PartitionsWithContext(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+
+	samples := []Sample{}
+	seen := map[string]bool{}
+	for _, p := range partitions {
+		if seen[p.Mountpoint] {
+			continue
+		}
+		seen[p.Mountpoint] = true
+
+		u, err := disk.UsageWithContext(ctx, p.Mountpoint)
+		if err != nil || u.Total == 0 {
+			continue
+		}
+		samples = append(samples, Sample{
+			Name:   "disk",
+			Labels: Labels{"mount": p.Mountpoint},
+			Value:  u.UsedPercent,
+		})
+	}
+
+	return samples, nil
+}
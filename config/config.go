@@ -3,6 +3,8 @@ package config
 import (
 	"fmt"
 	mybot "monitor/bot"
+	"monitor/i18n"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -106,48 +108,114 @@ func (c *Config) GetFloat64(name string) float64 {
 	return c.float64[name].Val
 }
 
-// All returns all configuration values in string format.
-func (c *Config) All() string {
+// Keys returns the names of all configuration values, sorted.
+func (c *Config) Keys() []string {
+	keys := []string{}
+	for k := range c.int {
+		keys = append(keys, k)
+	}
+	for k := range c.float64 {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Value returns the value of a configuration value in string format, empty if there is no such value.
+func (c *Config) Value(name string) string {
+	if v, ok := c.int[name]; ok {
+		return strconv.Itoa(v.Val)
+	}
+	if v, ok := c.float64[name]; ok {
+		return strconv.FormatFloat(v.Val, 'f', -1, 64)
+	}
+	return ""
+}
+
+// SetString parses val and sets the configuration value to it.
+func (c *Config) SetString(name string, val string) error {
+	if _, ok := c.int[name]; ok {
+		i, err := strconv.Atoi(val)
+		if err != nil {
+			return fmt.Errorf("failed to convert \"%s\" to int", val)
+		}
+		c.SetInt(name, i)
+		return nil
+	}
+	if _, ok := c.float64[name]; ok {
+		f, err := strconv.ParseFloat(val, 64)
+		if err != nil {
+			return fmt.Errorf("failed to convert \"%s\" to float64", val)
+		}
+		c.SetFloat64(name, f)
+		return nil
+	}
+	return fmt.Errorf("invalid config: %s", name)
+}
+
+// kind returns the type of a configuration value, empty if there is no such value
+func (c *Config) kind(name string) string {
+	if _, ok := c.int[name]; ok {
+		return "int"
+	}
+	if _, ok := c.float64[name]; ok {
+		return "float64"
+	}
+	return ""
+}
+
+// All returns all configuration values in string format, with the header and numbers in the language of l.
+func (c *Config) All(l *i18n.Locale) string {
 	var sb strings.Builder
 
-	sb.WriteString("===Config===\n")
+	sb.WriteString(l.T("config.header") + "\n")
 	for k, v := range c.int {
-		sb.WriteString(fmt.Sprintf("%s: %d\n", k, v.Val))
+		sb.WriteString(fmt.Sprintf("%s: %s\n", k, l.Num(float64(v.Val), 0)))
 	}
 	for k, v := range c.float64 {
-		sb.WriteString(fmt.Sprintf("%s: %.2f\n", k, v.Val))
+		sb.WriteString(fmt.Sprintf("%s: %s\n", k, l.Num(v.Val, 2)))
 	}
 
 	return sb.String()
 }
 
-// CmdSet handle /set command, if return true, the command was handled.
-func (c *Config) CmdSet(bot *mybot.Bot, update tgbotapi.Update) {
-	seg := strings.Split(update.Message.Text, " ")[1:]
-	fmt.Println(seg)
+// CmdSet handle /set command, it expects the "key" and "value" arguments, see Keys.
+func (c *Config) CmdSet(bot *mybot.Bot, update tgbotapi.Update, args mybot.Args) {
+	key, val := args.String("key"), args.String("value")
 
-	if len(seg) < 2 {
-		bot.SendMsg(update.Message.Chat.ID, "/set <key> <value>")
+	old := c.Value(key)
+	if c.kind(key) == "" {
+		bot.Reply(update, "config.invalid", "key", key)
+		return
+	}
+	if err := c.SetString(key, val); err != nil {
+		bot.Reply(update, "config.convert", "value", val, "type", c.kind(key))
 		return
 	}
 
-	if _, ok := c.int[seg[0]]; ok {
-		v := seg[1]
-		if i, err := strconv.Atoi(v); err == nil {
-			bot.SendMsg(update.Message.Chat.ID, fmt.Sprintf("Set %s to %d (previous: %d)", seg[0], i, c.GetInt(seg[0])))
-			c.SetInt(seg[0], i)
-		} else {
-			bot.SendMsg(update.Message.Chat.ID, fmt.Sprintf("Failed to convert \"%s\" to int", v))
-		}
-	} else if _, ok := c.float64[seg[0]]; ok {
-		v := seg[1]
-		if f, err := strconv.ParseFloat(v, 64); err == nil {
-			bot.SendMsg(update.Message.Chat.ID, fmt.Sprintf("Set %s to %.2f (previous: %.2f)", seg[0], f, c.GetFloat64(seg[0])))
-			c.SetFloat64(seg[0], f)
-		} else {
-			bot.SendMsg(update.Message.Chat.ID, fmt.Sprintf("Failed to convert \"%s\" to float64", v))
-		}
+	bot.Audit(update, "set", key, old, c.Value(key))
+	bot.Reply(update, "config.set", "key", key, "value", c.Value(key), "prev", old)
+}
+
+// CmdReset handle /reset command, it resets the configuration value of the "key" argument to its default value.
+func (c *Config) CmdReset(bot *mybot.Bot, update tgbotapi.Update, args mybot.Args) {
+	name := args.String("key")
+
+	old := c.Value(name)
+	if _, ok := c.int[name]; ok {
+		c.ResetInt(name)
+	} else if _, ok := c.float64[name]; ok {
+		c.ResetFloat(name)
 	} else {
-		bot.SendMsg(update.Message.Chat.ID, fmt.Sprintf("Invalid config: %s", seg[0]))
+		bot.Reply(update, "config.invalid", "key", name)
+		return
 	}
+
+	bot.Audit(update, "reset", name, old, c.Value(name))
+	bot.Reply(update, "config.reset", "key", name, "value", c.Value(name), "prev", old)
+}
+
+// Revert reverts a /set or /reset recorded in the audit log.
+func (c *Config) Revert(bot *mybot.Bot, e mybot.AuditEntry) error {
+	return c.SetString(e.Target, e.Old)
 }
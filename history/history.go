@@ -1,9 +1,10 @@
-// history is a package that provides a simple history of float64 values. It is used to store a history of values and calculate the average of those values over a given time period. Every operation will automatically remove the records that out of date, so that only the most recent values are kept. The history package is tested in history/history_test.go.
+// history keeps the recent values of a series and computes stats over them.
 package history
 
 import (
 	"fmt"
 	"math"
+	"sync"
 	"time"
 )
 
@@ -14,10 +15,11 @@ type Record[T any] struct {
 	Time time.Time
 }
 
-// History keep records that in live time. It implement String interface
+// History keep records that in live time. It implement String interface. It is safe for concurrent use.
 type History struct {
 	LiveTime time.Duration
 	Name     string
+	mu       sync.RWMutex
 	records  []Record[float64]
 }
 
@@ -32,35 +34,48 @@ func New(liveTime time.Duration, name string) *History {
 
 // Len returns the number of records in the history
 func (h *History) Len() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
 	return len(h.records)
 }
 
-// Records returns the records in the history
+// Records returns a copy of the records in the history
 func (h *History) Records() []Record[float64] {
-	return h.records
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return append([]Record[float64]{}, h.records...)
 }
 
 // Data returns the data in the history
 func (h *History) Datas() []float64 {
-	d := make([]float64, h.Len())
-	for i, v := range h.records {
-		d[i] = v.Data
-	}
-	return d
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return datas(h.records)
 }
 
 // Times returns the times of the records in the history
 func (h *History) Times() []time.Time {
-	t := make([]time.Time, h.Len())
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	t := make([]time.Time, len(h.records))
 	for i, v := range h.records {
 		t[i] = v.Time
 	}
 	return t
 }
 
-// after rettuenrs the index of the first element in the history that is after t
-func (h *History) after(t time.Time) int {
-	for i, v := range h.records {
+// datas returns the data of records
+func datas(records []Record[float64]) []float64 {
+	d := make([]float64, len(records))
+	for i, v := range records {
+		d[i] = v.Data
+	}
+	return d
+}
+
+// after rettuenrs the index of the first element in records that is after t
+func after(records []Record[float64], t time.Time) int {
+	for i, v := range records {
 		if v.Time.After(t) {
 			return i
 		}
@@ -68,21 +83,26 @@ func (h *History) after(t time.Time) int {
 	return 0
 }
 
-// update remove the records that are out of date
-func (h *History) update() {
-	end := now().Add(-h.LiveTime)
-	i := h.after(end)
+// live returns the records that are not out of date without removing the others, it must be called with h.mu held
+func (h *History) live() []Record[float64] {
+	return h.records[after(h.records, now().Add(-h.LiveTime)):]
+}
 
-	h.records = h.records[i:]
+// since returns the live records over the given duration, it must be called with h.mu held
+func (h *History) since(duration time.Duration) []Record[float64] {
+	r := h.live()
+	return r[after(r, now().Add(-duration)):]
 }
 
-// Append adds a new data point to the history
+// Append adds a new data point to the history and removes the records that are out of date
 func (h *History) Append(data float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
 	h.records = append(h.records, Record[float64]{
 		Data: data,
 		Time: now(),
 	})
-	h.update()
+	h.records = h.live()
 }
 
 // avg is a helper function, which calculates the average of the given data
@@ -96,31 +116,125 @@ func avg(data ...float64) float64 {
 
 // Average returns the average of the data in the history over the given duration
 func (h *History) Average(duration time.Duration) float64 {
-	h.update()
-	i := h.after(now().Add(-duration))
-	return avg(h.Datas()[i:]...)
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return avg(datas(h.since(duration))...)
 }
 
 func (h *History) StdDev(duration time.Duration) float64 {
-	h.update()
-	i := h.after(now().Add(-duration))
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	r := h.since(duration)
 
 	sum2 := 0.0
 
-	for _, v := range h.records[i:] {
+	for _, v := range r {
 		sum2 += v.Data * v.Data
 	}
 
-	n := float64(len(h.records[i:]))
-	avg := avg(h.Datas()[i:]...)
+	n := float64(len(r))
+	avg := avg(datas(r)...)
 	return math.Sqrt(sum2/n - avg*avg)
 }
 
+// Last returns the latest data in the history, ok is false if the history is empty
+func (h *History) Last() (float64, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	r := h.live()
+	if len(r) == 0 {
+		return 0, false
+	}
+	return r[len(r)-1].Data, true
+}
+
+// Min returns the minimum of the data in the history over the given duration
+func (h *History) Min(duration time.Duration) float64 {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	m := math.NaN()
+	for _, v := range h.since(duration) {
+		if math.IsNaN(m) || v.Data < m {
+			m = v.Data
+		}
+	}
+	return m
+}
+
+// Max returns the maximum of the data in the history over the given duration
+func (h *History) Max(duration time.Duration) float64 {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	m := math.NaN()
+	for _, v := range h.since(duration) {
+		if math.IsNaN(m) || v.Data > m {
+			m = v.Data
+		}
+	}
+	return m
+}
+
+// Rate returns the per-second change of the data in the history over the given duration. It is meant for counters such as received bytes.
+func (h *History) Rate(duration time.Duration) float64 {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	r := h.since(duration)
+	if len(r) < 2 {
+		return 0
+	}
+
+	first, last := r[0], r[len(r)-1]
+	dt := last.Time.Sub(first.Time).Seconds()
+	if dt <= 0 {
+		return 0
+	}
+	return (last.Data - first.Data) / dt
+}
+
+// Forecast predicts the data after the given duration with a linear regression over the whole history
+func (h *History) Forecast(duration time.Duration) float64 {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	r := h.live()
+	n := now()
+
+	if len(r) == 0 {
+		return math.NaN()
+	}
+	if len(r) == 1 {
+		return r[0].Data
+	}
+
+	var sx, sy, sxx, sxy float64
+	for _, v := range r {
+		x := v.Time.Sub(n).Seconds()
+		sx += x
+		sy += v.Data
+		sxx += x * x
+		sxy += x * v.Data
+	}
+
+	k := float64(len(r))
+	d := k*sxx - sx*sx
+	if d == 0 {
+		return sy / k
+	}
+
+	slope := (k*sxy - sx*sy) / d
+	intercept := (sy - slope*sx) / k
+
+	return intercept + slope*duration.Seconds()
+}
+
 func (h *History) String() string {
-	h.update()
+	h.mu.RLock()
+	defer h.mu.RUnlock()
 	n := now()
 	s := ""
-	for _, v := range h.records {
+	for _, v := range h.live() {
 		s += fmt.Sprintf("%f %s\n", v.Data, v.Time.Sub(n))
 	}
 
@@ -47,3 +47,78 @@ func TestHistory(t *testing.T) {
 		t.Error("Average returned the wrong value")
 	}
 }
+
+func TestStats(t *testing.T) {
+	n := time.Now()
+
+	now = func() time.Time {
+		return n
+	}
+
+	h := New(30*time.Minute, "test")
+
+	if _, ok := h.Last(); ok {
+		t.Error("Last of an empty history should not be ok")
+	}
+
+	for i := 0; i < 10; i++ {
+		h.Append(float64(i * 60))
+		n = n.Add(time.Minute)
+	}
+
+	if v, _ := h.Last(); !eq(t, v, 540) {
+		t.Error("Last returned the wrong value")
+	}
+
+	if !eq(t, h.Min(5*time.Minute), 360) {
+		t.Error("Min returned the wrong value")
+	}
+
+	if !eq(t, h.Max(5*time.Minute), 540) {
+		t.Error("Max returned the wrong value")
+	}
+
+	if !eq(t, h.Rate(5*time.Minute), 1) {
+		t.Error("Rate returned the wrong value")
+	}
+
+	// the last record is one minute ago, so in 10 minutes it should be 540 + 11*60
+	if !eq(t, h.Forecast(10*time.Minute), 1200) {
+		t.Error("Forecast returned the wrong value")
+	}
+}
+
+func TestPlotRange(t *testing.T) {
+	h := New(time.Hour, "cpu")
+	h.records = append(h.records, Record[float64]{Data: 10, Time: time.Now().Add(-30 * time.Minute)})
+	h.Append(20)
+
+	for _, d := range []time.Duration{0, time.Minute, time.Nanosecond} {
+		if _, err := PlotRange(d, h); err != nil {
+			t.Errorf("PlotRange(%s): %v", d, err)
+		}
+	}
+}
+
+func TestConcurrent(t *testing.T) {
+	h := New(time.Hour, "test")
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 1000; i++ {
+			h.Append(float64(i))
+		}
+	}()
+	for i := 0; i < 1000; i++ {
+		h.Average(time.Minute)
+		h.Last()
+		h.Records()
+		_ = h.String()
+	}
+	<-done
+
+	if h.Len() != 1000 {
+		t.Errorf("got %d records, want 1000", h.Len())
+	}
+}
@@ -2,6 +2,7 @@ package history
 
 import (
 	"io"
+	"time"
 
 	"gonum.org/v1/plot"
 	"gonum.org/v1/plot/plotter"
@@ -9,6 +10,16 @@ import (
 	"gonum.org/v1/plot/vg"
 )
 
+// since returns the points from x on
+func since(pts plotter.XYs, x float64) plotter.XYs {
+	for i, pt := range pts {
+		if pt.X >= x {
+			return pts[i:]
+		}
+	}
+	return plotter.XYs{}
+}
+
 func history2Points(history *History) plotter.XYs {
 	pts := make(plotter.XYs, history.Len())
 	for i, r := range history.Records() {
@@ -19,7 +30,13 @@ func history2Points(history *History) plotter.XYs {
 	return pts
 }
 
+// Plot plots all data of the histories.
 func Plot(histories ...*History) (io.WriterTo, error) {
+	return PlotRange(0, histories...)
+}
+
+// PlotRange plots the data of the histories in the last d, or all data if d is 0.
+func PlotRange(d time.Duration, histories ...*History) (io.WriterTo, error) {
 	// xticks defines how we convert and display time.Time values.
 	xticks := plot.TimeTicks{Format: "2006-01-02\n15:04"}
 
@@ -33,10 +50,18 @@ func Plot(histories ...*History) (io.WriterTo, error) {
 	p.Y.Min = 0
 	p.Y.Max = 100
 
+	if d > 0 {
+		p.X.Min = float64(time.Now().Add(-d).Unix())
+		p.X.Max = float64(time.Now().Unix())
+	}
+
 	lines := []interface{}{}
 
 	for _, h := range histories {
 		data := history2Points(h)
+		if d > 0 {
+			data = since(data, p.X.Min)
+		}
 		lines = append(lines, h.Name, data)
 	}
 
@@ -0,0 +1,230 @@
+package i18n
+
+// English is the catalog of English, the language of the command descriptions and the fallback of missing messages.
+var English = &Locale{
+	Tag:  "en",
+	Name: "English",
+	Plural: func(n float64) int {
+		if n == 1 {
+			return 0
+		}
+		return 1
+	},
+	Decimal: ".",
+	Group:   ",",
+	Clock:   "15:04",
+	Time:    "15:04:05",
+	Date:    "2006-01-02 15:04",
+	Units:   Units{Day: "d", Hour: "h", Minute: "m", Second: "s"},
+	Messages: map[string]string{
+		// shared
+		"not_subscribed": "Not subscribed, /subscribe first",
+		"saved":          "Saved",
+		"more":           "... and {{.n}} more",
+		"metric.cpu":     "CPU",
+		"metric.mem":     "Memory",
+
+		"severity.info":     "info",
+		"severity.warning":  "warning",
+		"severity.critical": "critical",
+
+		"role.guest":    "guest",
+		"role.viewer":   "viewer",
+		"role.operator": "operator",
+		"role.admin":    "admin",
+
+		"scope.private": "private chats",
+		"scope.groups":  "groups",
+		"scope.any":     "any chat",
+		"scope.none":    "no chat",
+
+		// bot
+		"bot.started":            "Bot started",
+		"bot.stopping":           "Bot stopping",
+		"subscribe.done":         "Subscribed to notifications",
+		"subscribe.already":      "Already subscribed",
+		"unsubscribe.done":       "Unsubscribed from notifications",
+		"unsubscribe.not":        "Not subscribed",
+		"error.panic":            "Something went wrong, please try again later",
+		"error.rate_limit":       "Too many requests, at most {{.n}} per {{dur .interval}}, slow down",
+		"error.rate_limit_toast": "Too many requests",
+		"denied":                 `Permission denied: this needs the {{t (print "role." .role)}} role, you are {{t (print "role." .have)}} (user ID {{.id}})`,
+
+		// commands
+		"help.header":         "Available commands:\n/help [command] - Show this message or the usage of a command",
+		"help.guest":          "Ask an admin for a role to use the other commands",
+		"cmd.scope":           `/{{.cmd}} only works in {{t (print "scope." .scope)}}`,
+		"cmd.usage":           "{{.err}}\n\nUsage: {{.usage}}",
+		"cmd.unknown":         "Unknown command /{{.cmd}}. ",
+		"cmd.invalid_arg":     `Invalid {{.name}} "{{.value}}". `,
+		"cmd.did_you_mean":    `Did you mean {{range $i, $c := .cmds}}{{if $i}} or {{end}}{{$c}}{{end}}?`,
+		"button.unknown":      "Unknown button",
+		"button.expired":      "This button has expired",
+		"button.invalid":      "Invalid button",
+		"form.timeout":        "No answer for too long, cancelled",
+		"form.optional":       "{{if .def}}(- for {{.def}}){{else}}(- to skip){{end}}",
+		"form.gone":           "This question is not asked anymore",
+		"form.not_yours":      "This question is for someone else",
+		"form.cancelled":      "Cancelled",
+		"form.nothing":        "Nothing to cancel",
+		"args.invalid":        `invalid {{.name}} "{{.value}}": {{.reason}}`,
+		"args.missing_arg":    "{{.name}}: {{.reason}}",
+		"args.missing":        "missing",
+		"args.missing_value":  "missing value",
+		"args.not_kind":       "not {{.kind}}",
+		"args.choose":         "choose one of {{.choices}}",
+		"args.unknown_flag":   "unknown flag --{{.flag}}",
+		"args.too_many":       "too many arguments: {{.args}}",
+		"kind.string":         "a word",
+		"kind.text":           "text",
+		"kind.int":            "an integer",
+		"kind.float":          "a number",
+		"kind.duration":       "a duration, e.g. 30m or 2h",
+		"kind.enum":           "a choice",
+		"kind.bool":           "true or false",
+		"lang.show":           "Language: {{.name}}{{if .auto}}, following your Telegram app{{end}}\n\nLanguages:\n{{range .langs}}{{.}}\n{{end}}auto - follow your Telegram app\n\n/lang <language>",
+		"lang.set":            "Language set to {{.name}}{{if .auto}}, following your Telegram app{{end}}",
+		"hi.ask":              "What's your name?",
+		"hi.hello":            "Hello, {{.name}}",
+		"inline.denied":       "Permission denied, tap for help",
+		"failures.none":       "No failed delivery",
+		"failures.header":     "===Failed deliveries===",
+		"secondary.on":        "Subscribed to escalated alerts",
+		"secondary.off":       "Unsubscribed from escalated alerts",
+		"group.settings":      "===Group settings===\nTopic: {{if .topic}}{{.topic}}{{else}}general{{end}}\nHost classes: {{or .classes \"all\"}}\n\n{{.prefs}}",
+		"group.invalid_topic": `Invalid topic "{{.value}}", use the number after the chat in links to the topic, or off`,
+
+		// status and plots
+		"status":                   "===Current Value===\nCPU: {{pct .cpu}}\nMemory: {{pct .mem}}\n\n=====Average=====\nCPU: {{pct .cpu_avg}} (±{{num .cpu_stddev}})\nMemory: {{pct .mem_avg}} (±{{num .mem_stddev}})",
+		"status.cpu_error":         "Error getting CPU usage",
+		"status.mem_error":         "Error getting memory usage",
+		"config.header":            "===Config===",
+		"config.set":               "Set {{.key}} to {{.value}} (previous: {{.prev}})",
+		"config.reset":             "Reset {{.key}} to {{.value}} (previous: {{.prev}})",
+		"config.invalid":           "Invalid config: {{.key}}",
+		"config.convert":           `Failed to convert "{{.value}}" to {{.type}}`,
+		"plot.no_series":           "No series named {{.metric}}",
+		"plot.error":               "Error plotting",
+		"plot.invalid_range":       "Invalid range {{.range}}",
+		"plotbtn.text":             "Click to plot",
+		"plotbtn.button":           "Plot",
+		"dashboard.header":         "===Dashboard===",
+		"dashboard.firing":         "===Firing alerts===",
+		"dashboard.none":           "None",
+		"dashboard.acked":          "(acked by {{.by}})",
+		"dashboard.updated":        "Updated at {{time .now}}, until {{clock .until}}",
+		"dashboard.error":          "Cannot start the dashboard: {{.err}}",
+		"dashboard.stop":           "Stop",
+		"dashboard.stopped":        "Stopped at {{clock .at}}",
+		"dashboard.gone":           "This dashboard is not running anymore",
+		"dashboard.stopping":       "Dashboard stopped",
+		"dashboard.interval":       "interval must be at least {{dur .min}}",
+		"dashboard.lifetime":       "lifetime must be between 0 and {{dur .max}}",
+		"add.progress":             "add {{.i}}",
+		"add.done":                 "Done",
+		"inline.value":             "{{.key}}: {{.value}} at {{date .now}}",
+		"inline.value_description": "Current value",
+		"inline.stats":             "avg {{pct .avg}} (±{{num .stddev}}), min {{pct .min}}, max {{pct .max}}",
+		"inline.over":              "{{.key}} over {{dur .range}}",
+		"inline.over_until":        "{{.key}} over {{dur .range}} until {{date .now}}",
+		"inline.plot":              "{{.key}} plot over {{dur .range}}",
+
+		// alerts
+		"alert.high":              `High {{t (print "metric." .metric)}} usage detected: {{pct .value}}`,
+		"alert.normal":            `{{t (print "metric." .metric)}} usage back to normal: {{pct .value}}`,
+		"alert.critical":          `Critical {{t (print "metric." .metric)}} usage: {{pct .value}}`,
+		"alert.flapping":          `{{t (print "metric." .metric)}} usage is flapping around {{pct .threshold}}: {{num .changes 0}} state {{plural .changes "change" "changes"}} in {{dur .window}} (min {{pct .min}}, max {{pct .max}}), further alerts are suppressed until it settles`,
+		"alert.flap_stopped":      `{{t (print "metric." .metric)}} usage stopped flapping and is {{if .firing}}high{{else}}normal{{end}}: {{pct .value}} ({{num .suppressed 0}} {{plural .suppressed "change" "changes"}} suppressed)`,
+		"alert.increase":          `Sudden increase in {{t (print "metric." .metric)}} usage detected: {{pct .value}} (z = {{num .z}})`,
+		"alert.rule_firing":       "Rule {{.rule}} firing: {{.expr}}{{range .terms}}\n{{.}}{{end}}",
+		"alert.rule_resolved":     "Rule {{.rule}} resolved: {{.expr}}{{range .terms}}\n{{.}}{{end}}",
+		"alert.rule_flapping":     `Rule {{.rule}} is flapping: {{num .changes 0}} state {{plural .changes "change" "changes"}} in {{dur .window}}, further alerts are suppressed until it settles`,
+		"alert.rule_flap_stopped": `Rule {{.rule}} stopped flapping and is {{if .firing}}firing{{else}}resolved{{end}} ({{num .suppressed 0}} {{plural .suppressed "change" "changes"}} suppressed)`,
+		"alerts":                  `{{.n}} {{plural .n "alert" "alerts"}}:`,
+		"alert.ack":               "Ack",
+		"alert.mute":              "Mute 1h",
+		"alert.plot":              "Show plot",
+		"alert.ack_id":            "Ack #{{.id}}",
+		"alert.mute_id":           "Mute #{{.id}} 1h",
+		"alert.plot_id":           "Plot #{{.id}}",
+		"alert.reminder":          "Reminder, unacknowledged for {{dur .for}}:\n{{.text}}",
+		"alert.escalated":         "Escalated, unacknowledged for {{dur .for}}:\n{{.text}}",
+		"alert.gone":              "Alert #{{.id}} no longer exists",
+		"alert.acked":             "Alert #{{.id}} acknowledged by {{.by}}",
+		"alert.acked_already":     "Alert #{{.id}} was acknowledged by {{.by}} already",
+		"digest":                  `Digest: {{.n}} {{plural .n "alert" "alerts"}} since {{clock .since}}`,
+		"digest.show":             "{{if .every}}Low severity alerts are sent as a digest every {{dur .every}}{{else}}Digest mode is off{{end}}\n/digest <minutes|off>",
+		"digest.on":               `Low severity alerts are sent as a digest every {{.n}} {{plural .n "minute" "minutes"}}, warning and critical alerts are still sent immediately`,
+		"digest.off":              "Digest mode is off, all alerts are sent immediately",
+		"digest.invalid":          "Invalid argument \"{{.arg}}\"\n/digest <minutes|off>",
+
+		// rules
+		"rules.none":           "No rules, add one with /rule add <name> <expression>",
+		"rules.header":         "===Rules===",
+		"rules.ok":             "ok",
+		"rules.firing":         "firing",
+		"rules.flapping":       "flapping",
+		"rules.invalid":        "Invalid rule: {{.err}}",
+		"rules.added":          "Added rule {{.name}}: {{.expr}}",
+		"rules.added_severity": "Added {{.severity}} rule {{.name}}: {{.expr}}",
+		"rules.deleted":        "Deleted rule {{.name}}",
+		"rules.no_rule":        "No rule named {{.name}}",
+		"rules.severity":       `Rule {{.name}} now raises {{t (print "severity." .severity)}} alerts`,
+		"rules.usage":          "/rule new (guided setup)\n/rule add <name> <expression>\n/rule del <name>\n/rule severity <name> <info|warning|critical>\n/rule test <name or expression>\n\nExample: /rule add high_cpu avg(cpu, 5m) > 80 && mem > 90\nFunctions: avg, min, max, stddev, rate, forecast (series, duration)",
+		"rules.bad_expr":       "Invalid expression: {{.err}}",
+		"rules.error":          "Error: {{.err}}",
+		"rules.result":         `Result: {{printf "%g" .value}}, the rule would {{if not .fire}}not {{end}}fire`,
+		"rules.not_series":     "{{.series}} is not a series",
+		"rules.ask_name":       "Name of the rule, lowercase letters, digits, _ and -, e.g. high_cpu",
+		"rules.bad_name":       `invalid rule name "{{.name}}", use lowercase letters, digits, '_' and '-'`,
+		"rules.ask_kind":       "Kind of rule: compare a series with a threshold, or write an expression",
+		"rules.ask_series":     `Series to watch, e.g. cpu, mem, net_rx or disk{mount="/"}`,
+		"rules.ask_function":   "Value to compare: the last sample, or a function over a window",
+		"rules.ask_window":     "Window of the function, e.g. 5m (for forecast, how far ahead)",
+		"rules.bad_window":     "the window must be positive",
+		"rules.ask_op":         "Fire when the value is above (>) or below (<) the threshold",
+		"rules.ask_value":      "Threshold, e.g. 80",
+		"rules.ask_expr":       "Expression, e.g. avg(cpu, 5m) > 80 && mem > 90\nFunctions: avg, min, max, stddev, rate, forecast (series, duration)",
+		"rules.ask_severity":   "Severity of the alerts",
+
+		// silences
+		"silence":                  "#{{.id}} {{.matcher}} until {{date .until}} by {{.by}}{{if .reason}}: {{.reason}}{{end}}",
+		"silence.muted":            "Muted {{.silence}}",
+		"silence.ended":            "Silence ended: {{.silence}}",
+		"silence.none_suppressed":  "No alert was suppressed",
+		"silence.suppressed":       `{{.n}} {{plural .n "alert was" "alerts were"}} suppressed:`,
+		"silence.invalid_duration": `Invalid duration "{{.d}}", it must be positive`,
+		"silence.no_match":         "No silence matches {{.arg}}",
+		"silence.unmuted":          "Ended {{.n}} {{plural .n \"silence\" \"silences\"}}:{{range .silences}}\n{{.}}{{end}}",
+		"silence.none":             "No active silence",
+		"silence.header":           "===Silences===",
+		"silence.item":             "{{.silence}} ({{.n}} suppressed)",
+
+		// preferences
+		"prefs":                  "Minimum severity: {{t (print \"severity.\" .severity)}}\nMetrics: {{or .metrics \"all\"}}\nQuiet hours: {{or .quiet \"off\"}}",
+		"prefs.show":             "===Preferences===\n{{.prefs}}\n\nAnswer - to keep a value, /cancel to stop",
+		"prefs.ask_severity":     "Minimum severity",
+		"prefs.ask_metrics":      "Rules or metrics to get alerts of, separated by commas, e.g. cpu, high_disk, or * for all",
+		"prefs.ask_quiet":        "Quiet hours, only critical alerts are sent during them, e.g. 22:00-07:00 or off",
+		"prefs.ask_timezone":     "Timezone of the quiet hours, e.g. Asia/Taipei",
+		"prefs.invalid_quiet":    `Invalid quiet hours "{{.value}}", use hh:mm-hh:mm or off`,
+		"prefs.invalid_timezone": `Unknown timezone "{{.value}}"`,
+		"prefs.unsubscribed":     "Not subscribed anymore, preferences not saved",
+
+		// roles and audit log
+		"role.grant_usage":    "/grant <user ID> <viewer|operator|admin>, or reply to a message of the user with /grant <role>",
+		"role.granted":        `User {{.id}} is now {{t (print "role." .role)}} (previous: {{t (print "role." .prev)}})`,
+		"role.revoke_usage":   "/revoke <user ID>, or reply to a message of the user with /revoke",
+		"role.revoke_self":    "You can not revoke your own role",
+		"role.no_role":        "User {{.id}} has no role",
+		"role.revoked":        `Revoked the {{t (print "role." .role)}} role of user {{.id}}`,
+		"role.empty":          "No user has a role",
+		"role.header":         "===Roles===",
+		"audit.invalid_n":     "The number of entries must be positive",
+		"audit.empty":         "The audit log is empty",
+		"audit.header":        "===Audit log===",
+		"audit.footer":        "/revert <id> to undo a change",
+		"audit.revert_failed": "Failed to revert #{{.id}}: {{.err}}",
+		"audit.reverted":      "Reverted\n{{.entry}}",
+	},
+}
@@ -0,0 +1,302 @@
+// i18n translates the messages of the bot by key, e.g.
+//
+//	l := i18n.Match("zh-hant")
+//	l.T("alert.high", "metric", "cpu", "value", 91.5)
+//
+// Templates are text/template templates of the arguments, with the functions
+//
+//	num x [decimals]  x with thousands separators, 2 decimals by default
+//	pct x             x as a percentage with 2 decimals
+//	plural n forms... the form of the noun for n, e.g. plural .n "alert" "alerts"
+//	dur d             a duration, e.g. 1h30m
+//	clock t, time t   the time of day of t, without and with seconds
+//	date t            the date and time of day of t
+//	t key             the message key, e.g. t (print "metric." .metric)
+//	cap s             s with the first letter in upper case
+//
+// Keys missing in a locale fall back to English. Errors given as arguments are shown in the language of the locale if they are made with Errorf.
+package i18n
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Units are the units of durations in a language.
+type Units struct {
+	Day, Hour, Minute, Second string
+}
+
+// Locale is the messages of a language and how it formats values.
+type Locale struct {
+	Tag      string              // IETF language tag, e.g. "zh-TW"
+	Name     string              // name of the language in itself, e.g. "繁體中文"
+	Messages map[string]string   // templates by key
+	Plural   func(n float64) int // index of the plural form for n, see plural
+	Decimal  string              // decimal separator
+	Group    string              // thousands separator
+	Clock    string              // layout of times of day, e.g. "15:04"
+	Time     string              // layout of times of day with seconds
+	Date     string              // layout of dates with the time of day
+	Units    Units
+
+	templates map[string]*template.Template
+}
+
+var (
+	locales = map[string]*Locale{}
+
+	// Default is the locale of users whose language has no catalog.
+	Default = English
+)
+
+func init() {
+	Register(English)
+	Register(TraditionalChinese)
+}
+
+// Register adds a locale, it panics if a template is invalid.
+func Register(l *Locale) {
+	l.templates = map[string]*template.Template{}
+	for key, text := range l.Messages {
+		tmpl, err := template.New(key).Funcs(l.funcs()).Parse(text)
+		if err != nil {
+			panic(fmt.Sprintf("i18n: message %s of %s: %v", key, l.Tag, err))
+		}
+		l.templates[key] = tmpl
+	}
+	locales[strings.ToLower(l.Tag)] = l
+}
+
+// Locales returns the registered locales sorted by tag.
+func Locales() []*Locale {
+	res := []*Locale{}
+	for _, l := range locales {
+		res = append(res, l)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Tag < res[j].Tag })
+	return res
+}
+
+// Match returns the locale of an IETF language tag like Telegram's language_code, e.g. "zh-hant". A tag without its own locale gets one of the same language, e.g. "en-GB" gets "en", and Default if there is none.
+func Match(tag string) *Locale {
+	tag = strings.ToLower(strings.ReplaceAll(tag, "_", "-"))
+	if l, ok := locales[tag]; ok {
+		return l
+	}
+
+	lang, _, _ := strings.Cut(tag, "-")
+	for _, l := range Locales() {
+		if l.Lang() == lang {
+			return l
+		}
+	}
+	return Default
+}
+
+// Lang returns the ISO 639-1 code of the language, e.g. "zh" for "zh-TW".
+func (l *Locale) Lang() string {
+	lang, _, _ := strings.Cut(strings.ToLower(l.Tag), "-")
+	return lang
+}
+
+// Has checks if the locale has its own message for key, without falling back to English.
+func (l *Locale) Has(key string) bool {
+	_, ok := l.templates[key]
+	return ok
+}
+
+// T returns the message key with the arguments given as name and value pairs, e.g. T("set", "key", "interval", "value", 5).
+func (l *Locale) T(key string, args ...interface{}) string {
+	return l.Format(key, Args(args...))
+}
+
+// Format returns the message key with the arguments args, the key itself if no locale has it.
+func (l *Locale) Format(key string, args map[string]interface{}) string {
+	tmpl, ok := l.templates[key]
+	if !ok {
+		if tmpl, ok = Default.templates[key]; !ok {
+			if tmpl, ok = English.templates[key]; !ok {
+				log.Printf("i18n: no message %s\n", key)
+				return key
+			}
+		}
+	}
+
+	// errors in the language of l, in a copy since the caller may keep args to format them later
+	local := make(map[string]interface{}, len(args))
+	for k, v := range args {
+		if err, ok := v.(error); ok {
+			v = l.Error(err)
+		}
+		local[k] = v
+	}
+
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, local); err != nil {
+		log.Printf("i18n: message %s of %s: %v\n", key, l.Tag, err)
+		return key
+	}
+	return sb.String()
+}
+
+// Error is an error whose message is in the catalogs, it is shown in the language of the user when formatted by a locale.
+type Error struct {
+	Key  string
+	Args []interface{} // name and value pairs like the arguments of T
+}
+
+// Errorf returns an Error with the message key and the arguments given as name and value pairs, e.g. Errorf("rules.not_series", "series", src).
+func Errorf(key string, args ...interface{}) *Error {
+	return &Error{Key: key, Args: args}
+}
+
+// Error returns the message in the Default language.
+func (e *Error) Error() string {
+	return e.Localize(Default)
+}
+
+// Localize returns the message in the language of l.
+func (e *Error) Localize(l *Locale) string {
+	return l.T(e.Key, e.Args...)
+}
+
+// Error returns the message of err in the language of l if err, or an error it wraps, has a Localize method like Error. Other errors keep their message.
+func (l *Locale) Error(err error) string {
+	var e interface{ Localize(*Locale) string }
+	if errors.As(err, &e) {
+		return e.Localize(l)
+	}
+	return err.Error()
+}
+
+// Args returns the name and value pairs of T as a map, e.g. to keep them in an alert and format it later.
+func Args(pairs ...interface{}) map[string]interface{} {
+	args := map[string]interface{}{}
+	for i := 0; i+1 < len(pairs); i += 2 {
+		args[fmt.Sprint(pairs[i])] = pairs[i+1]
+	}
+	return args
+}
+
+// funcs returns the template functions of the locale
+func (l *Locale) funcs() template.FuncMap {
+	return template.FuncMap{
+		"num": func(x interface{}, decimals ...int) string {
+			d := 2
+			if len(decimals) > 0 {
+				d = decimals[0]
+			}
+			return l.Num(float(x), d)
+		},
+		"pct": func(x interface{}) string {
+			return l.Num(float(x), 2) + "%"
+		},
+		"plural": func(n interface{}, forms ...string) string {
+			if len(forms) == 0 {
+				return ""
+			}
+			return forms[min(max(l.Plural(float(n)), 0), len(forms)-1)]
+		},
+		"dur": func(d interface{}) string {
+			return l.Duration(duration(d))
+		},
+		"clock": func(t time.Time) string { return t.Format(l.Clock) },
+		"time":  func(t time.Time) string { return t.Format(l.Time) },
+		"date":  func(t time.Time) string { return t.Format(l.Date) },
+		"t": func(key string) string {
+			return l.Format(key, nil)
+		},
+		"cap": func(s string) string {
+			if s == "" {
+				return s
+			}
+			return strings.ToUpper(s[:1]) + s[1:]
+		},
+	}
+}
+
+// Num formats x with the given number of decimals and the separators of the locale, e.g. 1,234.50.
+func (l *Locale) Num(x float64, decimals int) string {
+	if math.IsNaN(x) || math.IsInf(x, 0) {
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	}
+
+	s := strconv.FormatFloat(math.Abs(x), 'f', decimals, 64)
+	whole, frac, _ := strings.Cut(s, ".")
+
+	var sb strings.Builder
+	if x < 0 && strings.Trim(s, "0.") != "" {
+		sb.WriteString("-")
+	}
+	for i, c := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			sb.WriteString(l.Group)
+		}
+		sb.WriteRune(c)
+	}
+	if frac != "" {
+		sb.WriteString(l.Decimal + frac)
+	}
+	return sb.String()
+}
+
+// Duration formats d rounded to the second in the units of the locale, e.g. 1h30m.
+func (l *Locale) Duration(d time.Duration) string {
+	d = d.Round(time.Second)
+	if d < 0 {
+		return "-" + l.Duration(-d)
+	}
+	if d == 0 {
+		return "0" + l.Units.Second
+	}
+
+	var sb strings.Builder
+	for _, u := range []struct {
+		size time.Duration
+		name string
+	}{
+		{24 * time.Hour, l.Units.Day},
+		{time.Hour, l.Units.Hour},
+		{time.Minute, l.Units.Minute},
+		{time.Second, l.Units.Second},
+	} {
+		if n := d / u.size; n > 0 {
+			sb.WriteString(strconv.FormatInt(int64(n), 10) + u.name)
+			d -= n * u.size
+		}
+	}
+	return sb.String()
+}
+
+// float converts a number argument to float64, arguments kept as JSON are float64 already
+func float(x interface{}) float64 {
+	switch v := x.(type) {
+	case float64:
+		return v
+	case float32:
+		return float64(v)
+	case int:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case time.Duration:
+		return float64(v)
+	}
+	return math.NaN()
+}
+
+// duration converts a duration argument, a time.Duration or a number of nanoseconds once kept as JSON
+func duration(d interface{}) time.Duration {
+	if v, ok := d.(time.Duration); ok {
+		return v
+	}
+	return time.Duration(float(d))
+}
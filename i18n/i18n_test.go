@@ -0,0 +1,134 @@
+package i18n
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMatch(t *testing.T) {
+	for tag, want := range map[string]*Locale{
+		"en":      English,
+		"en-GB":   English,
+		"zh-TW":   TraditionalChinese,
+		"zh_hant": TraditionalChinese,
+		"zh-hans": TraditionalChinese,
+		"fr":      Default,
+		"":        Default,
+	} {
+		if got := Match(tag); got != want {
+			t.Errorf("Match(%q) = %s, want %s", tag, got.Tag, want.Tag)
+		}
+	}
+}
+
+func TestNum(t *testing.T) {
+	for _, c := range []struct {
+		x        float64
+		decimals int
+		want     string
+	}{
+		{0, 2, "0.00"},
+		{91.5, 2, "91.50"},
+		{1234567.891, 2, "1,234,567.89"},
+		{-1234, 0, "-1,234"},
+		{-0.001, 2, "0.00"},
+		{123, 0, "123"},
+	} {
+		if got := English.Num(c.x, c.decimals); got != c.want {
+			t.Errorf("Num(%v, %d) = %q, want %q", c.x, c.decimals, got, c.want)
+		}
+	}
+}
+
+func TestDuration(t *testing.T) {
+	for _, c := range []struct {
+		l    *Locale
+		d    time.Duration
+		want string
+	}{
+		{English, 0, "0s"},
+		{English, 90 * time.Minute, "1h30m"},
+		{English, 26*time.Hour + 1500*time.Millisecond, "1d2h2s"},
+		{TraditionalChinese, 90 * time.Minute, "1小時30分"},
+	} {
+		if got := c.l.Duration(c.d); got != c.want {
+			t.Errorf("%s Duration(%s) = %q, want %q", c.l.Tag, c.d, got, c.want)
+		}
+	}
+}
+
+func TestT(t *testing.T) {
+	for _, c := range []struct {
+		l    *Locale
+		key  string
+		args []interface{}
+		want string
+	}{
+		{English, "alert.high", []interface{}{"metric", "cpu", "value", 91.5}, "High CPU usage detected: 91.50%"},
+		{TraditionalChinese, "alert.high", []interface{}{"metric", "mem", "value", 91.5}, "偵測到記憶體使用率過高：91.50%"},
+		{English, "alerts", []interface{}{"n", 1}, "1 alert:"},
+		{English, "alerts", []interface{}{"n", 3}, "3 alerts:"},
+		{TraditionalChinese, "alerts", []interface{}{"n", 3}, "3 則警報："},
+		// arguments kept as JSON
+		{English, "alert.rule_flapping", []interface{}{"rule", "r", "changes", float64(1), "window", float64(time.Hour)}, "Rule r is flapping: 1 state change in 1h, further alerts are suppressed until it settles"},
+		{English, "no.such.key", nil, "no.such.key"},
+	} {
+		if got := c.l.T(c.key, c.args...); got != c.want {
+			t.Errorf("%s T(%s) = %q, want %q", c.l.Tag, c.key, got, c.want)
+		}
+	}
+}
+
+func TestError(t *testing.T) {
+	err := Errorf("rules.not_series", "series", "cpu > 1")
+	if err.Error() != "cpu > 1 is not a series" {
+		t.Errorf("Error() = %q, want the Default message", err.Error())
+	}
+	if got := TraditionalChinese.Error(fmt.Errorf("check: %w", err)); got != "cpu > 1 不是序列" {
+		t.Errorf("wrapped error = %q, want the message of the locale", got)
+	}
+	if got := TraditionalChinese.Error(errors.New("boom")); got != "boom" {
+		t.Errorf("plain error = %q", got)
+	}
+
+	args := Args("err", err)
+	if got := TraditionalChinese.Format("rules.invalid", args); got != "無效的規則：cpu > 1 不是序列" {
+		t.Errorf("error argument = %q, want it in the language of the locale", got)
+	}
+	if args["err"] != err {
+		t.Error("Format should not change the arguments")
+	}
+}
+
+func TestFallback(t *testing.T) {
+	l := &Locale{Tag: "xx", Messages: map[string]string{"saved": "ok"}, Plural: English.Plural}
+	Register(l)
+	defer delete(locales, "xx")
+
+	if got := l.T("saved"); got != "ok" {
+		t.Errorf("T(saved) = %q, want ok", got)
+	}
+	if got := l.T("form.cancelled"); got != "Cancelled" {
+		t.Errorf("missing message = %q, want the English one", got)
+	}
+	if !l.Has("saved") || l.Has("form.cancelled") {
+		t.Error("Has should only report the messages of the locale")
+	}
+}
+
+// the shipped catalogs have the same messages, except the command descriptions English has in the commands themselves
+func TestCatalogs(t *testing.T) {
+	for key := range TraditionalChinese.Messages {
+		if _, ok := English.Messages[key]; !ok && !strings.HasPrefix(key, "cmd.") {
+			t.Errorf("zh-TW has message %s, English does not", key)
+		}
+	}
+	for key := range English.Messages {
+		if _, ok := TraditionalChinese.Messages[key]; !ok {
+			t.Errorf("English has message %s, zh-TW does not", key)
+		}
+	}
+}
@@ -0,0 +1,252 @@
+package i18n
+
+// TraditionalChinese is the catalog of Traditional Chinese as used in Taiwan.
+var TraditionalChinese = &Locale{
+	Tag:     "zh-TW",
+	Name:    "繁體中文",
+	Plural:  func(n float64) int { return 0 },
+	Decimal: ".",
+	Group:   ",",
+	Clock:   "15:04",
+	Time:    "15:04:05",
+	Date:    "2006/01/02 15:04",
+	Units:   Units{Day: "天", Hour: "小時", Minute: "分", Second: "秒"},
+	Messages: map[string]string{
+		// shared
+		"not_subscribed": "尚未訂閱，請先 /subscribe",
+		"saved":          "已儲存",
+		"more":           "……還有 {{.n}} 則",
+		"metric.cpu":     "CPU",
+		"metric.mem":     "記憶體",
+
+		"severity.info":     "資訊",
+		"severity.warning":  "警告",
+		"severity.critical": "嚴重",
+
+		"role.guest":    "訪客",
+		"role.viewer":   "檢視者",
+		"role.operator": "操作員",
+		"role.admin":    "管理員",
+
+		"scope.private": "私人聊天",
+		"scope.groups":  "群組",
+		"scope.any":     "任何聊天",
+		"scope.none":    "沒有聊天",
+
+		// command menu
+		"cmd.help":        "顯示指令列表或指令用法",
+		"cmd.subscribe":   "訂閱通知",
+		"cmd.unsubscribe": "取消訂閱通知",
+		"cmd.status":      "查看伺服器狀態",
+		"cmd.set":         "設定設定值",
+		"cmd.reset":       "將設定值恢復預設",
+		"cmd.config":      "查看所有設定值",
+		"cmd.plot":        "繪製資源使用量圖表",
+		"cmd.dashboard":   "顯示即時更新的數值",
+		"cmd.rules":       "列出警報規則",
+		"cmd.rule":        "新增、刪除或測試警報規則",
+		"cmd.mute":        "暫時靜音規則或指標的警報",
+		"cmd.unmute":      "結束靜音",
+		"cmd.silences":    "列出生效中的靜音",
+		"cmd.secondary":   "訂閱升級的警報",
+		"cmd.prefs":       "設定要接收哪些警報及時間",
+		"cmd.group":       "查看或變更群組的討論串與主機類別",
+		"cmd.digest":      "以定期摘要接收低嚴重性警報",
+		"cmd.grant":       "授予使用者角色",
+		"cmd.revoke":      "移除使用者的角色",
+		"cmd.roles":       "列出有角色的使用者",
+		"cmd.audit":       "查看最近的變更",
+		"cmd.revert":      "還原稽核紀錄中的變更",
+		"cmd.history":     "查看歷史紀錄",
+		"cmd.lang":        "查看或設定機器人的語言",
+
+		// bot
+		"bot.started":            "機器人已啟動",
+		"bot.stopping":           "機器人即將停止",
+		"subscribe.done":         "已訂閱通知",
+		"subscribe.already":      "已經訂閱過了",
+		"unsubscribe.done":       "已取消訂閱通知",
+		"unsubscribe.not":        "尚未訂閱",
+		"error.panic":            "發生錯誤，請稍後再試",
+		"error.rate_limit":       "請求過多，每 {{dur .interval}} 最多 {{.n}} 次，請放慢速度",
+		"error.rate_limit_toast": "請求過多",
+		"denied":                 `權限不足：需要{{t (print "role." .role)}}角色，你目前是{{t (print "role." .have)}}（使用者 ID {{.id}}）`,
+
+		// commands
+		"help.header":         "可用的指令：\n/help [指令] - 顯示此訊息或指令的用法",
+		"help.guest":          "請向管理員申請角色以使用其他指令",
+		"cmd.scope":           `/{{.cmd}} 只能在{{t (print "scope." .scope)}}中使用`,
+		"cmd.usage":           "{{.err}}\n\n用法：{{.usage}}",
+		"cmd.unknown":         "未知的指令 /{{.cmd}}。",
+		"cmd.invalid_arg":     "無效的 {{.name}}「{{.value}}」。",
+		"cmd.did_you_mean":    `你是不是要找 {{range $i, $c := .cmds}}{{if $i}} 或 {{end}}{{$c}}{{end}}？`,
+		"button.unknown":      "未知的按鈕",
+		"button.expired":      "此按鈕已過期",
+		"button.invalid":      "無效的按鈕",
+		"form.timeout":        "太久沒有回答，已取消",
+		"form.optional":       "{{if .def}}（輸入 - 使用 {{.def}}）{{else}}（輸入 - 略過）{{end}}",
+		"form.gone":           "這個問題已經不再詢問",
+		"form.not_yours":      "這個問題是問其他人的",
+		"form.cancelled":      "已取消",
+		"form.nothing":        "沒有可取消的操作",
+		"args.invalid":        "無效的 {{.name}}「{{.value}}」：{{.reason}}",
+		"args.missing_arg":    "{{.name}}：{{.reason}}",
+		"args.missing":        "缺少此參數",
+		"args.missing_value":  "缺少值",
+		"args.not_kind":       "不是{{.kind}}",
+		"args.choose":         "請從 {{.choices}} 中選擇",
+		"args.unknown_flag":   "未知的選項 --{{.flag}}",
+		"args.too_many":       "參數過多：{{.args}}",
+		"kind.string":         "單字",
+		"kind.text":           "文字",
+		"kind.int":            "整數",
+		"kind.float":          "數字",
+		"kind.duration":       "時間長度，例如 30m 或 2h",
+		"kind.enum":           "選項",
+		"kind.bool":           "true 或 false",
+		"lang.show":           "語言：{{.name}}{{if .auto}}，跟隨你的 Telegram 應用程式{{end}}\n\n可用的語言：\n{{range .langs}}{{.}}\n{{end}}auto - 跟隨你的 Telegram 應用程式\n\n/lang <語言>",
+		"lang.set":            "語言已設為{{.name}}{{if .auto}}，跟隨你的 Telegram 應用程式{{end}}",
+		"hi.ask":              "你叫什麼名字？",
+		"hi.hello":            "你好，{{.name}}",
+		"inline.denied":       "權限不足，點此查看說明",
+		"failures.none":       "沒有傳送失敗的訊息",
+		"failures.header":     "===傳送失敗===",
+		"secondary.on":        "已訂閱升級的警報",
+		"secondary.off":       "已取消訂閱升級的警報",
+		"group.settings":      "===群組設定===\n討論串：{{if .topic}}{{.topic}}{{else}}一般{{end}}\n主機類別：{{or .classes \"全部\"}}\n\n{{.prefs}}",
+		"group.invalid_topic": "無效的討論串「{{.value}}」，請使用討論串連結中聊天之後的數字，或 off",
+
+		// status and plots
+		"status":                   "=====目前數值=====\nCPU：{{pct .cpu}}\n記憶體：{{pct .mem}}\n\n=====平均=====\nCPU：{{pct .cpu_avg}}（±{{num .cpu_stddev}}）\n記憶體：{{pct .mem_avg}}（±{{num .mem_stddev}}）",
+		"status.cpu_error":         "無法取得 CPU 使用率",
+		"status.mem_error":         "無法取得記憶體使用率",
+		"config.header":            "===設定===",
+		"config.set":               "已將 {{.key}} 設為 {{.value}}（原本：{{.prev}}）",
+		"config.reset":             "已將 {{.key}} 恢復為 {{.value}}（原本：{{.prev}}）",
+		"config.invalid":           "無效的設定：{{.key}}",
+		"config.convert":           "無法將「{{.value}}」轉換為 {{.type}}",
+		"plot.no_series":           "沒有名為 {{.metric}} 的序列",
+		"plot.error":               "繪圖失敗",
+		"plot.invalid_range":       "無效的範圍 {{.range}}",
+		"plotbtn.text":             "點擊以繪圖",
+		"plotbtn.button":           "繪圖",
+		"dashboard.header":         "===儀表板===",
+		"dashboard.firing":         "===觸發中的警報===",
+		"dashboard.none":           "無",
+		"dashboard.acked":          "（{{.by}} 已確認）",
+		"dashboard.updated":        "更新於 {{time .now}}，持續到 {{clock .until}}",
+		"dashboard.error":          "無法啟動儀表板：{{.err}}",
+		"dashboard.stop":           "停止",
+		"dashboard.stopped":        "已於 {{clock .at}} 停止",
+		"dashboard.gone":           "此儀表板已不再執行",
+		"dashboard.stopping":       "儀表板已停止",
+		"dashboard.interval":       "更新間隔至少要 {{dur .min}}",
+		"dashboard.lifetime":       "持續時間必須介於 0 與 {{dur .max}} 之間",
+		"add.progress":             "已新增 {{.i}}",
+		"add.done":                 "完成",
+		"inline.value":             "{{.key}}：{{.value}}，於 {{date .now}}",
+		"inline.value_description": "目前數值",
+		"inline.stats":             "平均 {{pct .avg}}（±{{num .stddev}}），最低 {{pct .min}}，最高 {{pct .max}}",
+		"inline.over":              "{{.key}} 過去 {{dur .range}}",
+		"inline.over_until":        "{{.key}} 過去 {{dur .range}}，至 {{date .now}}",
+		"inline.plot":              "{{.key}} 過去 {{dur .range}} 的圖表",
+
+		// alerts
+		"alert.high":              `偵測到{{t (print "metric." .metric)}}使用率過高：{{pct .value}}`,
+		"alert.normal":            `{{t (print "metric." .metric)}}使用率恢復正常：{{pct .value}}`,
+		"alert.critical":          `{{t (print "metric." .metric)}}使用率已達危險程度：{{pct .value}}`,
+		"alert.flapping":          `{{t (print "metric." .metric)}}使用率在 {{pct .threshold}} 附近反覆變動：{{dur .window}}內狀態變化 {{num .changes 0}} 次（最低 {{pct .min}}，最高 {{pct .max}}），穩定前不再發送警報`,
+		"alert.flap_stopped":      `{{t (print "metric." .metric)}}使用率已停止反覆變動，目前{{if .firing}}過高{{else}}正常{{end}}：{{pct .value}}（略過了 {{num .suppressed 0}} 次變化）`,
+		"alert.increase":          `偵測到{{t (print "metric." .metric)}}使用率突然上升：{{pct .value}}（z = {{num .z}}）`,
+		"alert.rule_firing":       "規則 {{.rule}} 觸發：{{.expr}}{{range .terms}}\n{{.}}{{end}}",
+		"alert.rule_resolved":     "規則 {{.rule}} 已解除：{{.expr}}{{range .terms}}\n{{.}}{{end}}",
+		"alert.rule_flapping":     "規則 {{.rule}} 反覆變動：{{dur .window}}內狀態變化 {{num .changes 0}} 次，穩定前不再發送警報",
+		"alert.rule_flap_stopped": "規則 {{.rule}} 已停止反覆變動，目前{{if .firing}}觸發中{{else}}已解除{{end}}（略過了 {{num .suppressed 0}} 次變化）",
+		"alerts":                  "{{.n}} 則警報：",
+		"alert.ack":               "確認",
+		"alert.mute":              "靜音 1 小時",
+		"alert.plot":              "顯示圖表",
+		"alert.ack_id":            "確認 #{{.id}}",
+		"alert.mute_id":           "靜音 #{{.id}} 1 小時",
+		"alert.plot_id":           "圖表 #{{.id}}",
+		"alert.reminder":          "提醒，已 {{dur .for}} 未確認：\n{{.text}}",
+		"alert.escalated":         "已升級，{{dur .for}} 未確認：\n{{.text}}",
+		"alert.gone":              "警報 #{{.id}} 已不存在",
+		"alert.acked":             "警報 #{{.id}} 已由 {{.by}} 確認",
+		"alert.acked_already":     "警報 #{{.id}} 已經由 {{.by}} 確認過了",
+		"digest":                  "摘要：自 {{clock .since}} 起共 {{.n}} 則警報",
+		"digest.show":             "{{if .every}}低嚴重性警報每 {{dur .every}} 以摘要發送{{else}}摘要模式已關閉{{end}}\n/digest <分鐘|off>",
+		"digest.on":               "低嚴重性警報將每 {{.n}} 分鐘以摘要發送，警告與嚴重警報仍會立即發送",
+		"digest.off":              "摘要模式已關閉，所有警報都會立即發送",
+		"digest.invalid":          "無效的參數「{{.arg}}」\n/digest <分鐘|off>",
+
+		// rules
+		"rules.none":           "沒有規則，使用 /rule add <名稱> <運算式> 新增",
+		"rules.header":         "===規則===",
+		"rules.ok":             "正常",
+		"rules.firing":         "觸發中",
+		"rules.flapping":       "反覆變動",
+		"rules.invalid":        "無效的規則：{{.err}}",
+		"rules.added":          "已新增規則 {{.name}}：{{.expr}}",
+		"rules.added_severity": "已新增{{.severity}}規則 {{.name}}：{{.expr}}",
+		"rules.deleted":        "已刪除規則 {{.name}}",
+		"rules.no_rule":        "沒有名為 {{.name}} 的規則",
+		"rules.severity":       `規則 {{.name}} 現在發出{{t (print "severity." .severity)}}警報`,
+		"rules.usage":          "/rule new（引導設定）\n/rule add <名稱> <運算式>\n/rule del <名稱>\n/rule severity <名稱> <info|warning|critical>\n/rule test <名稱或運算式>\n\n範例：/rule add high_cpu avg(cpu, 5m) > 80 && mem > 90\n函式：avg、min、max、stddev、rate、forecast（序列, 時間長度）",
+		"rules.bad_expr":       "無效的運算式：{{.err}}",
+		"rules.error":          "錯誤：{{.err}}",
+		"rules.result":         `結果：{{printf "%g" .value}}，規則{{if .fire}}會{{else}}不會{{end}}觸發`,
+		"rules.not_series":     "{{.series}} 不是序列",
+		"rules.ask_name":       "規則名稱，使用小寫字母、數字、_ 與 -，例如 high_cpu",
+		"rules.bad_name":       "無效的規則名稱「{{.name}}」，請使用小寫字母、數字、「_」與「-」",
+		"rules.ask_kind":       "規則類型：比較序列與門檻（threshold），或撰寫運算式（expression）",
+		"rules.ask_series":     `要監看的序列，例如 cpu、mem、net_rx 或 disk{mount="/"}`,
+		"rules.ask_function":   "要比較的值：最新的樣本（last），或一段時間內的函式值",
+		"rules.ask_window":     "函式的時間範圍，例如 5m（forecast 則是預測多久以後）",
+		"rules.bad_window":     "時間範圍必須大於零",
+		"rules.ask_op":         "數值高於（>）或低於（<）門檻時觸發",
+		"rules.ask_value":      "門檻，例如 80",
+		"rules.ask_expr":       "運算式，例如 avg(cpu, 5m) > 80 && mem > 90\n函式：avg、min、max、stddev、rate、forecast（序列, 時間長度）",
+		"rules.ask_severity":   "警報的嚴重性",
+
+		// silences
+		"silence":                  "#{{.id}} {{.matcher}} 直到 {{date .until}}，由 {{.by}} 設定{{if .reason}}：{{.reason}}{{end}}",
+		"silence.muted":            "已靜音 {{.silence}}",
+		"silence.ended":            "靜音結束：{{.silence}}",
+		"silence.none_suppressed":  "沒有警報被略過",
+		"silence.suppressed":       "略過了 {{.n}} 則警報：",
+		"silence.invalid_duration": "無效的時長「{{.d}}」，必須大於零",
+		"silence.no_match":         "沒有符合 {{.arg}} 的靜音",
+		"silence.unmuted":          "已結束 {{.n}} 個靜音：{{range .silences}}\n{{.}}{{end}}",
+		"silence.none":             "沒有生效中的靜音",
+		"silence.header":           "===靜音===",
+		"silence.item":             "{{.silence}}（略過 {{.n}} 則）",
+
+		// preferences
+		"prefs":                  "最低嚴重性：{{t (print \"severity.\" .severity)}}\n指標：{{or .metrics \"全部\"}}\n勿擾時段：{{or .quiet \"關閉\"}}",
+		"prefs.show":             "===偏好設定===\n{{.prefs}}\n\n回答 - 保留原值，/cancel 停止",
+		"prefs.ask_severity":     "最低嚴重性",
+		"prefs.ask_metrics":      "要接收警報的規則或指標，以逗號分隔，例如 cpu, high_disk，或 * 表示全部",
+		"prefs.ask_quiet":        "勿擾時段，期間只發送嚴重警報，例如 22:00-07:00 或 off",
+		"prefs.ask_timezone":     "勿擾時段的時區，例如 Asia/Taipei",
+		"prefs.invalid_quiet":    "無效的勿擾時段「{{.value}}」，請使用 hh:mm-hh:mm 或 off",
+		"prefs.invalid_timezone": "未知的時區「{{.value}}」",
+		"prefs.unsubscribed":     "已不再訂閱，偏好設定未儲存",
+
+		// roles and audit log
+		"role.grant_usage":    "/grant <使用者 ID> <viewer|operator|admin>，或回覆該使用者的訊息 /grant <角色>",
+		"role.granted":        `使用者 {{.id}} 現在是{{t (print "role." .role)}}（原本：{{t (print "role." .prev)}}）`,
+		"role.revoke_usage":   "/revoke <使用者 ID>，或回覆該使用者的訊息 /revoke",
+		"role.revoke_self":    "你不能移除自己的角色",
+		"role.no_role":        "使用者 {{.id}} 沒有角色",
+		"role.revoked":        `已移除使用者 {{.id}} 的{{t (print "role." .role)}}角色`,
+		"role.empty":          "沒有使用者有角色",
+		"role.header":         "===角色===",
+		"audit.invalid_n":     "筆數必須大於零",
+		"audit.empty":         "稽核紀錄是空的",
+		"audit.header":        "===稽核紀錄===",
+		"audit.footer":        "/revert <id> 以還原變更",
+		"audit.revert_failed": "無法還原 #{{.id}}：{{.err}}",
+		"audit.reverted":      "已還原\n{{.entry}}",
+	},
+}
@@ -2,15 +2,25 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"log"
 	"math"
+	"monitor/alert"
 	mybot "monitor/bot"
+	"monitor/collector"
 	cfg "monitor/config"
 	"monitor/history"
+	"monitor/i18n"
+	"monitor/notify"
+	"monitor/rules"
 	"os"
+	"os/signal"
+	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
@@ -18,11 +28,33 @@ import (
 	"github.com/shirou/gopsutil/mem"
 )
 
-var telegramBotToken = os.Getenv("TG_BOT_TOKEN")
+var (
+	telegramBotToken = os.Getenv("TG_BOT_TOKEN")
+	notifyConfig     = os.Getenv("NOTIFY_CONFIG")  // path of the notifiers and routes config, see notify.Config
+	dataDir          = os.Getenv("DATA_DIR")       // directory of the persistent state, "data" if empty
+	botAdmins        = os.Getenv("BOT_ADMINS")     // comma separated Telegram user IDs that are always admins
+	webhookURL       = os.Getenv("WEBHOOK_URL")    // public URL to receive updates by webhook, long polling if empty
+	webhookListen    = os.Getenv("WEBHOOK_LISTEN") // address of the webhook server, ":8080" if empty
+	webhookSecret    = os.Getenv("WEBHOOK_SECRET") // secret token of the webhook, random if empty
+	webhookCert      = os.Getenv("WEBHOOK_CERT")   // certificate and key to serve the webhook over HTTPS, plain HTTP if empty
+	webhookKey       = os.Getenv("WEBHOOK_KEY")
+	hostClass        = os.Getenv("HOST_CLASS") // class of this host set on its alerts, e.g. "db", so that groups can pick the alerts they get
+	inlinePlotChat   int64                     // chat to upload the plots of inline queries to, from INLINE_PLOT_CHAT, no plots if 0
+)
 
 var config = cfg.New().Float64("cpu_threshold", "CPU threshold", 75.0).
+	Float64("cpu_critical_threshold", "CPU usage from which a CPU alert is critical", 90.0).
+	Float64("cpu_clear_threshold", "CPU usage under which a CPU alert clears", 70.0).
 	Float64("mem_threshold", "Memory threshold", 85.0).
+	Float64("mem_critical_threshold", "Memory usage from which a memory alert is critical", 95.0).
+	Float64("mem_clear_threshold", "Memory usage under which a memory alert clears", 80.0).
 	Float64("increase_threshold", "Increase threshold (in how many standard deviation)", 2.0).
+	Int("min_samples", "Consecutive breaching samples before alerting", 2).
+	Int("flap_window", "Flap detection window (in minutes)", 30).
+	Int("flap_count", "State changes in flap window that count as flapping (0 to disable)", 4).
+	Int("reminder_interval", "Resend unacknowledged alerts every (in minutes, 0 to disable)", 15).
+	Int("escalate_after", "Escalate unacknowledged alerts to secondary subscribers after (in minutes, 0 to disable)", 30).
+	Int("stop_notice", "Tell the subscribers when the bot stops (0 to disable)", 1).
 	Int("interval", "Interval", 1)
 
 var (
@@ -30,6 +62,20 @@ var (
 	memUsageHistory = history.New(30*time.Minute, "Memory") // History of memory usage percentages
 )
 
+var collectors = collector.New(30*time.Minute).
+	Track("cpu", nil, cpuUsageHistory).
+	Track("mem", nil, memUsageHistory).
+	Register(collector.CPU, collector.Memory, collector.Network, collector.Disk)
+
+var ruleSet = rules.NewSet(collectors)
+
+var notifier = notify.NewRouter()
+
+var (
+	cpuAlert = alert.NewTracker("CPU")
+	memAlert = alert.NewTracker("Memory")
+)
+
 var avgInterval = 10 * time.Minute
 
 func main() {
@@ -38,117 +84,192 @@ func main() {
 		log.Fatal(err)
 	}
 
+	if dataDir == "" {
+		dataDir = "data"
+	}
+	if err := os.MkdirAll(dataDir, 0o755); err != nil {
+		log.Fatal(err)
+	}
+
+	if err := bot.UseStore(mybot.NewJSONStore(filepath.Join(dataDir, "subscribers.json"))); err != nil {
+		log.Fatal(err)
+	}
+
+	if err := bot.PersistRoles(filepath.Join(dataDir, "roles.json")); err != nil {
+		log.Fatal(err)
+	}
+	admins, err := mybot.ParseAdmins(botAdmins)
+	if err != nil {
+		log.Fatalf("BOT_ADMINS: %v", err)
+	}
+	for _, id := range admins {
+		bot.Grant(id, mybot.Admin)
+	}
+
+	if err := bot.PersistAudit(filepath.Join(dataDir, "audit.log")); err != nil {
+		log.Fatal(err)
+	}
+
+	if err := bot.PersistQueue(filepath.Join(dataDir, "outbox.json")); err != nil {
+		log.Fatal(err)
+	}
+
+	if webhookURL != "" {
+		err := bot.UseWebhook(mybot.Webhook{
+			URL:      webhookURL,
+			Listen:   webhookListen,
+			Secret:   webhookSecret,
+			CertFile: webhookCert,
+			KeyFile:  webhookKey,
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if id := os.Getenv("INLINE_PLOT_CHAT"); id != "" {
+		if inlinePlotChat, err = strconv.ParseInt(id, 10, 64); err != nil {
+			log.Fatalf("INLINE_PLOT_CHAT: %v", err)
+		}
+	}
+
+	bot.Use(mybot.Recover, mybot.Logger, mybot.RateLimit(20, time.Minute))
 	registerCmdsAndBtn(bot)
 
+	notifier.Add("telegram", notify.NewTelegram(bot))
+	if notifyConfig != "" {
+		if err := notifier.Load(notifyConfig); err != nil {
+			log.Fatal(err)
+		}
+	}
+
 	// bot.Debug = true
 
-	bot.Boradcast("Bot started")
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	bot.BroadcastT("bot.started")
 	fmt.Println("Bot started")
 
-	go bot.HandleUpdates()
+	handled := make(chan struct{})
+	go func() {
+		defer close(handled)
+		if err := bot.HandleUpdates(ctx); err != nil {
+			log.Fatal(err)
+		}
+	}()
+
+	for ctx.Err() == nil {
+		checkAndNotify(ctx, bot)
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(time.Duration(config.GetInt("interval")) * time.Minute): // Check every 5 minutes
+		}
+	}
+
+	shutdown(bot, handled)
+}
+
+// shutdown waits for the handlers in progress, then sends the queued messages and saves the state
+func shutdown(bot *mybot.Bot, handled <-chan struct{}) {
+	fmt.Println("Bot stopping")
+	<-handled
+
+	if config.GetInt("stop_notice") != 0 {
+		bot.BroadcastT("bot.stopping")
+	}
 
-	for {
-		checkAndNotify(bot)
-		time.Sleep(time.Duration(config.GetInt("interval")) * time.Minute) // Check every 5 minutes
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := bot.Shutdown(ctx); err != nil {
+		log.Printf("shutdown: %v, unsent messages are kept for the next start\n", err)
 	}
 }
 
 func registerCmdsAndBtn(bot *mybot.Bot) {
-	bot.AddCmd("subscribe", "Subscribe notifications", false, func(b *mybot.Bot, u tgbotapi.Update) {
+	bot.AddCmd("subscribe", "Subscribe notifications", false, func(b *mybot.Bot, u tgbotapi.Update, _ mybot.Args) {
 		if b.IsSubscribed(u.Message.Chat.ID) {
-			b.SendMsg(u.Message.Chat.ID, "Already subscribed")
+			b.Reply(u, "subscribe.already")
 		} else {
 			b.Subscribe(u.Message.Chat.ID)
-			b.SendMsg(u.Message.Chat.ID, "Subscribed to notifications")
+			b.Reply(u, "subscribe.done")
 		}
 	})
 
-	bot.AddCmd("unsubscribe", "Unsubscribe notifications", false, func(b *mybot.Bot, u tgbotapi.Update) {
+	bot.AddCmd("unsubscribe", "Unsubscribe notifications", false, func(b *mybot.Bot, u tgbotapi.Update, _ mybot.Args) {
 		if b.IsSubscribed(u.Message.Chat.ID) {
 			b.Unsubscribe(u.Message.Chat.ID)
-			b.SendMsg(u.Message.Chat.ID, "Unsubscribed from notifications")
+			b.Reply(u, "unsubscribe.done")
 		} else {
-			b.SendMsg(u.Message.Chat.ID, "Not subscribed")
+			b.Reply(u, "unsubscribe.not")
 		}
 	})
 
-	bot.AddCmd("status", "Get server status", false, func(b *mybot.Bot, u tgbotapi.Update) {
-		chatID := u.Message.Chat.ID
+	bot.AddCmd("status", "Get server status", false, func(b *mybot.Bot, u tgbotapi.Update, _ mybot.Args) {
 		cpuPercent, err := cpu.Percent(time.Second, false)
 		if err != nil {
-			bot.SendMsg(chatID, "Error getting CPU usage")
+			b.Reply(u, "status.cpu_error")
 			return
 		}
 		memPercent, err := mem.VirtualMemory()
 		if err != nil {
-			bot.SendMsg(chatID, "Error getting memory usage")
+			b.Reply(u, "status.mem_error")
 			return
 		}
 
-		cpuAvg := cpuUsageHistory.Average(avgInterval)
-		memAvg := memUsageHistory.Average(avgInterval)
-		cpuStddev := cpuUsageHistory.StdDev(avgInterval)
-		memStddev := memUsageHistory.StdDev(avgInterval)
-
-		bot.SendMsg(chatID, fmt.Sprintf(`===Current Value===
-CPU: %.2f%%
-Memory: %.2f%%
-
-=====Average=====
-CPU: %.2f%% (±%.2f)
-Memory: %.2f%% (±%.2f)`,
-			cpuPercent[0],
-			memPercent.UsedPercent,
-			cpuAvg, cpuStddev,
-			memAvg, memStddev,
-		))
+		b.Reply(u, "status",
+			"cpu", cpuPercent[0],
+			"mem", memPercent.UsedPercent,
+			"cpu_avg", cpuUsageHistory.Average(avgInterval),
+			"cpu_stddev", cpuUsageHistory.StdDev(avgInterval),
+			"mem_avg", memUsageHistory.Average(avgInterval),
+			"mem_stddev", memUsageHistory.StdDev(avgInterval),
+		)
 	})
 
-	bot.AddCmd("set", "Set config value", false, config.CmdSet)
+	bot.AddCmd("set", "Set config value", false, config.CmdSet).
+		Args(mybot.EnumArg("key", "config key, see /config", config.Keys()...), mybot.StringArg("value", "new value")).
+		Require(mybot.Admin)
 
-	bot.AddCmd("config", "Get all config values", false, func(b *mybot.Bot, u tgbotapi.Update) {
-		b.SendMsg(u.Message.Chat.ID, config.All())
-	})
+	bot.AddCmd("reset", "Reset config value to its default", false, config.CmdReset).
+		Args(mybot.EnumArg("key", "config key, see /config", config.Keys()...)).
+		Require(mybot.Admin)
 
-	bot.AddCmd("plot", "Plot resource usage", false, func(b *mybot.Bot, u tgbotapi.Update) {
-		plot(b, u.Message.Chat.ID)
+	bot.AddCmd("config", "Get all config values", false, func(b *mybot.Bot, u tgbotapi.Update, _ mybot.Args) {
+		b.SendMsg(u.Message.Chat.ID, config.All(b.Locale(u)))
 	})
 
-	bot.AddCmd("add", "Manualy add data point (for debug)", true, func(b *mybot.Bot, u tgbotapi.Update) {
-		seg := strings.Split(u.Message.Text, " ")
-		n := 1
-		if len(seg) > 1 {
-			var err error
-			n, err = strconv.Atoi(seg[1])
-			if err != nil {
-				b.SendMsg(u.Message.Chat.ID, "Invalid argument")
-				return
-			}
-		}
+	bot.AddCmd("plot", "Plot resource usage", false, func(b *mybot.Bot, u tgbotapi.Update, args mybot.Args) {
+		plot(b, b.Locale(u), u.Message.Chat.ID, args.String("metric"), args.Duration("range"))
+	}).Args(
+		mybot.StringArg("metric", "series to plot, e.g. cpu or net_rx, * for CPU and memory").Default("*"),
+		mybot.DurationArg("range", "time range, e.g. 1h, all data if not given").Opt(),
+	)
+
+	bot.AddCmd("add", "Manualy add data point (for debug)", true, func(b *mybot.Bot, u tgbotapi.Update, args mybot.Args) {
+		n := args.Int("n")
 		for i := 0; i < n; i++ {
-			checkAndNotify(bot)
-			b.SendMsg(u.Message.Chat.ID, fmt.Sprintf("add %d", i))
+			checkAndNotify(context.Background(), bot)
+			b.Reply(u, "add.progress", "i", i)
 			time.Sleep(1 * time.Second)
 		}
 
-		b.SendMsg(u.Message.Chat.ID, "Done")
-	})
-
-	plotBtn := tgbotapi.NewInlineKeyboardMarkup(
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("Plot", "plot"),
-		),
-	)
+		b.Reply(u, "add.done")
+	}).Args(mybot.IntArg("n", "number of data points").Default("1")).Require(mybot.Admin).In(mybot.InPrivate)
 
-	bot.AddCmd("plotbtn", "Send a message with a button to exec plot command", true, func(b *mybot.Bot, u tgbotapi.Update) {
+	bot.AddCmd("plotbtn", "Send a message with a button to exec plot command", true, func(b *mybot.Bot, u tgbotapi.Update, _ mybot.Args) {
 		// unpin all
 		unpinConfig := tgbotapi.UnpinAllChatMessagesConfig{
 			ChatID: u.Message.Chat.ID,
 		}
 		bot.Request(unpinConfig)
 
-		msg := tgbotapi.NewMessage(u.Message.Chat.ID, "Click to plot")
-		msg.ReplyMarkup = plotBtn
+		l := b.ChatLocale(u.Message.Chat.ID)
+		msg := tgbotapi.NewMessage(u.Message.Chat.ID, l.T("plotbtn.text"))
+		msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
+			tgbotapi.NewInlineKeyboardRow(b.NewButton(l.T("plotbtn.button"), "plot", "*", "30m")),
+		)
 		m, err := b.Send(msg)
 
 		if err == nil {
@@ -160,99 +281,369 @@ Memory: %.2f%% (±%.2f)`,
 			}
 			b.Request(pin)
 		}
-	})
+	}).Require(mybot.Operator)
 
-	bot.AddButton("plot", func(b *mybot.Bot, u tgbotapi.Update) {
-		plot(b, u.CallbackQuery.Message.Chat.ID)
-	})
+	bot.Inline(inlineResults)
 
-	bot.AddCmd("hi", "Example command for waiting", true, func(b *mybot.Bot, u tgbotapi.Update) {
-		forceReply := tgbotapi.ForceReply{
-			ForceReply:            true,
-			InputFieldPlaceholder: "What's your name?",
+	bot.AddCmd("dashboard", "Show live values in a message updated in place", false, func(b *mybot.Bot, u tgbotapi.Update, args mybot.Args) {
+		until := time.Now().Add(args.Duration("lifetime"))
+		// the dashboard is shared by the chat, it is in the language of the chat rather than of the sender
+		l := b.ChatLocale(u.Message.Chat.ID)
+		err := b.Dashboard(u.Message.Chat.ID, func() string { return dashboard(b, l, until) }, args.Duration("interval"), args.Duration("lifetime"), args.Bool("pin"))
+		if err != nil {
+			b.Reply(u, "dashboard.error", "err", err)
 		}
-		msg := tgbotapi.NewMessage(u.Message.Chat.ID, "What's your name?")
-		msg.ReplyMarkup = forceReply
-		b.Send(msg)
-		b.Wait(u.Message.Chat.ID, "name", func(b *mybot.Bot, u tgbotapi.Update) {
-			b.SendMsg(u.Message.Chat.ID, "Hello, "+u.Message.Text)
-		})
-	})
-
-	bot.AddCmd("cancel", "Cancel waiting", true, func(b *mybot.Bot, u tgbotapi.Update) {
-		b.Cancel(u.Message.Chat.ID)
-		b.SendMsg(u.Message.Chat.ID, "Cancelled")
-	})
-
-	bot.AddCmd("menu", "set commands menu", true, setMenu)
+	}).Args(
+		mybot.DurationArg("interval", "how often to update, at least 10s").Default("1m"),
+		mybot.DurationArg("lifetime", "how long to keep updating, at most 24h").Default("1h"),
+		mybot.BoolFlag("pin", "pin the message while it is updated"),
+	)
 
-	bot.AddCmd("history", "Show history", false, func(b *mybot.Bot, u tgbotapi.Update) {
-		seg := strings.Split(u.Message.Text, " ")
-		if len(seg) < 2 {
-			b.SendMsg(u.Message.Chat.ID, "Invalid argument")
+	bot.AddButton("plot:{metric}:{range}", func(b *mybot.Bot, u tgbotapi.Update) {
+		args := b.ButtonArgs(u)
+		d, err := time.ParseDuration(args.String("range"))
+		if err != nil {
+			b.Toast(u, b.T(u, "plot.invalid_range", "range", args.String("range")))
 			return
 		}
+		plot(b, b.Locale(u), u.CallbackQuery.Message.Chat.ID, args.String("metric"), d)
+	})
 
-		historyName := seg[1]
+	bot.AddCmd("hi", "Example command for forms", true, func(b *mybot.Bot, u tgbotapi.Update, _ mybot.Args) {
+		b.Start(u, mybot.NewForm().
+			Ask(mybot.TextArg("name", b.T(u, "hi.ask"))).
+			Finally(func(b *mybot.Bot, u tgbotapi.Update, answers mybot.Args) {
+				b.Reply(u, "hi.hello", "name", answers.String("name"))
+			}))
+	}).In(mybot.InPrivate)
+
+	bot.AddCmd("cancel", "Cancel the current question", true, mybot.CmdCancel)
+
+	bot.AddCmd("rules", "List alert rules", false, ruleSet.CmdRules)
+
+	bot.AddCmd("rule", "Add, delete or test an alert rule", false, ruleSet.CmdRule).
+		Sub("new", ruleSet.CmdRuleNew).
+		Sub("add", ruleSet.CmdRuleAdd,
+			mybot.StringArg("name", "name of the rule, lowercase letters, digits, _ and -"),
+			mybot.TextArg("expression", "e.g. avg(cpu, 5m) > 80 && mem > 90"),
+		).
+		Sub("del", ruleSet.CmdRuleDel, mybot.StringArg("name", "")).
+		Sub("severity", ruleSet.CmdRuleSeverity,
+			mybot.StringArg("name", ""),
+			mybot.EnumArg("severity", "", alert.Info.String(), alert.Warning.String(), alert.Critical.String()),
+		).
+		Sub("test", ruleSet.CmdRuleTest, mybot.TextArg("rule", "name of a rule, or an expression")).
+		Require(mybot.Operator)
+
+	bot.AddCmd("mute", "Mute alerts of a rule or metric for a while", false, mybot.CmdMute).
+		Args(
+			mybot.StringArg("target", "rule or metric, * for all alerts"),
+			mybot.DurationArg("duration", "how long, e.g. 30m or 2h"),
+			mybot.TextArg("reason", "why, e.g. maintenance").Opt(),
+		).
+		Require(mybot.Operator)
+
+	bot.AddCmd("unmute", "End a silence", false, mybot.CmdUnmute).
+		Args(mybot.StringArg("silence", "silence ID, rule or metric")).
+		Require(mybot.Operator)
+
+	bot.AddCmd("silences", "List active silences", false, mybot.CmdSilences)
+
+	bot.AddCmd("secondary", "Subscribe to escalated alerts", false, mybot.CmdSecondary)
+
+	bot.AddCmd("prefs", "Set which alerts you get and when", false, mybot.CmdPrefs)
+
+	bot.AddCmd("group", "Show or change the forum topic and host classes of the group", false, mybot.CmdGroup).Args(
+		mybot.EnumArg("setting", "setting to change", "topic", "class").Opt(),
+		mybot.TextArg("value", "topic ID from the links to the topic or off, or host classes separated by commas or *").Opt(),
+	).Require(mybot.Operator).In(mybot.InGroups)
+
+	bot.AddCmd("digest", "Get low severity alerts as a periodic digest", false, mybot.CmdDigest).
+		Args(mybot.IntArg("minutes", "minutes between digests").Opt()).
+		Sub("off", mybot.CmdDigestOff)
+
+	bot.AddCmd("grant", "Give a user a role", false, mybot.CmdGrant).
+		Args(
+			mybot.IntArg("user", "Telegram user ID, not needed in a reply to the user").Opt(),
+			mybot.EnumArg("role", "", mybot.Viewer.String(), mybot.Operator.String(), mybot.Admin.String()),
+		).
+		Require(mybot.Admin)
+
+	bot.AddCmd("revoke", "Remove the role of a user", false, mybot.CmdRevoke).
+		Args(mybot.IntArg("user", "Telegram user ID, not needed in a reply to the user").Opt()).
+		Require(mybot.Admin)
+
+	bot.AddCmd("roles", "List users with a role", false, mybot.CmdRoles).Require(mybot.Admin)
+
+	bot.AddCmd("audit", "Show recent changes", false, mybot.CmdAudit).
+		Args(mybot.IntArg("n", "number of entries").Default("10")).
+		Require(mybot.Operator)
+
+	bot.AddCmd("revert", "Revert a change of the audit log", false, mybot.CmdRevert).
+		Args(mybot.IntArg("id", "ID of the change, see /audit")).
+		Require(mybot.Admin)
+
+	bot.AddReverter("set", config.Revert)
+	bot.AddReverter("reset", config.Revert)
+	bot.AddReverter("rule", ruleSet.Revert)
+	bot.AddReverter("rule_severity", ruleSet.RevertSeverity)
+
+	bot.AddCmd("failures", "List failed deliveries (for debug)", true, mybot.CmdFailures).Require(mybot.Admin)
+
+	bot.AddCmd("history", "Show history", false, func(b *mybot.Bot, u tgbotapi.Update, args mybot.Args) {
 		var h *history.History
-		switch historyName {
+		switch args.String("metric") {
 		case "cpu":
 			h = cpuUsageHistory
 		case "mem":
 			h = memUsageHistory
-
-		default:
-			b.SendMsg(u.Message.Chat.ID, "Invalid argument")
-			return
 		}
 
 		b.SendMsg(u.Message.Chat.ID, h.String())
-	})
+	}).Args(mybot.EnumArg("metric", "series to show", "cpu", "mem"))
+
+	bot.AddCmd("lang", "Show or set the language of the bot", false, mybot.CmdLang).
+		Args(mybot.EnumArg("lang", "language, auto to follow your Telegram app", mybot.LangChoices()...).Opt())
+
+	// descriptions of the commands in the command menu and /help are translated in the catalogs as cmd.<name>
+	for _, l := range i18n.Locales() {
+		if l.Has("cmd.help") {
+			bot.DescribeHelp(l.Lang(), l.T("cmd.help"))
+		}
+		for name, c := range bot.Cmds() {
+			if l.Has("cmd." + name) {
+				c.Describe(l.Lang(), l.T("cmd."+name))
+			}
+		}
+	}
 }
 
-// setMenu set the bot's command menu
-func setMenu(b *mybot.Bot, u tgbotapi.Update) {
-	deleteCommandConfig := tgbotapi.NewDeleteMyCommands()
-	b.Request(deleteCommandConfig)
+// plotRanges are the time ranges offered as buttons under a plot
+var plotRanges = []string{"30m", "1h", "6h", "24h"}
 
-	setConfig := tgbotapi.NewSetMyCommandsWithScope(tgbotapi.NewBotCommandScopeAllPrivateChats())
+// dashboard returns the text of the /dashboard message: the current value and recent trend of every series, the firing alerts and until when it is updated
+func dashboard(b *mybot.Bot, l *i18n.Locale, until time.Time) string {
+	var sb strings.Builder
 
-	for cmd, C := range b.Cmds() {
-		if C.Hide {
+	sb.WriteString(l.T("dashboard.header") + "\n")
+	for _, s := range collectors.All() {
+		v, ok := seriesValue(s)
+		if !ok {
 			continue
 		}
-		log.Println(cmd, C.Description)
-		setConfig.Commands = append(setConfig.Commands, tgbotapi.BotCommand{
-			Command:     cmd,
-			Description: C.Description,
-		})
+		if isCounter(s) {
+			sb.WriteString(fmt.Sprintf("%s: %s\n", s.Key(), v))
+			continue
+		}
+		data := s.History.Datas()
+		if len(data) > 20 {
+			data = data[len(data)-20:]
+		}
+		sb.WriteString(fmt.Sprintf("%s: %s %s\n", s.Key(), v, alert.Sparkline(data)))
+	}
+
+	sb.WriteString("\n" + l.T("dashboard.firing") + "\n")
+	firing := b.FiringAlerts()
+	if len(firing) == 0 {
+		sb.WriteString(l.T("dashboard.none") + "\n")
+	}
+	for _, r := range firing {
+		sb.WriteString(fmt.Sprintf("#%s [%s] %s", r.ID, l.T("severity."+r.Severity.String()), mybot.AlertText(l, r.Alert)))
+		if r.AckedBy != "" {
+			sb.WriteString(" " + l.T("dashboard.acked", "by", r.AckedBy))
+		}
+		sb.WriteString("\n")
+	}
+
+	sb.WriteString("\n" + l.T("dashboard.updated", "now", time.Now(), "until", until))
+	return sb.String()
+}
+
+// isCounter checks if s counts bytes, its rate is what matters rather than its value
+func isCounter(s *collector.Series) bool {
+	return s.Name == "net_rx" || s.Name == "net_tx"
+}
+
+// seriesValue returns the current value of s in text, the rate for counters. ok is false if s has no data yet.
+func seriesValue(s *collector.Series) (v string, ok bool) {
+	last, ok := s.History.Last()
+	if !ok {
+		return "", false
+	}
+	if isCounter(s) {
+		return bytesSize(s.History.Rate(avgInterval)) + "/s", true
+	}
+	return fmt.Sprintf("%.2f%%", last), true
+}
+
+// bytesSize formats n bytes with a binary unit, e.g. 1.5 MiB
+func bytesSize(n float64) string {
+	units := []string{"B", "KiB", "MiB", "GiB", "TiB"}
+	i := 0
+	for n >= 1024 && i < len(units)-1 {
+		n /= 1024
+		i++
+	}
+	return fmt.Sprintf("%.1f %s", n, units[i])
+}
+
+// maxInlineResults is the most results Telegram takes for an inline query
+const maxInlineResults = 50
+
+// inlineResults answers inline queries like "cpu" or "disk 1h" with the current value, the stats over the range (30m by default) and a plot of the matching series. Plots are only offered if inlinePlotChat is set, they are uploaded there and reused for plotTTL.
+func inlineResults(b *mybot.Bot, q *tgbotapi.InlineQuery) ([]interface{}, error) {
+	fields := strings.Fields(q.Query)
+	name, d := "", 30*time.Minute
+	if len(fields) > 0 {
+		name = fields[0]
+	}
+	if len(fields) > 1 {
+		var err error
+		if d, err = time.ParseDuration(fields[1]); err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid range %q", fields[1])
+		}
 	}
 
-	log.Println(setConfig.Commands)
+	l := b.UserLocale(q.From)
+	results := []interface{}{}
+	plots := 0
+	for i, s := range collectors.All() {
+		v, ok := seriesValue(s)
+		if !ok || !strings.HasPrefix(s.Key(), name) {
+			continue
+		}
+		if len(results)+3 > maxInlineResults {
+			break
+		}
+
+		now := time.Now()
+		value := tgbotapi.NewInlineQueryResultArticle(fmt.Sprintf("%d:value", i), fmt.Sprintf("%s: %s", s.Key(), v), l.T("inline.value", "key", s.Key(), "value", v, "now", now))
+		value.Description = l.T("inline.value_description")
+		results = append(results, value)
+
+		if !isCounter(s) {
+			h := s.History
+			stats := l.T("inline.stats", "avg", h.Average(d), "stddev", h.StdDev(d), "min", h.Min(d), "max", h.Max(d))
+			article := tgbotapi.NewInlineQueryResultArticle(fmt.Sprintf("%d:stats:%s", i, d), l.T("inline.over", "key", s.Key(), "range", d), l.T("inline.over_until", "key", s.Key(), "range", d, "now", now)+": "+stats)
+			article.Description = stats
+			results = append(results, article)
+		}
+
+		// uploading plots is slow, only upload the plots of the first few series
+		if name == "" || inlinePlotChat == 0 {
+			continue
+		}
+		fileID, ok := inlinePlots.get(s.Key(), d)
+		if !ok {
+			if plots >= 3 {
+				continue
+			}
+			var err error
+			if fileID, err = uploadPlot(b, s.History, d); err != nil {
+				log.Printf("inline plot %s: %v\n", s.Key(), err)
+				continue
+			}
+			inlinePlots.put(s.Key(), d, fileID)
+			plots++
+		}
+		photo := tgbotapi.NewInlineQueryResultCachedPhoto(fmt.Sprintf("%d:plot:%s", i, d), fileID)
+		photo.Title = l.T("inline.plot", "key", s.Key(), "range", d)
+		photo.Caption = l.T("inline.over_until", "key", s.Key(), "range", d, "now", now)
+		results = append(results, photo)
+	}
+
+	return results, nil
+}
+
+// plotTTL is how long an uploaded inline plot is offered again, Telegram sends an inline query for every keystroke
+const plotTTL = time.Minute
+
+// plotCache keeps the file IDs of the uploaded inline plots by series and range
+type plotCache struct {
+	mu    sync.Mutex
+	plots map[string]cachedPlot
+}
 
-	_, err := b.Request(setConfig)
+// cachedPlot is an uploaded plot and when it was uploaded
+type cachedPlot struct {
+	fileID string
+	at     time.Time
+}
+
+var inlinePlots = plotCache{plots: map[string]cachedPlot{}}
+
+// get returns the file ID of the plot of the series key over d if it was uploaded less than plotTTL ago
+func (c *plotCache) get(key string, d time.Duration) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	p, ok := c.plots[fmt.Sprintf("%s:%s", key, d)]
+	if !ok || time.Since(p.at) >= plotTTL {
+		return "", false
+	}
+	return p.fileID, true
+}
+
+// put caches the file ID of the plot of the series key over d, and drops the expired plots
+func (c *plotCache) put(key string, d time.Duration, fileID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for k, p := range c.plots {
+		if time.Since(p.at) >= plotTTL {
+			delete(c.plots, k)
+		}
+	}
+	c.plots[fmt.Sprintf("%s:%s", key, d)] = cachedPlot{fileID: fileID, at: time.Now()}
+}
+
+// uploadPlot plots h over the last d and uploads the plot to inlinePlotChat, it returns the file ID of the plot
+func uploadPlot(b *mybot.Bot, h *history.History, d time.Duration) (string, error) {
+	img, err := history.PlotRange(d, h)
 	if err != nil {
-		b.SendMsg(u.Message.Chat.ID, err.Error())
-	} else {
-		b.SendMsg(u.Message.Chat.ID, "doen")
+		return "", err
 	}
+	var buf bytes.Buffer
+	if _, err := img.WriteTo(&buf); err != nil {
+		return "", err
+	}
+	return b.UploadPhoto(inlinePlotChat, tgbotapi.FileBytes{Name: "plot.png", Bytes: buf.Bytes()})
 }
 
-// plot plots the CPU and memory usage history and sends the plot to the chat
-func plot(b *mybot.Bot, chatID int64) {
-	img, err := history.Plot(cpuUsageHistory, memUsageHistory)
+// plot plots the series of metric in the last d, or CPU and memory usage if metric is "*", and sends the plot to the chat with buttons to change the range. Errors are told in the language of l.
+func plot(b *mybot.Bot, l *i18n.Locale, chatID int64, metric string, d time.Duration) {
+	histories := []*history.History{cpuUsageHistory, memUsageHistory}
+	if metric != "*" {
+		histories = []*history.History{}
+		for _, s := range collectors.Find(metric, nil) {
+			histories = append(histories, s.History)
+		}
+		if len(histories) == 0 {
+			b.SendMsg(chatID, l.T("plot.no_series", "metric", metric))
+			return
+		}
+	}
+
+	img, err := history.PlotRange(d, histories...)
 	if err != nil {
-		b.SendMsg(chatID, "Error plotting")
+		b.SendMsg(chatID, l.T("plot.error"))
+		return
 	}
 
 	var imgBuf bytes.Buffer
 	if _, err := img.WriteTo(&imgBuf); err != nil {
-		b.SendMsg(chatID, "Error plotting")
+		b.SendMsg(chatID, l.T("plot.error"))
+		return
 	}
 
 	file := tgbotapi.FileBytes{Name: "usage.png", Bytes: imgBuf.Bytes()}
 
 	photo := tgbotapi.NewPhoto(chatID, file)
+	row := []tgbotapi.InlineKeyboardButton{}
+	for _, r := range plotRanges {
+		row = append(row, b.NewButton(r, "plot", metric, r))
+	}
+	photo.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(row)
 
 	if _, err := b.Send(photo); err != nil {
 		b.SendMsg(chatID, err.Error())
@@ -274,31 +665,214 @@ func isSuddenlyIncrease(curr float64, history *history.History) (float64, bool)
 	return z, z > threshold
 }
 
-func checkAndNotify(bot *mybot.Bot) {
-	cpuPercent, err := cpu.Percent(time.Second, false)
+// thresholdOptions returns the alert options of the threshold alert with the given config prefix
+func thresholdOptions(prefix string) alert.Options {
+	return alert.Options{
+		Trigger:    config.GetFloat64(prefix + "_threshold"),
+		Clear:      config.GetFloat64(prefix + "_clear_threshold"),
+		MinSamples: config.GetInt("min_samples"),
+		FlapWindow: time.Duration(config.GetInt("flap_window")) * time.Minute,
+		FlapCount:  config.GetInt("flap_count"),
+	}
+}
 
-	if err == nil {
-		if cpuPercent[0] > config.GetFloat64("cpu_threshold") {
-			bot.Boradcast(fmt.Sprintf("High CPU usage detected: %.2f%%", cpuPercent[0]))
-		}
+// ruleOptions returns the alert options of rules, they have no trigger and clear thresholds since an expression is either true or false
+func ruleOptions() alert.Options {
+	return alert.Options{
+		MinSamples: config.GetInt("min_samples"),
+		FlapWindow: time.Duration(config.GetInt("flap_window")) * time.Minute,
+		FlapCount:  config.GetInt("flap_count"),
+	}
+}
 
-		if z, yes := isSuddenlyIncrease(cpuPercent[0], cpuUsageHistory); yes {
-			bot.Boradcast(fmt.Sprintf("Sudden increase in CPU usage detected: %.2f%% (z = %.2f)", cpuPercent[0], z))
+// sparkline returns the sparkline of the recent data in h followed by the current value
+func sparkline(h *history.History, curr float64) string {
+	data := append(h.Datas(), curr)
+	if len(data) > 20 {
+		data = data[len(data)-20:]
+	}
+	return alert.Sparkline(data)
+}
+
+// localize sets the text of a to the message key with args, see i18n.Locale.T. Text is in the default language for the notifiers, Telegram subscribers get it in their own.
+func localize(a *alert.Alert, key string, args ...interface{}) {
+	a.Msg = key
+	a.Args = i18n.Args(args...)
+	a.Text = i18n.Default.Format(key, a.Args)
+}
+
+// notifyTimeout bounds the notifications of one check so that a slow or throttling notifier cannot delay the next check
+const notifyTimeout = 30 * time.Second
+
+// notifyAlerts sends the alerts raised in the same check to the notifiers routed to them, notifiers that support it group them into one notification
+func notifyAlerts(ctx context.Context, alerts []alert.Alert) {
+	if len(alerts) == 0 {
+		return
+	}
+	for i := range alerts {
+		alerts[i].Class = hostClass
+	}
+	ctx, cancel := context.WithTimeout(ctx, notifyTimeout)
+	defer cancel()
+	if err := notifier.NotifyBatch(ctx, alerts); err != nil {
+		log.Printf("notify: %v\n", err)
+	}
+}
+
+// thresholdAlert returns the alert matching the event of a threshold alert, or the escalation of the firing alert to critical. ok is false if there is nothing worth notifying.
+func thresholdAlert(metric string, h *history.History, t *alert.Tracker, e alert.Event, value float64, opt alert.Options) (a alert.Alert, ok bool) {
+	severity := alert.Warning
+	if value >= config.GetFloat64(metric+"_critical_threshold") {
+		severity = alert.Critical
+	}
+
+	escalated := t.Escalate(severity)
+
+	a = alert.Alert{
+		Severity:  severity,
+		Rule:      metric + "_threshold",
+		Metric:    metric,
+		Value:     value,
+		Threshold: opt.Trigger,
+		Sparkline: sparkline(h, value),
+		Time:      time.Now(),
+	}
+
+	switch e {
+	case alert.Fire:
+		a.State = alert.Firing
+		localize(&a, "alert.high", "metric", metric, "value", value)
+	case alert.Resolve:
+		a.State = alert.Resolved
+		localize(&a, "alert.normal", "metric", metric, "value", value)
+	case alert.FlapStart:
+		s := t.Summary(opt.FlapWindow)
+		localize(&a, "alert.flapping", "metric", metric, "threshold", opt.Trigger, "changes", s.Changes, "window", s.Window, "min", s.Min, "max", s.Max)
+	case alert.FlapStop:
+		s := t.Summary(opt.FlapWindow)
+		a.State = alert.Resolved
+		if s.Firing {
+			a.State = alert.Firing
+		}
+		localize(&a, "alert.flap_stopped", "metric", metric, "firing", s.Firing, "value", value, "suppressed", s.Suppressed)
+	default:
+		// a firing warning crossed the critical threshold, notify again for the subscribers of critical alerts only
+		if !escalated || t.Flapping() {
+			return a, false
 		}
+		a.State = alert.Firing
+		localize(&a, "alert.critical", "metric", metric, "value", value)
+	}
+
+	return a, true
+}
+
+// increaseAlert returns a sudden increase alert, z is how many standard deviations value is above the average
+func increaseAlert(metric string, h *history.History, value float64, z float64) alert.Alert {
+	a := alert.Alert{
+		Severity:  alert.Info,
+		Rule:      metric + "_increase",
+		Metric:    metric,
+		Value:     value,
+		Sparkline: sparkline(h, value),
+		Time:      time.Now(),
+	}
+	localize(&a, "alert.increase", "metric", metric, "value", value, "z", z)
+	return a
+}
+
+// ruleAlert returns the alert matching the event of a rule, ok is false if the event is not worth notifying
+func ruleAlert(res rules.Result, opt alert.Options) (a alert.Alert, ok bool) {
+	terms := []string{}
+	for _, t := range res.Terms {
+		terms = append(terms, fmt.Sprintf("%s = %.2f", t.Expr, t.Value))
+	}
 
-		cpuUsageHistory.Append(cpuPercent[0])
+	r := res.Rule
+	a = alert.Alert{
+		Severity: r.Severity,
+		Rule:     r.Name,
+		Time:     time.Now(),
+	}
+	if series := r.Expr.Series(); len(series) > 0 {
+		a.Metric = series[0]
+	}
+	if len(res.Terms) > 0 {
+		a.Value = res.Terms[0].Value
 	}
 
-	memStat, err := mem.VirtualMemory()
-	if err == nil {
-		if memStat.UsedPercent > config.GetFloat64("mem_threshold") {
-			bot.Boradcast(fmt.Sprintf("High memory usage detected: %.2f%%", memStat.UsedPercent))
+	switch res.Event {
+	case alert.Fire:
+		a.State = alert.Firing
+		localize(&a, "alert.rule_firing", "rule", r.Name, "expr", r.Expr.String(), "terms", terms)
+	case alert.Resolve:
+		a.State = alert.Resolved
+		localize(&a, "alert.rule_resolved", "rule", r.Name, "expr", r.Expr.String(), "terms", terms)
+	case alert.FlapStart:
+		s := r.Tracker.Summary(opt.FlapWindow)
+		localize(&a, "alert.rule_flapping", "rule", r.Name, "changes", s.Changes, "window", s.Window)
+	case alert.FlapStop:
+		s := r.Tracker.Summary(opt.FlapWindow)
+		a.State = alert.Resolved
+		if s.Firing {
+			a.State = alert.Firing
 		}
+		localize(&a, "alert.rule_flap_stopped", "rule", r.Name, "firing", s.Firing, "suppressed", s.Suppressed)
+	default:
+		return a, false
+	}
+
+	return a, true
+}
+
+func checkAndNotify(ctx context.Context, bot *mybot.Bot) {
+	samples := collectors.Collect(ctx)
+	alerts := []alert.Alert{}
+
+	for _, s := range samples {
+		switch s.Key() {
+		case "cpu":
+			opt := thresholdOptions("cpu")
+			e := cpuAlert.Update(s.Value, time.Now(), opt)
+			if a, ok := thresholdAlert("cpu", cpuUsageHistory, cpuAlert, e, s.Value, opt); ok {
+				alerts = append(alerts, a)
+			}
+
+			if z, yes := isSuddenlyIncrease(s.Value, cpuUsageHistory); yes {
+				alerts = append(alerts, increaseAlert("cpu", cpuUsageHistory, s.Value, z))
+			}
 
-		if z, yes := isSuddenlyIncrease(memStat.UsedPercent, memUsageHistory); yes {
-			bot.Boradcast(fmt.Sprintf("Sudden increase in memory usage detected: %.2f%% (z = %.2f)", memStat.UsedPercent, z))
+		case "mem":
+			opt := thresholdOptions("mem")
+			e := memAlert.Update(s.Value, time.Now(), opt)
+			if a, ok := thresholdAlert("mem", memUsageHistory, memAlert, e, s.Value, opt); ok {
+				alerts = append(alerts, a)
+			}
+
+			if z, yes := isSuddenlyIncrease(s.Value, memUsageHistory); yes {
+				alerts = append(alerts, increaseAlert("mem", memUsageHistory, s.Value, z))
+			}
 		}
+	}
+
+	collectors.Record(samples)
 
-		memUsageHistory.Append(memStat.UsedPercent)
+	bot.Remind(
+		time.Duration(config.GetInt("reminder_interval"))*time.Minute,
+		time.Duration(config.GetInt("escalate_after"))*time.Minute,
+	)
+	bot.SendDigests()
+
+	opt := ruleOptions()
+	for _, res := range ruleSet.Eval(time.Now(), opt) {
+		if res.Err != nil {
+			log.Printf("rule %s: %v\n", res.Rule.Name, res.Err)
+			continue
+		}
+		if a, ok := ruleAlert(res, opt); ok {
+			alerts = append(alerts, a)
+		}
 	}
+
+	notifyAlerts(ctx, alerts)
 }
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"context"
+	"monitor/alert"
+	mybot "monitor/bot"
+	"monitor/bot/bottest"
+	"monitor/history"
+	"strings"
+	"testing"
+	"time"
+)
+
+// startBot starts the bot with all commands, handling the updates of a fake Telegram server until the test ends
+func startBot(t *testing.T) (*mybot.Bot, *bottest.Server) {
+	srv := bottest.NewServer(t)
+	bot, err := mybot.New(srv.API())
+	if err != nil {
+		t.Fatal(err)
+	}
+	bot.DisableLimits()
+	registerCmdsAndBtn(bot)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if err := bot.HandleUpdates(ctx); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	t.Cleanup(func() {
+		cancel()
+		<-done
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		bot.Shutdown(ctx)
+	})
+
+	return bot, srv
+}
+
+func TestSubscribe(t *testing.T) {
+	bot, srv := startBot(t)
+	bot.Grant(10, mybot.Viewer)
+
+	srv.Message(10, "/subscribe")
+	srv.WaitText(10, "Subscribed to notifications")
+	if !bot.IsSubscribed(10) {
+		t.Fatal("chat should be subscribed")
+	}
+
+	srv.Message(10, "/unsubscribe")
+	srv.WaitText(10, "Unsubscribed from notifications")
+	if bot.IsSubscribed(10) {
+		t.Error("chat should be unsubscribed")
+	}
+
+	// users without a role only get the help
+	srv.Message(11, "/subscribe")
+	srv.WaitText(11, "Permission denied")
+	if bot.IsSubscribed(11) {
+		t.Error("guests should not subscribe")
+	}
+}
+
+func TestSet(t *testing.T) {
+	bot, srv := startBot(t)
+	bot.Grant(10, mybot.Admin)
+	bot.Grant(11, mybot.Viewer)
+	t.Cleanup(func() { config.ResetInt("min_samples") })
+
+	srv.Message(11, "/set min_samples 5")
+	srv.WaitText(11, "Permission denied")
+	if config.GetInt("min_samples") != 2 {
+		t.Fatal("viewers should not change the config")
+	}
+
+	srv.Message(10, "/set min_samples 5")
+	srv.WaitText(10, "Set min_samples to 5 (previous: 2)")
+	if config.GetInt("min_samples") != 5 {
+		t.Errorf("min_samples = %d, want 5", config.GetInt("min_samples"))
+	}
+
+	srv.Message(10, "/set min_samples five")
+	srv.WaitText(10, "Failed to convert")
+	if config.GetInt("min_samples") != 5 {
+		t.Error("invalid values should not change the config")
+	}
+
+	log := bot.AuditLog(10)
+	if len(log) != 1 || log[0].Action != "set" || log[0].New != "5" {
+		t.Errorf("audit log = %v, want one set", log)
+	}
+}
+
+func TestPlot(t *testing.T) {
+	bot, srv := startBot(t)
+	bot.Grant(10, mybot.Viewer)
+	for _, v := range []float64{10, 20, 30} {
+		cpuUsageHistory.Append(v)
+		memUsageHistory.Append(v)
+	}
+
+	srv.Message(10, "/plot")
+	photo := srv.WaitMethod(10, "sendPhoto")
+	if len(photo.Files) != 1 {
+		t.Errorf("files = %v, want the plot", photo.Files)
+	}
+	buttons := photo.Buttons()
+	if len(buttons) != len(plotRanges) || buttons[1] != "plot:*:1h" {
+		t.Fatalf("buttons = %v, want one per range", buttons)
+	}
+
+	srv.Message(10, "/plot nope")
+	srv.WaitText(10, "No series named nope")
+
+	// the range buttons plot again
+	srv.Reset()
+	id := srv.Press(10, buttons[1])
+	srv.WaitMethod(10, "sendPhoto")
+	srv.Wait(func(r bottest.Request) bool {
+		return r.Method == "answerCallbackQuery" && r.Params["callback_query_id"] == id
+	})
+}
+
+func TestDashboard(t *testing.T) {
+	bot, srv := startBot(t)
+	bot.Grant(10, mybot.Viewer)
+
+	srv.Message(10, "/dashboard 1s")
+	srv.WaitText(10, "Cannot start the dashboard")
+
+	srv.Message(10, "/dashboard --pin")
+	msg := srv.WaitText(10, "===Dashboard===")
+	srv.WaitMethod(10, "pinChatMessage")
+
+	buttons := msg.Buttons()
+	if len(buttons) != 1 {
+		t.Fatalf("buttons = %v, want Stop", buttons)
+	}
+	srv.Press(10, buttons[0])
+	srv.Wait(func(r bottest.Request) bool {
+		return r.Method == "editMessageText" && strings.Contains(r.Text(), "Stopped at")
+	})
+	srv.WaitMethod(10, "unpinChatMessage")
+
+	// the button of a stopped dashboard does nothing
+	id := srv.Press(10, buttons[0])
+	toast := srv.Wait(func(r bottest.Request) bool {
+		return r.Method == "answerCallbackQuery" && r.Params["callback_query_id"] == id
+	})
+	if !strings.Contains(toast.Text(), "not running") {
+		t.Errorf("toast = %q", toast.Text())
+	}
+}
+
+func TestBytesSize(t *testing.T) {
+	for n, want := range map[float64]string{
+		0:         "0.0 B",
+		1536:      "1.5 KiB",
+		3 << 30:   "3.0 GiB",
+		1 << 50:   "1024.0 TiB",
+		1023.9:    "1023.9 B",
+		2.5 * 1e6: "2.4 MiB",
+	} {
+		if got := bytesSize(n); got != want {
+			t.Errorf("bytesSize(%g) = %s, want %s", n, got, want)
+		}
+	}
+}
+
+func TestInline(t *testing.T) {
+	bot, srv := startBot(t)
+	bot.Grant(10, mybot.Viewer)
+	cpuUsageHistory.Append(42)
+	inlinePlotChat = 99
+	t.Cleanup(func() { inlinePlotChat = 0 })
+
+	answer := func(id string) bottest.Request {
+		return srv.Wait(func(r bottest.Request) bool {
+			return r.Method == "answerInlineQuery" && r.Params["inline_query_id"] == id
+		})
+	}
+
+	results := answer(srv.Inline(10, "cpu 1h")).Results()
+	if len(results) != 3 {
+		t.Fatalf("got %d results, want value, stats and plot: %v", len(results), results)
+	}
+	if results[0]["title"] != "cpu: 42.00%" || results[1]["title"] != "cpu over 1h" || results[2]["photo_file_id"] == "" {
+		t.Errorf("unexpected results %v", results)
+	}
+	srv.WaitMethod(99, "sendPhoto")
+
+	// the plot is reused while it is recent
+	again := answer(srv.Inline(10, "cpu 1h")).Results()
+	if len(again) != 3 || again[2]["photo_file_id"] != results[2]["photo_file_id"] {
+		t.Errorf("got %v, want the same plot", again)
+	}
+	uploads := 0
+	for _, r := range srv.Requests() {
+		if r.Method == "sendPhoto" {
+			uploads++
+		}
+	}
+	if uploads != 1 {
+		t.Errorf("uploaded %d plots, want 1", uploads)
+	}
+
+	if results := answer(srv.Inline(10, "nope")).Results(); len(results) != 0 {
+		t.Errorf("got %v, want no results", results)
+	}
+
+	// guests are told to ask for access
+	denied := answer(srv.Inline(11, "cpu"))
+	if len(denied.Results()) != 0 || denied.Params["switch_pm_text"] == "" {
+		t.Errorf("guests got %v", denied.Params)
+	}
+}
+
+func TestThresholdEscalation(t *testing.T) {
+	tr := alert.NewTracker("CPU")
+	opt := alert.Options{Trigger: 80, Clear: 70, MinSamples: 1}
+	h := history.New(time.Hour, "cpu")
+
+	check := func(value float64) (alert.Alert, bool) {
+		e := tr.Update(value, time.Now(), opt)
+		return thresholdAlert("cpu", h, tr, e, value, opt)
+	}
+
+	if a, ok := check(85); !ok || a.Severity != alert.Warning || a.State != alert.Firing {
+		t.Fatalf("got %+v, %v, want a firing warning", a, ok)
+	}
+	if _, ok := check(86); ok {
+		t.Error("still firing warning notified again")
+	}
+
+	// crossing cpu_critical_threshold while firing escalates once
+	a, ok := check(95)
+	if !ok || a.Severity != alert.Critical || a.State != alert.Firing || a.Msg != "alert.critical" {
+		t.Fatalf("got %+v, %v, want the alert escalated to critical", a, ok)
+	}
+	if _, ok := check(96); ok {
+		t.Error("critical alert notified again")
+	}
+	if _, ok := check(85); ok {
+		t.Error("alert notified when it went back under the critical threshold")
+	}
+
+	if a, ok := check(60); !ok || a.State != alert.Resolved {
+		t.Errorf("got %+v, %v, want the alert resolved", a, ok)
+	}
+}
@@ -0,0 +1,117 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"monitor/alert"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Retry controls how chat notifiers retry failed deliveries.
+type Retry struct {
+	Attempts int           // total attempts, at least 1
+	Backoff  time.Duration // delay before the second attempt, doubled after every attempt
+	MaxWait  time.Duration // longest delay before an attempt, a server asking to wait longer is given up on; no limit if 0
+}
+
+// DefaultRetry is the retry policy of chat notifiers created by their constructors.
+var DefaultRetry = Retry{Attempts: 3, Backoff: time.Second, MaxWait: 30 * time.Second}
+
+// temporary checks if a failed request is worth retrying. Client errors other than 429 Too Many Requests are not.
+func temporary(err error) bool {
+	var se *StatusError
+	if errors.As(err, &se) {
+		return se.Code == http.StatusTooManyRequests || se.Code >= 500
+	}
+	return true
+}
+
+// Do calls f until it succeeds, fails permanently or runs out of attempts. A Retry-After from the server overrides the backoff, unless it is longer than MaxWait: then Do gives up rather than stall the caller.
+func (r Retry) Do(ctx context.Context, f func() error) error {
+	backoff := r.Backoff
+	var err error
+
+	for i := 0; i < max(r.Attempts, 1); i++ {
+		if i > 0 {
+			wait := backoff
+			var se *StatusError
+			if errors.As(err, &se) && se.RetryAfter > 0 {
+				if r.MaxWait > 0 && se.RetryAfter > r.MaxWait {
+					return fmt.Errorf("%w, giving up instead of waiting %s", err, se.RetryAfter)
+				}
+				wait = se.RetryAfter
+			}
+			backoff *= 2
+			if r.MaxWait > 0 {
+				wait = min(wait, r.MaxWait)
+			}
+
+			select {
+			case <-ctx.Done():
+				return errors.Join(err, ctx.Err())
+			case <-time.After(wait):
+			}
+		}
+
+		err = f()
+		if err == nil || !temporary(err) {
+			return err
+		}
+	}
+
+	return err
+}
+
+// title returns the headline of a in chat messages, e.g. "[FIRING] cpu_threshold"
+func title(a alert.Alert) string {
+	return fmt.Sprintf("[%s] %s", strings.ToUpper(a.State.String()), a.Rule)
+}
+
+// color returns the color of a in chat messages as 0xRRGGBB, green for resolved alerts and by severity otherwise
+func color(a alert.Alert) int {
+	if a.State == alert.Resolved {
+		return 0x2eb67d
+	}
+
+	switch a.Severity {
+	case alert.Critical:
+		return 0xd00000
+	case alert.Warning:
+		return 0xf2a900
+	default:
+		return 0x3aa3e3
+	}
+}
+
+// hexColor returns color as #rrggbb
+func hexColor(color int) string {
+	return fmt.Sprintf("#%06x", color)
+}
+
+// field is a labeled value shown with the alert
+type field struct {
+	Name  string
+	Value string
+}
+
+// fields returns the details of a shown by every chat notifier
+func fields(a alert.Alert) []field {
+	res := []field{
+		{"Severity", a.Severity.String()},
+	}
+	if a.Metric != "" {
+		res = append(res, field{"Metric", a.Metric})
+	}
+	res = append(res, field{"Value", strconv.FormatFloat(a.Value, 'f', 2, 64)})
+	if a.Threshold != 0 {
+		res = append(res, field{"Threshold", strconv.FormatFloat(a.Threshold, 'f', 2, 64)})
+	}
+	if a.Sparkline != "" {
+		res = append(res, field{"Recent", a.Sparkline})
+	}
+	return res
+}
@@ -0,0 +1,166 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// flaky is an HTTP stand-in that fails the first n requests with status and records the rest
+type flaky struct {
+	mu       sync.Mutex
+	n        int
+	status   int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (f *flaky) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.n > 0 {
+		f.n--
+		w.Header().Set("Retry-After", "0")
+		w.WriteHeader(f.status)
+		return
+	}
+
+	b, _ := io.ReadAll(r.Body)
+	f.requests = append(f.requests, r)
+	f.bodies = append(f.bodies, b)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+var fastRetry = Retry{Attempts: 3, Backoff: time.Millisecond}
+
+func TestSlack(t *testing.T) {
+	f := &flaky{n: 1, status: http.StatusTooManyRequests}
+	srv := httptest.NewServer(f)
+	defer srv.Close()
+
+	s := NewSlack(srv.URL)
+	s.Retry = fastRetry
+	if err := s.Notify(context.Background(), testAlert()); err != nil {
+		t.Fatal(err)
+	}
+
+	var msg slackMessage
+	if err := json.Unmarshal(f.bodies[0], &msg); err != nil {
+		t.Fatal(err)
+	}
+	att := msg.Attachments[0]
+	if att.Title != "[FIRING] cpu_threshold" || att.Text != testAlert().Text || att.Color != "#3aa3e3" {
+		t.Errorf("unexpected attachment %+v", att)
+	}
+	if att.Fields[1].Title != "Metric" || att.Fields[1].Value != "cpu" {
+		t.Errorf("unexpected fields %+v", att.Fields)
+	}
+}
+
+func TestDiscord(t *testing.T) {
+	f := &flaky{n: 2, status: http.StatusBadGateway}
+	srv := httptest.NewServer(f)
+	defer srv.Close()
+
+	d := NewDiscord(srv.URL)
+	d.Retry = fastRetry
+	a := testAlert()
+	a.Sparkline = "▁▄█"
+	if err := d.Notify(context.Background(), a); err != nil {
+		t.Fatal(err)
+	}
+
+	var msg discordMessage
+	if err := json.Unmarshal(f.bodies[0], &msg); err != nil {
+		t.Fatal(err)
+	}
+	embed := msg.Embeds[0]
+	last := embed.Fields[len(embed.Fields)-1]
+	if embed.Title != "[FIRING] cpu_threshold" || last.Value != "`▁▄█`" {
+		t.Errorf("unexpected embed %+v", embed)
+	}
+}
+
+func TestMatrix(t *testing.T) {
+	f := &flaky{n: 1, status: http.StatusServiceUnavailable}
+	srv := httptest.NewServer(f)
+	defer srv.Close()
+
+	m := NewMatrix(srv.URL+"/", "secret", "!room:example.com")
+	m.Retry = fastRetry
+	if err := m.Notify(context.Background(), testAlert()); err != nil {
+		t.Fatal(err)
+	}
+
+	r := f.requests[0]
+	if r.Method != http.MethodPut || r.Header.Get("Authorization") != "Bearer secret" {
+		t.Errorf("unexpected request %s %s", r.Method, r.Header)
+	}
+	if !strings.HasPrefix(r.URL.Path, "/_matrix/client/v3/rooms/!room:example.com/send/m.room.message/") {
+		t.Errorf("unexpected path %s", r.URL.Path)
+	}
+
+	var msg matrixMessage
+	if err := json.Unmarshal(f.bodies[0], &msg); err != nil {
+		t.Fatal(err)
+	}
+	if msg.Format != "org.matrix.custom.html" || !strings.Contains(msg.FormattedBody, "<b>Metric</b>: <code>cpu</code>") {
+		t.Errorf("unexpected message %+v", msg)
+	}
+}
+
+func TestRetryGivesUp(t *testing.T) {
+	f := &flaky{n: 10, status: http.StatusInternalServerError}
+	srv := httptest.NewServer(f)
+	defer srv.Close()
+
+	s := NewSlack(srv.URL)
+	s.Retry = fastRetry
+	if err := s.Notify(context.Background(), testAlert()); err == nil {
+		t.Error("Notify should fail after all attempts")
+	}
+	if f.n != 7 {
+		t.Errorf("made %d attempts, want 3", 10-f.n)
+	}
+
+	// a server asking to wait longer than MaxWait is given up on at once
+	attempts := 0
+	start := time.Now()
+	err := Retry{Attempts: 3, Backoff: time.Millisecond, MaxWait: time.Second}.Do(context.Background(), func() error {
+		attempts++
+		return &StatusError{Code: http.StatusTooManyRequests, RetryAfter: time.Hour}
+	})
+	if err == nil || attempts != 1 || time.Since(start) > time.Second {
+		t.Errorf("made %d attempts in %s, err %v, want to give up after 1", attempts, time.Since(start), err)
+	}
+
+	// and the backoff never grows past it
+	attempts = 0
+	start = time.Now()
+	Retry{Attempts: 3, Backoff: time.Hour, MaxWait: 10 * time.Millisecond}.Do(context.Background(), func() error {
+		attempts++
+		return &StatusError{Code: http.StatusBadGateway}
+	})
+	if attempts != 3 || time.Since(start) > time.Second {
+		t.Errorf("made %d attempts in %s, want 3 quick ones", attempts, time.Since(start))
+	}
+
+	// client errors are not retried
+	f = &flaky{n: 10, status: http.StatusNotFound}
+	srv2 := httptest.NewServer(f)
+	defer srv2.Close()
+
+	s = NewSlack(srv2.URL)
+	s.Retry = fastRetry
+	s.Notify(context.Background(), testAlert())
+	if f.n != 9 {
+		t.Errorf("made %d attempts, want 1", 10-f.n)
+	}
+}
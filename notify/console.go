@@ -0,0 +1,30 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"monitor/alert"
+	"sync"
+	"time"
+)
+
+// Console writes alerts to a writer, usually stdout.
+type Console struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewConsole creates a Console writing to w.
+func NewConsole(w io.Writer) *Console {
+	return &Console{w: w}
+}
+
+// Notify implements Notifier.
+func (c *Console) Notify(_ context.Context, a alert.Alert) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	_, err := fmt.Fprintf(c.w, "%s [%s] %s: %s\n", a.Time.Format(time.RFC3339), a.State, a.Rule, a.Text)
+	return err
+}
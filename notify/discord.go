@@ -0,0 +1,78 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"monitor/alert"
+	"net/http"
+	"time"
+)
+
+// Discord posts alerts to a Discord webhook as embeds.
+type Discord struct {
+	URL    string
+	Retry  Retry
+	Client *http.Client
+}
+
+// NewDiscord creates a Discord notifier posting to the webhook url.
+func NewDiscord(url string) *Discord {
+	return &Discord{
+		URL:    url,
+		Retry:  DefaultRetry,
+		Client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+type discordField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline"`
+}
+
+type discordEmbed struct {
+	Title       string         `json:"title"`
+	Description string         `json:"description"`
+	Color       int            `json:"color"`
+	Fields      []discordField `json:"fields"`
+	Timestamp   string         `json:"timestamp"`
+}
+
+type discordMessage struct {
+	Content string         `json:"content"`
+	Embeds  []discordEmbed `json:"embeds"`
+}
+
+// payload returns the webhook payload of a
+func (d *Discord) payload(a alert.Alert) ([]byte, error) {
+	embed := discordEmbed{
+		Title:       title(a),
+		Description: a.Text,
+		Color:       color(a),
+		Timestamp:   a.Time.Format(time.RFC3339),
+	}
+	for _, f := range fields(a) {
+		v := f.Value
+		if f.Name == "Recent" {
+			v = "`" + v + "`"
+		}
+		embed.Fields = append(embed.Fields, discordField{Name: f.Name, Value: v, Inline: f.Name != "Recent"})
+	}
+
+	return json.Marshal(discordMessage{
+		Embeds: []discordEmbed{embed},
+	})
+}
+
+// Notify implements Notifier.
+func (d *Discord) Notify(ctx context.Context, a alert.Alert) error {
+	body, err := d.payload(a)
+	if err != nil {
+		return err
+	}
+
+	header := http.Header{"Content-Type": []string{"application/json"}}
+	return d.Retry.Do(ctx, func() error {
+		return post(ctx, d.Client, d.URL, header, body)
+	})
+}
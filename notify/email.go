@@ -0,0 +1,116 @@
+package notify
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"monitor/alert"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// Email sends alerts by SMTP.
+type Email struct {
+	Addr string // host:port of the SMTP server
+	Auth smtp.Auth
+	From string
+	To   []string
+}
+
+// NewEmail creates an Email notifier, it authenticates with PLAIN auth if username is not empty.
+func NewEmail(addr string, username string, password string, from string, to ...string) *Email {
+	e := &Email{
+		Addr: addr,
+		From: from,
+		To:   to,
+	}
+
+	if username != "" {
+		host, _, _ := net.SplitHostPort(addr)
+		e.Auth = smtp.PlainAuth("", username, password, host)
+	}
+
+	return e
+}
+
+// message returns the mail of a
+func (e *Email) message(a alert.Alert) []byte {
+	var sb strings.Builder
+
+	sb.WriteString(fmt.Sprintf("From: %s\r\n", e.From))
+	sb.WriteString(fmt.Sprintf("To: %s\r\n", strings.Join(e.To, ", ")))
+	sb.WriteString(fmt.Sprintf("Subject: [monitor] %s: %s\r\n", a.State, a.Rule))
+	sb.WriteString(fmt.Sprintf("Date: %s\r\n", a.Time.Format(time.RFC1123Z)))
+	sb.WriteString("MIME-Version: 1.0\r\n")
+	sb.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	sb.WriteString("\r\n")
+	sb.WriteString(strings.ReplaceAll(a.Text, "\n", "\r\n"))
+	sb.WriteString("\r\n")
+
+	return []byte(sb.String())
+}
+
+// Notify implements Notifier. It upgrades to TLS if the server supports STARTTLS, and gives up when ctx is done.
+func (e *Email) Notify(ctx context.Context, a alert.Alert) error {
+	if len(e.To) == 0 {
+		return fmt.Errorf("no recipient")
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", e.Addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// net/smtp does not take a context, the deadline of the connection stops a hung server
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	host, _, _ := net.SplitHostPort(e.Addr)
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if e.Auth != nil {
+		if ok, _ := c.Extension("AUTH"); !ok {
+			return errors.New("smtp: server does not support AUTH")
+		}
+		if err := c.Auth(e.Auth); err != nil {
+			return err
+		}
+	}
+
+	if err := c.Mail(e.From); err != nil {
+		return err
+	}
+	for _, to := range e.To {
+		if err := c.Rcpt(to); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(e.message(a)); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
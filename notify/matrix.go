@@ -0,0 +1,83 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"monitor/alert"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Matrix sends alerts to a Matrix room through the client-server API of a homeserver.
+type Matrix struct {
+	Homeserver string // e.g. https://matrix.org
+	Token      string // access token of the bot account
+	Room       string // room ID, e.g. !abc:matrix.org
+	Retry      Retry
+	Client     *http.Client
+	txn        atomic.Int64
+}
+
+// NewMatrix creates a Matrix notifier sending to room on homeserver as the user of token.
+func NewMatrix(homeserver string, token string, room string) *Matrix {
+	return &Matrix{
+		Homeserver: strings.TrimSuffix(homeserver, "/"),
+		Token:      token,
+		Room:       room,
+		Retry:      DefaultRetry,
+		Client:     &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+type matrixMessage struct {
+	MsgType       string `json:"msgtype"`
+	Body          string `json:"body"`
+	Format        string `json:"format"`
+	FormattedBody string `json:"formatted_body"`
+}
+
+// payload returns the m.room.message event of a
+func (m *Matrix) payload(a alert.Alert) ([]byte, error) {
+	var plain, rich strings.Builder
+
+	plain.WriteString(title(a) + "\n" + a.Text + "\n")
+	rich.WriteString(fmt.Sprintf(`<h4><font color="%s">%s</font></h4><p>%s</p><ul>`,
+		hexColor(color(a)), html.EscapeString(title(a)), strings.ReplaceAll(html.EscapeString(a.Text), "\n", "<br>")))
+
+	for _, f := range fields(a) {
+		plain.WriteString(fmt.Sprintf("%s: %s\n", f.Name, f.Value))
+		rich.WriteString(fmt.Sprintf("<li><b>%s</b>: <code>%s</code></li>", f.Name, html.EscapeString(f.Value)))
+	}
+	rich.WriteString("</ul>")
+
+	return json.Marshal(matrixMessage{
+		MsgType:       "m.text",
+		Body:          plain.String(),
+		Format:        "org.matrix.custom.html",
+		FormattedBody: rich.String(),
+	})
+}
+
+// Notify implements Notifier. Every retry reuses the transaction ID, so the homeserver sends the message only once.
+func (m *Matrix) Notify(ctx context.Context, a alert.Alert) error {
+	body, err := m.payload(a)
+	if err != nil {
+		return err
+	}
+
+	txn := fmt.Sprintf("monitor-%d-%d", time.Now().UnixNano(), m.txn.Add(1))
+	u := fmt.Sprintf("%s/_matrix/client/v3/rooms/%s/send/m.room.message/%s", m.Homeserver, url.PathEscape(m.Room), txn)
+	header := http.Header{
+		"Content-Type":  []string{"application/json"},
+		"Authorization": []string{"Bearer " + m.Token},
+	}
+
+	return m.Retry.Do(ctx, func() error {
+		return send(ctx, m.Client, http.MethodPut, u, header, body)
+	})
+}
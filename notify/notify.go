@@ -0,0 +1,247 @@
+// notify delivers alerts to notifiers, a Router picks the notifiers of each alert.
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"monitor/alert"
+	"os"
+	"slices"
+)
+
+// Notifier delivers alerts to a backend.
+type Notifier interface {
+	Notify(ctx context.Context, a alert.Alert) error
+}
+
+// Batcher is a Notifier that can deliver alerts raised together as one notification.
+type Batcher interface {
+	Notifier
+	NotifyBatch(ctx context.Context, alerts []alert.Alert) error
+}
+
+// Route sends the alerts it matches to notifiers.
+type Route struct {
+	Match     []string      `json:"match"`     // rule or metric names, empty matches every alert
+	States    []alert.State `json:"states"`    // alert states, empty matches every state
+	Notifiers []string      `json:"notifiers"` // names of the notifiers
+}
+
+// Matches checks if the route matches a.
+func (r Route) Matches(a alert.Alert) bool {
+	if len(r.States) > 0 && !slices.Contains(r.States, a.State) {
+		return false
+	}
+
+	if len(r.Match) == 0 {
+		return true
+	}
+	for _, m := range r.Match {
+		if a.Matches(m) {
+			return true
+		}
+	}
+	return false
+}
+
+// Router is a Notifier that sends alerts to the notifiers of every route matching them. Without routes, alerts go to every notifier.
+type Router struct {
+	notifiers map[string]Notifier
+	routes    []Route
+}
+
+// NewRouter creates an empty Router.
+func NewRouter() *Router {
+	return &Router{
+		notifiers: map[string]Notifier{},
+	}
+}
+
+// Add adds a notifier with the given name.
+func (r *Router) Add(name string, n Notifier) *Router {
+	r.notifiers[name] = n
+	return r
+}
+
+// Route adds a route, it fails if the route refers to an unknown notifier.
+func (r *Router) Route(route Route) error {
+	for _, name := range route.Notifiers {
+		if _, ok := r.notifiers[name]; !ok {
+			return fmt.Errorf("route refers to unknown notifier %q", name)
+		}
+	}
+
+	r.routes = append(r.routes, route)
+	return nil
+}
+
+// targets returns the names of the notifiers a goes to
+func (r *Router) targets(a alert.Alert) []string {
+	names := []string{}
+
+	if len(r.routes) == 0 {
+		for name := range r.notifiers {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		return names
+	}
+
+	for _, route := range r.routes {
+		if !route.Matches(a) {
+			continue
+		}
+		for _, name := range route.Notifiers {
+			if !slices.Contains(names, name) {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// Notify sends a to the notifiers of every matching route and returns the errors of all failed notifiers.
+func (r *Router) Notify(ctx context.Context, a alert.Alert) error {
+	errs := []error{}
+
+	for _, name := range r.targets(a) {
+		if err := r.notifiers[name].Notify(ctx, a); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// NotifyBatch sends alerts raised together to the notifiers of every matching route. Notifiers implementing Batcher get all their alerts at once, the others one by one.
+func (r *Router) NotifyBatch(ctx context.Context, alerts []alert.Alert) error {
+	names := []string{}
+	batches := map[string][]alert.Alert{}
+	for _, a := range alerts {
+		for _, name := range r.targets(a) {
+			if _, ok := batches[name]; !ok {
+				names = append(names, name)
+			}
+			batches[name] = append(batches[name], a)
+		}
+	}
+
+	errs := []error{}
+	for _, name := range names {
+		n := r.notifiers[name]
+		if b, ok := n.(Batcher); ok {
+			if err := b.NotifyBatch(ctx, batches[name]); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			}
+			continue
+		}
+		for _, a := range batches[name] {
+			if err := n.Notify(ctx, a); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Config is the notification config file, e.g.
+//
+//	{
+//		"notifiers": {
+//			"ops": {"type": "webhook", "url": "https://example.com/hook"},
+//			"slack": {"type": "slack", "url": "https://hooks.slack.com/services/..."},
+//			"matrix": {"type": "matrix", "homeserver": "https://matrix.org", "token": "...", "room": "!abc:matrix.org"},
+//			"mail": {"type": "email", "addr": "smtp.example.com:587", "from": "monitor@example.com", "to": ["ops@example.com"]},
+//			"stdout": {"type": "console"}
+//		},
+//		"routes": [
+//			{"match": ["cpu", "high_cpu"], "notifiers": ["telegram", "ops", "slack", "matrix"]},
+//			{"states": ["firing"], "notifiers": ["mail"]},
+//			{"notifiers": ["telegram", "stdout"]}
+//		]
+//	}
+type Config struct {
+	Notifiers map[string]NotifierConfig `json:"notifiers"`
+	Routes    []Route                   `json:"routes"`
+}
+
+// NotifierConfig configures a notifier, which fields are used depends on Type.
+type NotifierConfig struct {
+	Type string `json:"type"` // webhook, slack, discord, matrix, email or console
+
+	// webhook, slack and discord
+	URL      string            `json:"url"`
+	Template string            `json:"template"`
+	Headers  map[string]string `json:"headers"`
+
+	// email
+	Addr     string   `json:"addr"`
+	Username string   `json:"username"`
+	Password string   `json:"password"`
+	From     string   `json:"from"`
+	To       []string `json:"to"`
+
+	// matrix
+	Homeserver string `json:"homeserver"`
+	Token      string `json:"token"`
+	Room       string `json:"room"`
+}
+
+// build creates the notifier described by the config
+func (c NotifierConfig) build() (Notifier, error) {
+	switch c.Type {
+	case "webhook":
+		w, err := NewWebhook(c.URL, c.Template)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range c.Headers {
+			w.Header.Set(k, v)
+		}
+		return w, nil
+	case "slack":
+		return NewSlack(c.URL), nil
+	case "discord":
+		return NewDiscord(c.URL), nil
+	case "matrix":
+		return NewMatrix(c.Homeserver, c.Token, c.Room), nil
+	case "email":
+		return NewEmail(c.Addr, c.Username, c.Password, c.From, c.To...), nil
+	case "console":
+		return NewConsole(os.Stdout), nil
+	}
+
+	return nil, fmt.Errorf("unknown notifier type %q", c.Type)
+}
+
+// Load adds the notifiers and routes in the config file at path to the router.
+func (r *Router) Load(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var c Config
+	if err := json.Unmarshal(data, &c); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	for name, nc := range c.Notifiers {
+		n, err := nc.build()
+		if err != nil {
+			return fmt.Errorf("notifier %s: %w", name, err)
+		}
+		r.Add(name, n)
+	}
+
+	for _, route := range c.Routes {
+		if err := r.Route(route); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,301 @@
+package notify
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"monitor/alert"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testAlert() alert.Alert {
+	return alert.Alert{
+		ID:     "1",
+		State:  alert.Firing,
+		Rule:   "cpu_threshold",
+		Metric: "cpu",
+		Text:   "High CPU usage detected: 90.00%",
+		Time:   time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+}
+
+// recorder is a Notifier that keeps the alerts it got
+type recorder struct {
+	alerts []alert.Alert
+}
+
+func (r *recorder) Notify(_ context.Context, a alert.Alert) error {
+	r.alerts = append(r.alerts, a)
+	return nil
+}
+
+func TestRouter(t *testing.T) {
+	all, cpu, firing := &recorder{}, &recorder{}, &recorder{}
+
+	r := NewRouter().Add("all", all).Add("cpu", cpu).Add("firing", firing)
+	for _, route := range []Route{
+		{Notifiers: []string{"all"}},
+		{Match: []string{"cpu"}, Notifiers: []string{"cpu", "all"}},
+		{States: []alert.State{alert.Firing}, Notifiers: []string{"firing"}},
+	} {
+		if err := r.Route(route); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := r.Route(Route{Notifiers: []string{"nope"}}); err == nil {
+		t.Error("Route should reject unknown notifiers")
+	}
+
+	a := testAlert()
+	r.Notify(context.Background(), a)
+
+	a.Metric, a.Rule, a.State = "mem", "mem_threshold", alert.Resolved
+	r.Notify(context.Background(), a)
+
+	if len(all.alerts) != 2 || len(cpu.alerts) != 1 || len(firing.alerts) != 1 {
+		t.Errorf("got %d, %d, %d alerts, want 2, 1, 1", len(all.alerts), len(cpu.alerts), len(firing.alerts))
+	}
+}
+
+func TestConsole(t *testing.T) {
+	var buf bytes.Buffer
+	NewConsole(&buf).Notify(context.Background(), testAlert())
+
+	want := "2024-01-02T03:04:05Z [firing] cpu_threshold: High CPU usage detected: 90.00%\n"
+	if buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+}
+
+func TestWebhook(t *testing.T) {
+	bodies := make(chan []byte, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != "application/json" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		b, _ := io.ReadAll(r.Body)
+		bodies <- b
+	}))
+	defer srv.Close()
+
+	w, err := NewWebhook(srv.URL, `{"msg": {{json .Text}}, "state": "{{.State}}"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Notify(context.Background(), testAlert()); err != nil {
+		t.Fatal(err)
+	}
+
+	var got map[string]string
+	if err := json.Unmarshal(<-bodies, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got["msg"] != testAlert().Text || got["state"] != "firing" {
+		t.Errorf("unexpected payload %v", got)
+	}
+
+	// default template
+	w, _ = NewWebhook(srv.URL, "")
+	w.Notify(context.Background(), testAlert())
+	var a alert.Alert
+	if err := json.Unmarshal(<-bodies, &a); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(a, testAlert()) {
+		t.Errorf("got %+v, want %+v", a, testAlert())
+	}
+}
+
+func TestWebhookError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "boom", http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	w, _ := NewWebhook(srv.URL+"/hooks/secret", "")
+	if err := w.Notify(context.Background(), testAlert()); err == nil || !strings.Contains(err.Error(), "boom") || strings.Contains(err.Error(), "secret") {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	// nor do connection errors tell the secret part of the URL
+	srv.Close()
+	if err := w.Notify(context.Background(), testAlert()); err == nil || strings.Contains(err.Error(), "secret") {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+// smtpServer is a minimal in-process SMTP server that accepts one mail per connection
+func smtpServer(t *testing.T) (string, <-chan string) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	mails := make(chan string, 1)
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				reply := func(s string) { conn.Write([]byte(s + "\r\n")) }
+
+				reply("220 localhost ESMTP")
+				var data strings.Builder
+				inData := false
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if inData {
+						if line == ".\r\n" {
+							inData = false
+							mails <- data.String()
+							reply("250 OK")
+							continue
+						}
+						data.WriteString(line)
+						continue
+					}
+
+					switch cmd := strings.ToUpper(strings.Fields(line)[0]); cmd {
+					case "EHLO", "HELO":
+						reply("250 localhost")
+					case "DATA":
+						inData = true
+						reply("354 go ahead")
+					case "QUIT":
+						reply("221 bye")
+						return
+					default:
+						reply("250 OK")
+					}
+				}
+			}()
+		}
+	}()
+
+	return l.Addr().String(), mails
+}
+
+func TestEmail(t *testing.T) {
+	addr, mails := smtpServer(t)
+
+	e := NewEmail(addr, "", "", "monitor@example.com", "ops@example.com")
+	if err := e.Notify(context.Background(), testAlert()); err != nil {
+		t.Fatal(err)
+	}
+
+	mail := <-mails
+	for _, want := range []string{
+		"To: ops@example.com",
+		"Subject: [monitor] firing: cpu_threshold",
+		testAlert().Text,
+	} {
+		if !strings.Contains(mail, want) {
+			t.Errorf("mail does not contain %q:\n%s", want, mail)
+		}
+	}
+}
+
+func TestEmailTimeout(t *testing.T) {
+	// a server that accepts connections and never answers
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	e := NewEmail(l.Addr().String(), "", "", "monitor@example.com", "ops@example.com")
+	if err := e.Notify(ctx, testAlert()); err == nil || time.Since(start) > time.Second {
+		t.Errorf("Notify returned %v after %s, want to give up at the deadline", err, time.Since(start))
+	}
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notify.json")
+	os.WriteFile(path, []byte(`{
+		"notifiers": {
+			"hook": {"type": "webhook", "url": "http://localhost/hook"},
+			"stdout": {"type": "console"}
+		},
+		"routes": [
+			{"match": ["cpu"], "states": ["firing"], "notifiers": ["hook"]},
+			{"notifiers": ["stdout"]}
+		]
+	}`), 0o644)
+
+	r := NewRouter()
+	if err := r.Load(path); err != nil {
+		t.Fatal(err)
+	}
+
+	got := r.targets(testAlert())
+	if strings.Join(got, ",") != "hook,stdout" {
+		t.Errorf("targets = %v", got)
+	}
+
+	os.WriteFile(path, []byte(`{"notifiers": {"x": {"type": "pager"}}}`), 0o644)
+	if err := NewRouter().Load(path); err == nil {
+		t.Error("Load should reject unknown notifier types")
+	}
+}
+
+// batcher is a Batcher that keeps the batches it got
+type batcher struct {
+	recorder
+	batches [][]alert.Alert
+}
+
+func (b *batcher) NotifyBatch(_ context.Context, alerts []alert.Alert) error {
+	b.batches = append(b.batches, alerts)
+	return nil
+}
+
+func TestNotifyBatch(t *testing.T) {
+	single, batch := &recorder{}, &batcher{}
+	r := NewRouter().Add("single", single).Add("batch", batch)
+
+	a, b := testAlert(), testAlert()
+	b.Rule, b.Metric = "mem_threshold", "mem"
+
+	if err := r.NotifyBatch(context.Background(), []alert.Alert{a, b}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(single.alerts) != 2 {
+		t.Errorf("single got %d alerts, want 2", len(single.alerts))
+	}
+	if len(batch.batches) != 1 || len(batch.batches[0]) != 2 || len(batch.alerts) != 0 {
+		t.Errorf("batch got %d batches, want one batch of 2", len(batch.batches))
+	}
+}
@@ -0,0 +1,77 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"monitor/alert"
+	"net/http"
+	"time"
+)
+
+// Slack posts alerts to a Slack incoming webhook as attachments.
+type Slack struct {
+	URL    string
+	Retry  Retry
+	Client *http.Client
+}
+
+// NewSlack creates a Slack notifier posting to the incoming webhook url.
+func NewSlack(url string) *Slack {
+	return &Slack{
+		URL:    url,
+		Retry:  DefaultRetry,
+		Client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+type slackField struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+	Short bool   `json:"short"`
+}
+
+type slackAttachment struct {
+	Fallback string       `json:"fallback"`
+	Color    string       `json:"color"`
+	Title    string       `json:"title"`
+	Text     string       `json:"text"`
+	Fields   []slackField `json:"fields"`
+	Ts       int64        `json:"ts"`
+}
+
+type slackMessage struct {
+	Text        string            `json:"text"`
+	Attachments []slackAttachment `json:"attachments"`
+}
+
+// payload returns the webhook payload of a
+func (s *Slack) payload(a alert.Alert) ([]byte, error) {
+	att := slackAttachment{
+		Fallback: title(a) + ": " + a.Text,
+		Color:    hexColor(color(a)),
+		Title:    title(a),
+		Text:     a.Text,
+		Ts:       a.Time.Unix(),
+	}
+	for _, f := range fields(a) {
+		att.Fields = append(att.Fields, slackField{Title: f.Name, Value: f.Value, Short: f.Name != "Recent"})
+	}
+
+	return json.Marshal(slackMessage{
+		Text:        title(a),
+		Attachments: []slackAttachment{att},
+	})
+}
+
+// Notify implements Notifier.
+func (s *Slack) Notify(ctx context.Context, a alert.Alert) error {
+	body, err := s.payload(a)
+	if err != nil {
+		return err
+	}
+
+	header := http.Header{"Content-Type": []string{"application/json"}}
+	return s.Retry.Do(ctx, func() error {
+		return post(ctx, s.Client, s.URL, header, body)
+	})
+}
@@ -0,0 +1,33 @@
+package notify
+
+import (
+	"context"
+	"monitor/alert"
+)
+
+// Broadcaster broadcasts alerts to chat subscribers, it is implemented by the Telegram bot.
+type Broadcaster interface {
+	BroadcastAlerts(alerts ...alert.Alert)
+}
+
+// Telegram sends alerts to the subscribers of the Telegram bot.
+type Telegram struct {
+	bot Broadcaster
+}
+
+// NewTelegram creates a Telegram notifier using bot.
+func NewTelegram(bot Broadcaster) *Telegram {
+	return &Telegram{bot: bot}
+}
+
+// Notify implements Notifier.
+func (t *Telegram) Notify(_ context.Context, a alert.Alert) error {
+	t.bot.BroadcastAlerts(a)
+	return nil
+}
+
+// NotifyBatch implements Batcher, the bot sends the alerts as one message.
+func (t *Telegram) NotifyBatch(_ context.Context, alerts []alert.Alert) error {
+	t.bot.BroadcastAlerts(alerts...)
+	return nil
+}
@@ -0,0 +1,131 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"monitor/alert"
+	"net/http"
+	"net/url"
+	"strconv"
+	"text/template"
+	"time"
+)
+
+// DefaultTemplate posts the alert as a JSON object.
+const DefaultTemplate = `{{json .}}`
+
+var templateFuncs = template.FuncMap{
+	// json encodes a value as JSON, e.g. {"text": {{json .Text}}}
+	"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+// Webhook posts alerts to a URL, the payload is rendered from a text/template with the alert as data.
+type Webhook struct {
+	URL      string
+	Header   http.Header
+	Client   *http.Client
+	template *template.Template
+}
+
+// NewWebhook creates a Webhook posting to url with the payload rendered from tmpl, DefaultTemplate is used if tmpl is empty.
+func NewWebhook(url string, tmpl string) (*Webhook, error) {
+	if url == "" {
+		return nil, fmt.Errorf("webhook url is required")
+	}
+	if tmpl == "" {
+		tmpl = DefaultTemplate
+	}
+
+	t, err := template.New("webhook").Funcs(templateFuncs).Parse(tmpl)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Webhook{
+		URL:      url,
+		Header:   http.Header{"Content-Type": []string{"application/json"}},
+		Client:   &http.Client{Timeout: 10 * time.Second},
+		template: t,
+	}, nil
+}
+
+// Notify implements Notifier.
+func (w *Webhook) Notify(ctx context.Context, a alert.Alert) error {
+	var body bytes.Buffer
+	if err := w.template.Execute(&body, a); err != nil {
+		return err
+	}
+
+	return post(ctx, w.Client, w.URL, w.Header, body.Bytes())
+}
+
+// StatusError is returned when a server responds with a non-2xx status.
+type StatusError struct {
+	URL        string
+	Status     string
+	Code       int
+	Body       string
+	RetryAfter time.Duration // from the Retry-After header, 0 if absent
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s: %s %s", redact(e.URL), e.Status, e.Body)
+}
+
+// redact returns the scheme and host of rawURL, the path and query of webhook URLs are often secret
+func redact(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "invalid URL"
+	}
+	return u.Scheme + "://" + u.Host
+}
+
+// post sends body to target and fails unless the response status is 2xx
+func post(ctx context.Context, client *http.Client, target string, header http.Header, body []byte) error {
+	return send(ctx, client, http.MethodPost, target, header, body)
+}
+
+// send sends a request with body and fails unless the response status is 2xx
+func send(ctx context.Context, client *http.Client, method string, target string, header http.Header, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		var ue *url.Error
+		if errors.As(err, &ue) {
+			ue.URL = redact(ue.URL)
+		}
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(res.Body, 512))
+		e := &StatusError{
+			URL:    target,
+			Status: res.Status,
+			Code:   res.StatusCode,
+			Body:   string(bytes.TrimSpace(msg)),
+		}
+		if s, err := strconv.Atoi(res.Header.Get("Retry-After")); err == nil {
+			e.RetryAfter = time.Duration(s) * time.Second
+		}
+		return e
+	}
+
+	return nil
+}
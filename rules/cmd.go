@@ -0,0 +1,162 @@
+package rules
+
+import (
+	"fmt"
+	"monitor/alert"
+	mybot "monitor/bot"
+	"monitor/i18n"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// state returns the state of the rule in text
+func (r *Rule) state() string {
+	switch {
+	case r.Tracker.Flapping():
+		return "flapping"
+	case r.Tracker.Firing():
+		return "firing"
+	default:
+		return "ok"
+	}
+}
+
+// CmdRules handle /rules command, it lists all rules.
+func (s *Set) CmdRules(bot *mybot.Bot, update tgbotapi.Update, _ mybot.Args) {
+	rules := s.All()
+	if len(rules) == 0 {
+		bot.Reply(update, "rules.none")
+		return
+	}
+
+	l := bot.Locale(update)
+	var sb strings.Builder
+	sb.WriteString(l.T("rules.header") + "\n")
+	for _, r := range rules {
+		sb.WriteString(fmt.Sprintf("%s [%s, %s]: %s\n", r.Name, l.T("rules."+r.state()), l.T("severity."+r.Severity.String()), r.Expr))
+	}
+
+	bot.SendMsg(update.Message.Chat.ID, sb.String())
+}
+
+// CmdRule handle /rule command without subcommand, it shows the usage.
+func (s *Set) CmdRule(bot *mybot.Bot, update tgbotapi.Update, _ mybot.Args) {
+	bot.Reply(update, "rules.usage")
+}
+
+// CmdRuleNew handle /rule new command, it walks the user through adding a rule.
+func (s *Set) CmdRuleNew(bot *mybot.Bot, update tgbotapi.Update, _ mybot.Args) {
+	bot.Start(update, s.RuleForm())
+}
+
+// CmdRuleAdd handle /rule add command, it adds or replaces the rule of the "name" argument with the "expression" argument.
+func (s *Set) CmdRuleAdd(bot *mybot.Bot, update tgbotapi.Update, args mybot.Args) {
+	name := args.String("name")
+	prev := s.source(name)
+	r, err := s.Add(name, args.String("expression"))
+	if err != nil {
+		bot.Reply(update, "rules.invalid", "err", err)
+		return
+	}
+	bot.Audit(update, "rule", r.Name, prev, r.Expr.String())
+	bot.Reply(update, "rules.added", "name", r.Name, "expr", r.Expr.String())
+}
+
+// CmdRuleDel handle /rule del command, it deletes the rule of the "name" argument.
+func (s *Set) CmdRuleDel(bot *mybot.Bot, update tgbotapi.Update, args mybot.Args) {
+	name := args.String("name")
+	prev := s.source(name)
+	if !s.Del(name) {
+		bot.Reply(update, "rules.no_rule", "name", name)
+		return
+	}
+	bot.Audit(update, "rule", name, prev, "")
+	bot.Reply(update, "rules.deleted", "name", name)
+}
+
+// CmdRuleSeverity handle /rule severity command, it sets the severity of the rule of the "name" argument to the "severity" argument.
+func (s *Set) CmdRuleSeverity(bot *mybot.Bot, update tgbotapi.Update, args mybot.Args) {
+	name := args.String("name")
+	var severity alert.Severity
+	if err := severity.UnmarshalText([]byte(args.String("severity"))); err != nil {
+		bot.Reply(update, "rules.invalid", "err", err)
+		return
+	}
+	r, ok := s.Get(name)
+	if !ok || !s.SetSeverity(name, severity) {
+		bot.Reply(update, "rules.no_rule", "name", name)
+		return
+	}
+	bot.Audit(update, "rule_severity", name, r.Severity.String(), severity.String())
+	bot.Reply(update, "rules.severity", "name", name, "severity", severity.String())
+}
+
+// CmdRuleTest handle /rule test command, it evaluates the rule or expression of the "rule" argument and explains the result.
+func (s *Set) CmdRuleTest(bot *mybot.Bot, update tgbotapi.Update, args mybot.Args) {
+	bot.SendMsg(update.Message.Chat.ID, s.test(bot.Locale(update), args.String("rule")))
+}
+
+// source returns the expression of the rule with the given name, empty if there is no such rule
+func (s *Set) source(name string) string {
+	if r, ok := s.Get(name); ok {
+		return r.Expr.String()
+	}
+	return ""
+}
+
+// Revert reverts a rule change recorded in the audit log, a rule that did not exist before is deleted. A deleted rule comes back with the severity and alert state it had.
+func (s *Set) Revert(bot *mybot.Bot, e mybot.AuditEntry) error {
+	if e.Old == "" {
+		s.Del(e.Target)
+		return nil
+	}
+	_, err := s.add(e.Target, e.Old, true)
+	return err
+}
+
+// RevertSeverity reverts a severity change of a rule recorded in the audit log.
+func (s *Set) RevertSeverity(bot *mybot.Bot, e mybot.AuditEntry) error {
+	var severity alert.Severity
+	if err := severity.UnmarshalText([]byte(e.Old)); err != nil {
+		return err
+	}
+	if !s.SetSeverity(e.Target, severity) {
+		return fmt.Errorf("no rule named %s", e.Target)
+	}
+	return nil
+}
+
+// test evaluates the rule with the given name, or the given expression, against current data and explains the result in the language of l
+func (s *Set) test(l *i18n.Locale, src string) string {
+	var expr *Expr
+	if r, ok := s.Get(src); ok {
+		expr = r.Expr
+	} else {
+		var err error
+		expr, err = Parse(src)
+		if err != nil {
+			return l.T("rules.bad_expr", "err", err)
+		}
+	}
+
+	v, terms, err := expr.Explain(s.Source)
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%s\n\n", expr))
+	for _, t := range terms {
+		if t.Err != nil {
+			sb.WriteString(fmt.Sprintf("%s: %s\n", t.Expr, l.Error(t.Err)))
+		} else {
+			sb.WriteString(fmt.Sprintf("%s = %.2f\n", t.Expr, t.Value))
+		}
+	}
+
+	if err != nil {
+		sb.WriteString("\n" + l.T("rules.error", "err", err))
+	} else {
+		sb.WriteString("\n" + l.T("rules.result", "value", v, "fire", v != 0))
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,264 @@
+package rules
+
+import (
+	"fmt"
+	"math"
+	"monitor/collector"
+	"monitor/history"
+	"strconv"
+	"time"
+)
+
+// Source provides the history of the series used in expressions.
+type Source interface {
+	History(name string, selector collector.Labels) (*history.History, error)
+}
+
+// function is a function over the history of a series in a window
+type function func(h *history.History, d time.Duration) float64
+
+var functions = map[string]function{
+	"avg":      (*history.History).Average,
+	"min":      (*history.History).Min,
+	"max":      (*history.History).Max,
+	"stddev":   (*history.History).StdDev,
+	"rate":     (*history.History).Rate,
+	"forecast": (*history.History).Forecast,
+}
+
+// Term is the value of a series or function call in an expression, used to explain the result.
+type Term struct {
+	Expr  string
+	Value float64
+	Err   error
+}
+
+type node interface {
+	eval(s Source, terms *[]Term) (float64, error)
+	String() string
+}
+
+type numberNode float64
+
+func (n numberNode) eval(Source, *[]Term) (float64, error) {
+	return float64(n), nil
+}
+
+func (n numberNode) String() string {
+	return strconv.FormatFloat(float64(n), 'g', -1, 64)
+}
+
+type seriesNode struct {
+	name   string
+	labels collector.Labels
+}
+
+func (n seriesNode) eval(s Source, terms *[]Term) (float64, error) {
+	v, err := n.last(s)
+	*terms = append(*terms, Term{Expr: n.String(), Value: v, Err: err})
+	return v, err
+}
+
+func (n seriesNode) last(s Source) (float64, error) {
+	h, err := s.History(n.name, n.labels)
+	if err != nil {
+		return 0, err
+	}
+
+	v, ok := h.Last()
+	if !ok {
+		return 0, fmt.Errorf("no data for %s", n)
+	}
+	return v, nil
+}
+
+func (n seriesNode) String() string {
+	return n.name + n.labels.String()
+}
+
+type callNode struct {
+	name     string
+	fn       function
+	series   seriesNode
+	duration time.Duration
+}
+
+func (n callNode) eval(s Source, terms *[]Term) (float64, error) {
+	v, err := n.call(s)
+	*terms = append(*terms, Term{Expr: n.String(), Value: v, Err: err})
+	return v, err
+}
+
+func (n callNode) call(s Source) (float64, error) {
+	h, err := s.History(n.series.name, n.series.labels)
+	if err != nil {
+		return 0, err
+	}
+
+	v := n.fn(h, n.duration)
+	if math.IsNaN(v) {
+		return 0, fmt.Errorf("no data for %s", n)
+	}
+	return v, nil
+}
+
+func (n callNode) String() string {
+	return fmt.Sprintf("%s(%s, %s)", n.name, n.series, n.duration)
+}
+
+type unaryNode struct {
+	op string
+	x  node
+}
+
+func (n unaryNode) eval(s Source, terms *[]Term) (float64, error) {
+	x, err := n.x.eval(s, terms)
+	if err != nil {
+		return 0, err
+	}
+
+	if n.op == "!" {
+		return bool2float(x == 0), nil
+	}
+	return -x, nil
+}
+
+func (n unaryNode) String() string {
+	return n.op + n.x.String()
+}
+
+type binaryNode struct {
+	op   string
+	x, y node
+}
+
+func (n binaryNode) eval(s Source, terms *[]Term) (float64, error) {
+	x, err := n.x.eval(s, terms)
+	if err != nil {
+		return 0, err
+	}
+
+	// short circuit
+	switch {
+	case n.op == "&&" && x == 0:
+		return 0, nil
+	case n.op == "||" && x != 0:
+		return 1, nil
+	}
+
+	y, err := n.y.eval(s, terms)
+	if err != nil {
+		return 0, err
+	}
+
+	switch n.op {
+	case "&&", "||":
+		return bool2float(y != 0), nil
+	case ">":
+		return bool2float(x > y), nil
+	case "<":
+		return bool2float(x < y), nil
+	case ">=":
+		return bool2float(x >= y), nil
+	case "<=":
+		return bool2float(x <= y), nil
+	case "==":
+		return bool2float(x == y), nil
+	case "!=":
+		return bool2float(x != y), nil
+	case "+":
+		return x + y, nil
+	case "-":
+		return x - y, nil
+	case "*":
+		return x * y, nil
+	case "/":
+		return x / y, nil
+	}
+
+	return 0, fmt.Errorf("unknown operator %s", n.op)
+}
+
+func (n binaryNode) String() string {
+	return fmt.Sprintf("(%s %s %s)", n.x, n.op, n.y)
+}
+
+func bool2float(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// Expr is a parsed expression. Comparisons and logical operators evaluate to 1 (true) or 0 (false).
+type Expr struct {
+	src  string
+	root node
+}
+
+// Parse parses an expression like `avg(cpu, 5m) > 80 && mem > 90`.
+func Parse(src string) (*Expr, error) {
+	tokens, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, fmt.Errorf("unexpected %s at %d", t, t.pos)
+	}
+
+	return &Expr{src: src, root: root}, nil
+}
+
+// Eval evaluates the expression against the data in s.
+func (e *Expr) Eval(s Source) (float64, error) {
+	terms := []Term{}
+	return e.root.eval(s, &terms)
+}
+
+// Explain evaluates the expression and returns the value of every series and function call it evaluated on the way.
+func (e *Expr) Explain(s Source) (float64, []Term, error) {
+	terms := []Term{}
+	v, err := e.root.eval(s, &terms)
+	return v, terms, err
+}
+
+// Series returns the names of the series used in the expression, in the order they appear.
+func (e *Expr) Series() []string {
+	names := []string{}
+	seen := map[string]bool{}
+
+	var walk func(n node)
+	walk = func(n node) {
+		var name string
+		switch n := n.(type) {
+		case seriesNode:
+			name = n.name
+		case callNode:
+			name = n.series.name
+		case unaryNode:
+			walk(n.x)
+		case binaryNode:
+			walk(n.x)
+			walk(n.y)
+		}
+		if name != "" && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	walk(e.root)
+
+	return names
+}
+
+// String returns the source of the expression.
+func (e *Expr) String() string {
+	return e.src
+}
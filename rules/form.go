@@ -0,0 +1,107 @@
+package rules
+
+import (
+	"fmt"
+	"monitor/alert"
+	mybot "monitor/bot"
+	"monitor/i18n"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// checkSeries checks if src is a single series with data in the source, e.g. `disk{mount="/"}`
+func (s *Set) checkSeries(src string) error {
+	expr, err := Parse(src)
+	if err != nil {
+		return err
+	}
+
+	n, ok := expr.root.(seriesNode)
+	if !ok {
+		return i18n.Errorf("rules.not_series", "series", src)
+	}
+	_, err = s.Source.History(n.name, n.labels)
+	return err
+}
+
+// thresholdSource returns the expression of a threshold rule built from the answers of the rule form
+func thresholdSource(answers mybot.Args) string {
+	value := fmt.Sprintf("%s %s %g", answers.String("series"), answers.String("op"), answers.Float("value"))
+	if f := answers.String("function"); f != "last" {
+		value = fmt.Sprintf("%s(%s, %s) %s %g", f, answers.String("series"), answers.Duration("window"), answers.String("op"), answers.Float("value"))
+	}
+	return value
+}
+
+// RuleForm returns the form of the guided setup of an alert rule, started by /rule new.
+func (s *Set) RuleForm() *mybot.Form {
+	isThreshold := func(answers mybot.Args) bool {
+		return answers.String("kind") == "threshold"
+	}
+
+	return mybot.NewForm().
+		Ask(mybot.StringArg("name", "rules.ask_name")).
+		Check(func(answers mybot.Args) error {
+			if !validName(answers.String("name")) {
+				return i18n.Errorf("rules.bad_name", "name", answers.String("name"))
+			}
+			return nil
+		}).
+		Ask(mybot.EnumArg("kind", "rules.ask_kind", "threshold", "expression")).
+		Ask(mybot.StringArg("series", "rules.ask_series")).
+		When(isThreshold).
+		Check(func(answers mybot.Args) error {
+			return s.checkSeries(answers.String("series"))
+		}).
+		Ask(mybot.EnumArg("function", "rules.ask_function", "last", "avg", "min", "max", "rate", "forecast")).
+		When(isThreshold).
+		Ask(mybot.DurationArg("window", "rules.ask_window")).
+		When(func(answers mybot.Args) bool {
+			return isThreshold(answers) && answers.String("function") != "last"
+		}).
+		Check(func(answers mybot.Args) error {
+			if answers.Duration("window") <= 0 {
+				return i18n.Errorf("rules.bad_window")
+			}
+			return nil
+		}).
+		Ask(mybot.EnumArg("op", "rules.ask_op", ">", "<")).
+		When(isThreshold).
+		Ask(mybot.FloatArg("value", "rules.ask_value")).
+		When(isThreshold).
+		Ask(mybot.TextArg("expr", "rules.ask_expr")).
+		When(func(answers mybot.Args) bool {
+			return !isThreshold(answers)
+		}).
+		Check(func(answers mybot.Args) error {
+			_, err := Parse(answers.String("expr"))
+			return err
+		}).
+		Ask(mybot.EnumArg("severity", "rules.ask_severity", "info", "warning", "critical").Default("warning")).
+		Timeout(15 * time.Minute).
+		Finally(func(bot *mybot.Bot, update tgbotapi.Update, answers mybot.Args) {
+			chatID := update.FromChat().ID
+			name := answers.String("name")
+
+			src := answers.String("expr")
+			if isThreshold(answers) {
+				src = thresholdSource(answers)
+			}
+
+			prev := s.source(name)
+			r, err := s.Add(name, src)
+			if err != nil {
+				bot.SendMsg(chatID, bot.ChatLocale(chatID).T("rules.invalid", "err", err))
+				return
+			}
+			bot.Audit(update, "rule", r.Name, prev, r.Expr.String())
+
+			var severity alert.Severity
+			severity.UnmarshalText([]byte(answers.String("severity")))
+			s.SetSeverity(name, severity)
+
+			l := bot.ChatLocale(chatID)
+			bot.SendMsg(chatID, l.T("rules.added_severity", "severity", l.T("severity."+severity.String()), "name", r.Name, "expr", r.Expr.String())+"\n\n"+s.test(l, name))
+		})
+}
@@ -0,0 +1,161 @@
+package rules
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokNumber
+	tokDuration
+	tokIdent
+	tokString
+	tokOp
+	tokLParen
+	tokRParen
+	tokLBrace
+	tokRBrace
+	tokComma
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+	num  float64
+	dur  time.Duration
+}
+
+func (t token) String() string {
+	if t.kind == tokEOF {
+		return "end of expression"
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+// operators sorted so that longer operators are tried first
+var operators = []string{"&&", "||", ">=", "<=", "==", "!=", ">", "<", "!", "+", "-", "*", "/", "="}
+
+// lex splits src into tokens
+func lex(src string) ([]token, error) {
+	tokens := []token{}
+	i := 0
+
+	for i < len(src) {
+		c := rune(src[i])
+
+		switch {
+		case unicode.IsSpace(c):
+			i++
+
+		case unicode.IsDigit(c) || c == '.':
+			t, err := lexNumber(src, i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, t)
+			i += len(t.text)
+
+		case unicode.IsLetter(c) || c == '_':
+			j := i
+			for j < len(src) && (unicode.IsLetter(rune(src[j])) || unicode.IsDigit(rune(src[j])) || src[j] == '_') {
+				j++
+			}
+			tokens = append(tokens, token{kind: tokIdent, text: src[i:j], pos: i})
+			i = j
+
+		case c == '"':
+			j := i + 1
+			for j < len(src) && src[j] != '"' {
+				if src[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j >= len(src) {
+				return nil, fmt.Errorf("unterminated string at %d", i)
+			}
+			s, err := strconv.Unquote(src[i : j+1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid string at %d: %v", i, err)
+			}
+			tokens = append(tokens, token{kind: tokString, text: s, pos: i})
+			i = j + 1
+
+		default:
+			kinds := map[rune]tokenKind{
+				'(': tokLParen,
+				')': tokRParen,
+				'{': tokLBrace,
+				'}': tokRBrace,
+				',': tokComma,
+			}
+			if k, ok := kinds[c]; ok {
+				tokens = append(tokens, token{kind: k, text: string(c), pos: i})
+				i++
+				continue
+			}
+
+			matched := false
+			for _, op := range operators {
+				if strings.HasPrefix(src[i:], op) {
+					tokens = append(tokens, token{kind: tokOp, text: op, pos: i})
+					i += len(op)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("unexpected character %q at %d", c, i)
+			}
+		}
+	}
+
+	return append(tokens, token{kind: tokEOF, pos: len(src)}), nil
+}
+
+// lexNumber reads a number like 80, 0.5 or 100e6, or a duration like 5m or 1h30m starting at i
+func lexNumber(src string, i int) (token, error) {
+	j := i
+	for j < len(src) && (unicode.IsDigit(rune(src[j])) || src[j] == '.') {
+		j++
+	}
+
+	// exponent
+	if j < len(src) && (src[j] == 'e' || src[j] == 'E') {
+		k := j + 1
+		if k < len(src) && (src[k] == '+' || src[k] == '-') {
+			k++
+		}
+		if k < len(src) && unicode.IsDigit(rune(src[k])) {
+			for k < len(src) && unicode.IsDigit(rune(src[k])) {
+				k++
+			}
+			j = k
+		}
+	}
+
+	// a number directly followed by letters is a duration
+	if j < len(src) && unicode.IsLetter(rune(src[j])) {
+		for j < len(src) && (unicode.IsLetter(rune(src[j])) || unicode.IsDigit(rune(src[j])) || src[j] == '.') {
+			j++
+		}
+		d, err := time.ParseDuration(src[i:j])
+		if err != nil {
+			return token{}, fmt.Errorf("invalid duration %q at %d", src[i:j], i)
+		}
+		return token{kind: tokDuration, text: src[i:j], pos: i, dur: d}, nil
+	}
+
+	f, err := strconv.ParseFloat(src[i:j], 64)
+	if err != nil {
+		return token{}, fmt.Errorf("invalid number %q at %d", src[i:j], i)
+	}
+	return token{kind: tokNumber, text: src[i:j], pos: i, num: f}, nil
+}
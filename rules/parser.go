@@ -0,0 +1,221 @@
+package rules
+
+import (
+	"fmt"
+	"monitor/collector"
+)
+
+// parser is a recursive descent parser, from the lowest precedence to the highest:
+//
+//	or      = and { "||" and }
+//	and     = cmp { "&&" cmp }
+//	cmp     = add [ ( ">" | "<" | ">=" | "<=" | "==" | "!=" ) add ]
+//	add     = mul { ( "+" | "-" ) mul }
+//	mul     = unary { ( "*" | "/" ) unary }
+//	unary   = ( "!" | "-" ) unary | primary
+//	primary = number | series | ident "(" series "," duration ")" | "(" or ")"
+//	series  = ident [ "{" ident "=" string { "," ident "=" string } "}" ]
+type parser struct {
+	tokens []token
+	i      int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.i]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.i]
+	if t.kind != tokEOF {
+		p.i++
+	}
+	return t
+}
+
+// isOp checks if the next token is one of the given operators
+func (p *parser) isOp(ops ...string) bool {
+	t := p.peek()
+	if t.kind != tokOp {
+		return false
+	}
+	for _, op := range ops {
+		if t.text == op {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *parser) expect(kind tokenKind, what string) (token, error) {
+	t := p.next()
+	if t.kind != kind {
+		return t, fmt.Errorf("expected %s at %d, got %s", what, t.pos, t)
+	}
+	return t, nil
+}
+
+// binary parses a left associative chain of operators
+func (p *parser) binary(operand func() (node, error), ops ...string) (node, error) {
+	x, err := operand()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.isOp(ops...) {
+		op := p.next().text
+		y, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		x = binaryNode{op: op, x: x, y: y}
+	}
+
+	return x, nil
+}
+
+func (p *parser) parseOr() (node, error) {
+	return p.binary(p.parseAnd, "||")
+}
+
+func (p *parser) parseAnd() (node, error) {
+	return p.binary(p.parseCmp, "&&")
+}
+
+func (p *parser) parseCmp() (node, error) {
+	x, err := p.parseAdd()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.isOp(">", "<", ">=", "<=", "==", "!=") {
+		op := p.next().text
+		y, err := p.parseAdd()
+		if err != nil {
+			return nil, err
+		}
+		return binaryNode{op: op, x: x, y: y}, nil
+	}
+
+	return x, nil
+}
+
+func (p *parser) parseAdd() (node, error) {
+	return p.binary(p.parseMul, "+", "-")
+}
+
+func (p *parser) parseMul() (node, error) {
+	return p.binary(p.parseUnary, "*", "/")
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if p.isOp("!", "-") {
+		op := p.next().text
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return unaryNode{op: op, x: x}, nil
+	}
+
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	t := p.peek()
+
+	switch t.kind {
+	case tokNumber:
+		p.next()
+		return numberNode(t.num), nil
+
+	case tokLParen:
+		p.next()
+		x, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(tokRParen, `")"`); err != nil {
+			return nil, err
+		}
+		return x, nil
+
+	case tokIdent:
+		if p.tokens[p.i+1].kind == tokLParen {
+			return p.parseCall()
+		}
+		return p.parseSeries()
+
+	case tokDuration:
+		return nil, fmt.Errorf("unexpected duration %s at %d, durations are only allowed as function arguments", t, t.pos)
+	}
+
+	return nil, fmt.Errorf("unexpected %s at %d", t, t.pos)
+}
+
+func (p *parser) parseCall() (node, error) {
+	name := p.next()
+	fn, ok := functions[name.text]
+	if !ok {
+		return nil, fmt.Errorf("unknown function %s at %d", name, name.pos)
+	}
+	p.next() // (
+
+	series, err := p.parseSeries()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := p.expect(tokComma, `","`); err != nil {
+		return nil, err
+	}
+
+	d, err := p.expect(tokDuration, "duration")
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := p.expect(tokRParen, `")"`); err != nil {
+		return nil, err
+	}
+
+	return callNode{name: name.text, fn: fn, series: series, duration: d.dur}, nil
+}
+
+func (p *parser) parseSeries() (seriesNode, error) {
+	name, err := p.expect(tokIdent, "series name")
+	if err != nil {
+		return seriesNode{}, err
+	}
+
+	n := seriesNode{name: name.text}
+	if p.peek().kind != tokLBrace {
+		return n, nil
+	}
+	p.next()
+
+	n.labels = collector.Labels{}
+	for {
+		key, err := p.expect(tokIdent, "label name")
+		if err != nil {
+			return n, err
+		}
+		if !p.isOp("=") {
+			t := p.peek()
+			return n, fmt.Errorf(`expected "=" at %d, got %s`, t.pos, t)
+		}
+		p.next()
+		val, err := p.expect(tokString, "label value")
+		if err != nil {
+			return n, err
+		}
+		n.labels[key.text] = val.text
+
+		t := p.next()
+		if t.kind == tokRBrace {
+			return n, nil
+		}
+		if t.kind != tokComma {
+			return n, fmt.Errorf(`expected "," or "}" at %d, got %s`, t.pos, t)
+		}
+	}
+}
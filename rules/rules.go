@@ -0,0 +1,169 @@
+// rules evaluates alert rules, expressions over series like `avg(cpu, 5m) > 80`.
+package rules
+
+import (
+	"monitor/alert"
+	"monitor/i18n"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Rule is a named expression with its alert state.
+type Rule struct {
+	Name     string
+	Expr     *Expr
+	Severity alert.Severity
+	Tracker  *alert.Tracker
+}
+
+// Result is the result of evaluating a rule.
+type Result struct {
+	Rule  *Rule
+	Value float64
+	Terms []Term
+	Event alert.Event
+	Err   error
+}
+
+// Set is a set of rules evaluated against the same source. It is safe for concurrent use.
+type Set struct {
+	Source  Source
+	mu      sync.Mutex
+	rules   map[string]*Rule
+	deleted map[string]*Rule // last deleted rule of each name, so that undoing the delete brings back its severity and state
+}
+
+// NewSet creates an empty Set evaluated against source.
+func NewSet(source Source) *Set {
+	return &Set{
+		Source:  source,
+		rules:   map[string]*Rule{},
+		deleted: map[string]*Rule{},
+	}
+}
+
+// validName checks if name only contains lowercase letters, digits, '_' and '-'
+func validName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, c := range name {
+		if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '_' || c == '-') {
+			return false
+		}
+	}
+	return true
+}
+
+// Add parses src and adds it as a rule, replacing the rule with the same name. A replaced rule keeps its severity and alert state.
+func (s *Set) Add(name string, src string) (*Rule, error) {
+	return s.add(name, src, false)
+}
+
+// add adds src as the rule name like Add. If undelete is true and there is no such rule, the last deleted rule of the name comes back with its severity and alert state.
+func (s *Set) add(name string, src string, undelete bool) (*Rule, error) {
+	if !validName(name) {
+		return nil, i18n.Errorf("rules.bad_name", "name", name)
+	}
+
+	expr, err := Parse(src)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Rule{
+		Name:     name,
+		Expr:     expr,
+		Severity: alert.Warning,
+		Tracker:  alert.NewTracker(name),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	prev, ok := s.rules[name]
+	if !ok && undelete {
+		prev, ok = s.deleted[name]
+	}
+	if ok {
+		r.Severity = prev.Severity
+		r.Tracker = prev.Tracker
+	}
+	delete(s.deleted, name)
+	s.rules[name] = r
+
+	return r, nil
+}
+
+// Del removes the rule with the given name, it returns false if there is no such rule.
+func (s *Set) Del(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.rules[name]
+	if ok {
+		s.deleted[name] = r
+	}
+	delete(s.rules, name)
+	return ok
+}
+
+// SetSeverity sets the severity of the alerts raised by the rule with the given name, it returns false if there is no such rule.
+func (s *Set) SetSeverity(name string, severity alert.Severity) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.rules[name]
+	if !ok {
+		return false
+	}
+
+	// replace the rule instead of changing it, others may be reading it
+	c := *r
+	c.Severity = severity
+	s.rules[name] = &c
+	return true
+}
+
+// Get returns the rule with the given name.
+func (s *Set) Get(name string) (*Rule, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.rules[name]
+	return r, ok
+}
+
+// All returns all rules sorted by name.
+func (s *Set) All() []*Rule {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	res := make([]*Rule, 0, len(s.rules))
+	for _, r := range s.rules {
+		res = append(res, r)
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Name < res[j].Name
+	})
+
+	return res
+}
+
+// Eval evaluates all rules and feeds the results to their trackers. A rule that fails to evaluate keeps its state.
+func (s *Set) Eval(now time.Time, opt alert.Options) []Result {
+	results := []Result{}
+
+	for _, r := range s.All() {
+		v, terms, err := r.Expr.Explain(s.Source)
+		res := Result{Rule: r, Value: v, Terms: terms, Err: err}
+		if err == nil {
+			res.Event = r.Tracker.Observe(v != 0, v == 0, now, opt)
+		}
+		results = append(results, res)
+	}
+
+	return results
+}
@@ -0,0 +1,241 @@
+package rules
+
+import (
+	"monitor/alert"
+	mybot "monitor/bot"
+	"monitor/collector"
+	"monitor/history"
+	"monitor/i18n"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testSource() *collector.Registry {
+	r := collector.New(time.Hour)
+	r.Record([]collector.Sample{
+		{Name: "cpu", Value: 70},
+		{Name: "mem", Value: 95},
+		{Name: "disk", Labels: collector.Labels{"mount": "/"}, Value: 40},
+		{Name: "disk", Labels: collector.Labels{"mount": "/home"}, Value: 60},
+	})
+	r.Record([]collector.Sample{
+		{Name: "cpu", Value: 90},
+	})
+	return r
+}
+
+func TestEval(t *testing.T) {
+	src := testSource()
+
+	tests := []struct {
+		expr string
+		want float64
+	}{
+		{"1 + 2 * 3", 7},
+		{"(1 + 2) * 3", 9},
+		{"-2 * -3", 6},
+		{"100e6 > 1e8", 0},
+		{"cpu", 90},
+		{"avg(cpu, 5m)", 80},
+		{"max(cpu, 1h) - min(cpu, 1h)", 20},
+		{"avg(cpu, 5m) > 75 && mem > 90", 1},
+		{"cpu < 50 || !(mem > 90)", 0},
+		{`disk{mount="/home"} >= 60`, 1},
+		{`disk{mount="/"} == 40`, 1},
+	}
+
+	for _, test := range tests {
+		e, err := Parse(test.expr)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", test.expr, err)
+			continue
+		}
+		got, err := e.Eval(src)
+		if err != nil {
+			t.Errorf("Eval(%q): %v", test.expr, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("Eval(%q) = %g, want %g", test.expr, got, test.want)
+		}
+	}
+}
+
+func TestParseError(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"cpu >",
+		"avg(cpu)",
+		"avg(cpu, 5)",
+		"avg(1, 5m)",
+		"foo(cpu, 5m)",
+		"cpu > 5m",
+		`disk{mount=/}`,
+		"(cpu > 1",
+		"cpu $ 1",
+	} {
+		if _, err := Parse(expr); err == nil {
+			t.Errorf("Parse(%q) should fail", expr)
+		}
+	}
+}
+
+func TestEvalError(t *testing.T) {
+	src := testSource()
+
+	for _, expr := range []string{
+		"net_rx > 0",   // no such series
+		"disk > 50",    // ambiguous
+		"avg(gpu, 1m)", // no such series
+	} {
+		e, err := Parse(expr)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", expr, err)
+			continue
+		}
+		if _, err := e.Eval(src); err == nil {
+			t.Errorf("Eval(%q) should fail", expr)
+		}
+	}
+}
+
+func TestSet(t *testing.T) {
+	h := history.New(time.Hour, "cpu")
+	src := collector.New(time.Hour).Track("cpu", nil, h)
+	s := NewSet(src)
+
+	if _, err := s.Add("High CPU", "cpu > 80"); err == nil {
+		t.Error("Add should reject invalid names")
+	}
+	if _, err := s.Add("high_cpu", "cpu > 80"); err != nil {
+		t.Fatal(err)
+	}
+
+	n := time.Now()
+	events := []alert.Event{}
+	for _, v := range []float64{70, 90, 90, 60} {
+		h.Append(v)
+		n = n.Add(time.Minute)
+		res := s.Eval(n, alert.Options{MinSamples: 1})
+		events = append(events, res[0].Event)
+	}
+
+	want := []alert.Event{alert.None, alert.Fire, alert.None, alert.Resolve}
+	for i := range want {
+		if events[i] != want[i] {
+			t.Errorf("event %d = %d, want %d", i, events[i], want[i])
+		}
+	}
+
+	if r, _ := s.Get("high_cpu"); r.Severity != alert.Warning {
+		t.Errorf("default severity = %s, want warning", r.Severity)
+	}
+	if !s.SetSeverity("high_cpu", alert.Critical) || s.SetSeverity("nope", alert.Critical) {
+		t.Error("SetSeverity should only set the severity of existing rules")
+	}
+	if r, _ := s.Get("high_cpu"); r.Severity != alert.Critical {
+		t.Errorf("severity = %s, want critical", r.Severity)
+	}
+
+	if !s.Del("high_cpu") || s.Del("high_cpu") {
+		t.Error("Del should delete the rule exactly once")
+	}
+}
+
+func TestRevert(t *testing.T) {
+	s := NewSet(collector.New(time.Hour))
+
+	r, _ := s.Add("high_cpu", "avg(cpu, 5m) > 80 && mem > 90")
+	s.Add("high_cpu", "cpu > 95")
+
+	// restore the previous expression from its string form
+	if err := s.Revert(nil, mybot.AuditEntry{Target: "high_cpu", Old: r.Expr.String()}); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := s.Get("high_cpu"); got.Expr.String() != r.Expr.String() {
+		t.Errorf("got %s, want %s", got.Expr, r.Expr)
+	}
+
+	// a rule that did not exist before is deleted
+	s.Revert(nil, mybot.AuditEntry{Target: "high_cpu"})
+	if _, ok := s.Get("high_cpu"); ok {
+		t.Error("Revert should delete a new rule")
+	}
+}
+
+func TestRevertKeepsState(t *testing.T) {
+	h := history.New(time.Hour, "cpu")
+	s := NewSet(collector.New(time.Hour).Track("cpu", nil, h))
+
+	s.Add("high_cpu", "cpu > 80")
+	s.SetSeverity("high_cpu", alert.Critical)
+	h.Append(90)
+	if res := s.Eval(time.Now(), alert.Options{MinSamples: 1}); res[0].Event != alert.Fire {
+		t.Fatalf("event %d, want the rule firing", res[0].Event)
+	}
+
+	// an edit and its revert keep the severity and the firing state
+	r, _ := s.Add("high_cpu", "cpu > 85")
+	if r.Severity != alert.Critical || !r.Tracker.Firing() {
+		t.Errorf("edited rule is %s, firing %v, want critical and firing", r.Severity, r.Tracker.Firing())
+	}
+	s.Revert(nil, mybot.AuditEntry{Target: "high_cpu", Old: "cpu > 80", New: "cpu > 85"})
+	if r, _ := s.Get("high_cpu"); r.Severity != alert.Critical || !r.Tracker.Firing() || r.Expr.String() != "cpu > 80" {
+		t.Errorf("reverted rule is %s %s, firing %v, want critical and firing", r.Expr, r.Severity, r.Tracker.Firing())
+	}
+
+	// so does undoing a delete, no alert is raised again for the same breach
+	s.Del("high_cpu")
+	s.Revert(nil, mybot.AuditEntry{Target: "high_cpu", Old: "cpu > 80"})
+	r, ok := s.Get("high_cpu")
+	if !ok || r.Severity != alert.Critical || !r.Tracker.Firing() {
+		t.Fatalf("restored rule %+v, want critical and firing", r)
+	}
+	h.Append(90)
+	if res := s.Eval(time.Now(), alert.Options{MinSamples: 1}); res[0].Event != alert.None {
+		t.Errorf("event %d after restoring, want none", res[0].Event)
+	}
+
+	// a new rule of a deleted name starts afresh
+	s.Del("high_cpu")
+	if r, _ := s.Add("high_cpu", "cpu > 80"); r.Severity != alert.Warning || r.Tracker.Firing() {
+		t.Errorf("new rule is %s, firing %v, want a fresh warning rule", r.Severity, r.Tracker.Firing())
+	}
+}
+
+func TestRuleFormHelpers(t *testing.T) {
+	s := NewSet(testSource())
+
+	if err := s.checkSeries("cpu"); err != nil {
+		t.Error(err)
+	}
+	for _, src := range []string{"nope", "cpu > 1", "avg(cpu, 5m)"} {
+		if err := s.checkSeries(src); err == nil {
+			t.Errorf("checkSeries(%q) should fail", src)
+		}
+	}
+
+	if got := s.test(i18n.TraditionalChinese, "cpu > 80"); !strings.HasSuffix(got, "結果：1，規則會觸發") {
+		t.Errorf("test = %q, want the result in the language of the user", got)
+	}
+	if got := s.test(i18n.English, "mem < 0"); !strings.HasSuffix(got, "Result: 0, the rule would not fire") {
+		t.Errorf("test = %q", got)
+	}
+
+	for _, c := range []struct {
+		answers mybot.Args
+		want    string
+	}{
+		{mybot.Args{"series": "cpu", "function": "last", "op": ">", "value": 80.0}, "cpu > 80"},
+		{mybot.Args{"series": "mem", "function": "avg", "window": 5 * time.Minute, "op": "<", "value": 0.5}, "avg(mem, 5m0s) < 0.5"},
+	} {
+		got := thresholdSource(c.answers)
+		if got != c.want {
+			t.Errorf("got %q, want %q", got, c.want)
+		}
+		if _, err := Parse(got); err != nil {
+			t.Errorf("Parse(%q): %v", got, err)
+		}
+	}
+}